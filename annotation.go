@@ -0,0 +1,236 @@
+package chess
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// A NAG is a Numeric Annotation Glyph, the $n annotations of the PGN format.
+type NAG uint8
+
+const (
+	// NoNAG is the null annotation ($0).
+	NoNAG NAG = iota
+	// GoodMove is written as ! or $1.
+	GoodMove
+	// MistakeMove is written as ? or $2.
+	MistakeMove
+	// BrilliantMove is written as !! or $3.
+	BrilliantMove
+	// BlunderMove is written as ?? or $4.
+	BlunderMove
+	// SpeculativeMove is written as !? or $5.
+	SpeculativeMove
+	// DubiousMove is written as ?! or $6.
+	DubiousMove
+)
+
+var nagSuffixes = map[string]NAG{
+	"!":  GoodMove,
+	"?":  MistakeMove,
+	"!!": BrilliantMove,
+	"??": BlunderMove,
+	"!?": SpeculativeMove,
+	"?!": DubiousMove,
+}
+
+// String returns the NAG in its $n form.
+func (n NAG) String() string {
+	return "$" + strconv.Itoa(int(n))
+}
+
+// moveAnnotations holds the comments, NAGs and variations of a single move.
+// Variations are alternatives to the move, played from the position before it.
+type moveAnnotations struct {
+	comments   []string
+	nags       []NAG
+	variations []variation
+}
+
+// variation is a line of moves along with the variations nested inside it,
+// keyed by the index of the move they are alternatives to.
+type variation struct {
+	moves      []Move
+	variations map[int][]variation
+}
+
+func (v variation) clone() variation {
+	cp := variation{moves: append([]Move(nil), v.moves...)}
+	for i, vs := range v.variations {
+		if cp.variations == nil {
+			cp.variations = map[int][]variation{}
+		}
+		for _, sub := range vs {
+			cp.variations[i] = append(cp.variations[i], sub.clone())
+		}
+	}
+	return cp
+}
+
+// lines appends the variation and those nested inside it to lines, each
+// prefixed with the moves leading to it.
+func (v variation) lines(prefix []Move, lines [][]Move) [][]Move {
+	line := append(append([]Move(nil), prefix...), v.moves...)
+	lines = append(lines, line)
+	for i := range v.moves {
+		for _, sub := range v.variations[i] {
+			lines = sub.lines(line[:len(prefix)+i], lines)
+		}
+	}
+	return lines
+}
+
+func (a moveAnnotations) empty() bool {
+	return len(a.comments) == 0 && len(a.nags) == 0 && len(a.variations) == 0
+}
+
+func (a moveAnnotations) clone() moveAnnotations {
+	cp := moveAnnotations{
+		comments: append([]string(nil), a.comments...),
+		nags:     append([]NAG(nil), a.nags...),
+	}
+	for _, v := range a.variations {
+		cp.variations = append(cp.variations, v.clone())
+	}
+	return cp
+}
+
+// annotationsAt returns the annotations of the move at ply, or nil if ply
+// isn't a move of the game.
+func (g *Game) annotationsAt(ply int) *moveAnnotations {
+	if ply < 0 || ply >= len(g.moves) {
+		return nil
+	}
+	for len(g.annotations) <= ply {
+		g.annotations = append(g.annotations, moveAnnotations{})
+	}
+	return &g.annotations[ply]
+}
+
+func (g *Game) annotation(ply int) moveAnnotations {
+	if ply < len(g.annotations) {
+		return g.annotations[ply]
+	}
+	return moveAnnotations{}
+}
+
+// Comments returns the comments of each move in the game, indexed by ply
+// (0 is the first move of the game).
+func (g *Game) Comments() [][]string {
+	comments := make([][]string, len(g.moves))
+	for i := range comments {
+		comments[i] = append([]string(nil), g.annotation(i).comments...)
+	}
+	return comments
+}
+
+// AddComment adds a comment to the move at the given ply.  An error is
+// returned if the game has no such move.
+func (g *Game) AddComment(ply int, comment string) error {
+	a := g.annotationsAt(ply)
+	if a == nil {
+		return fmt.Errorf("chess: no move at ply %d", ply)
+	}
+	a.comments = append(a.comments, comment)
+	return nil
+}
+
+// SetComments replaces the comments of the move at the given ply.  An error
+// is returned if the game has no such move.
+func (g *Game) SetComments(ply int, comments []string) error {
+	a := g.annotationsAt(ply)
+	if a == nil {
+		return fmt.Errorf("chess: no move at ply %d", ply)
+	}
+	a.comments = append([]string(nil), comments...)
+	return nil
+}
+
+// NAGs returns the NAGs of each move in the game, indexed by ply.
+func (g *Game) NAGs() [][]NAG {
+	nags := make([][]NAG, len(g.moves))
+	for i := range nags {
+		nags[i] = append([]NAG(nil), g.annotation(i).nags...)
+	}
+	return nags
+}
+
+// AddNAG adds a NAG to the move at the given ply unless the move already has
+// it.  An error is returned if the game has no such move.
+func (g *Game) AddNAG(ply int, nag NAG) error {
+	a := g.annotationsAt(ply)
+	if a == nil {
+		return fmt.Errorf("chess: no move at ply %d", ply)
+	}
+	for _, n := range a.nags {
+		if n == nag {
+			return nil
+		}
+	}
+	a.nags = append(a.nags, nag)
+	return nil
+}
+
+// Variations returns the variations of the move at the given ply.  Each
+// variation is a line played instead of the move, from the position before it.
+// Variations nested inside them are returned by Lines.
+func (g *Game) Variations(ply int) [][]Move {
+	var out [][]Move
+	for _, v := range g.annotation(ply).variations {
+		out = append(out, append([]Move(nil), v.moves...))
+	}
+	return out
+}
+
+// Lines returns every line of the game from its starting position: the main
+// line followed by each variation, nested ones included, prefixed with the
+// moves leading to it.
+func (g *Game) Lines() [][]Move {
+	lines := [][]Move{g.Moves()}
+	for ply := range g.moves {
+		for _, v := range g.annotation(ply).variations {
+			lines = v.lines(g.moves[:ply], lines)
+		}
+	}
+	return lines
+}
+
+// AddVariation adds a variation to the move at the given ply.  An error is
+// returned if the game has no such move or the moves aren't a legal line from
+// the position before it.
+func (g *Game) AddVariation(ply int, moves []Move) error {
+	if ply < 0 || ply >= len(g.moves) {
+		return fmt.Errorf("chess: no move at ply %d", ply)
+	}
+	line, err := validLine(g.positions[ply], moves)
+	if err != nil {
+		return err
+	}
+	a := g.annotationsAt(ply)
+	a.variations = append(a.variations, variation{moves: line})
+	return nil
+}
+
+// validLine returns the moves, with their tags set, if they can be played in
+// order from pos.
+func validLine(pos *Position, moves []Move) ([]Move, error) {
+	if len(moves) == 0 {
+		return nil, fmt.Errorf("chess: empty variation")
+	}
+	line := make([]Move, 0, len(moves))
+	for _, m := range moves {
+		valid := Move(0)
+		for _, v := range pos.ValidMoves() {
+			if v.Eq(m) {
+				valid = v
+				break
+			}
+		}
+		if valid == 0 {
+			return nil, fmt.Errorf("chess: invalid move %s in variation", m)
+		}
+		line = append(line, valid)
+		pos = pos.Update(valid)
+	}
+	return line, nil
+}
@@ -0,0 +1,73 @@
+package chess
+
+const antichessFEN = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w - - 0 1"
+
+// antichessPromos are the promotions of Antichess, where the king is an
+// ordinary piece.
+var antichessPromos = []PromoType{PromoQueen, PromoRook, PromoBishop, PromoKnight, PromoKing}
+
+type antichess struct {
+	standard
+}
+
+func (antichess) Name() string {
+	return "Antichess"
+}
+
+func (antichess) StartingPosition() *Position {
+	return variantPosition(Antichess, antichessFEN)
+}
+
+// moves returns the captures of the position if there are any, since
+// capturing is compulsory, and otherwise all of its moves.  Kings can be
+// captured like any other piece, so no move is illegal because of check,
+// and there is no castling.
+func (antichess) moves(pos *Position, first bool) []Move {
+	moves := pseudoMoves(pos, antichessPromos)
+	captures := []Move{}
+	for _, m := range moves {
+		if m.HasTag(Capture) || m.HasTag(EnPassant) {
+			captures = append(captures, m)
+		}
+	}
+	if len(captures) > 0 {
+		moves = captures
+	}
+	if first && len(moves) > 0 {
+		return moves[:1]
+	}
+	return moves
+}
+
+// result returns a win for the side to move when it has no moves left,
+// either because it lost all its pieces or because it's stalemated.
+func (v antichess) result(pos *Position) (Outcome, Method) {
+	hasMove := len(pos.validMoves) > 0
+	if pos.validMoves == nil {
+		hasMove = len(v.moves(pos, true)) > 0
+	}
+	if !hasMove {
+		return winner(pos.turn), VariantWin
+	}
+	return NoOutcome, NoMethod
+}
+
+// insufficientMaterial returns true if only bishops are left and the
+// bishops of each side are on squares of a different color, so neither
+// side can ever capture.
+func (antichess) insufficientMaterial(pos *Position) bool {
+	b := pos.board
+	white := b.bbForPiece(WhiteBishop)
+	black := b.bbForPiece(BlackBishop)
+	if white == 0 || black == 0 || b.occupied() != white|black {
+		return false
+	}
+	light := bitboard(lightSquares)
+	return (white&light == 0 && black&^light == 0) ||
+		(white&^light == 0 && black&light == 0)
+}
+
+// there is no check in Antichess
+func (antichess) inCheck(b *Board, c Color) bool {
+	return false
+}
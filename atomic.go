@@ -0,0 +1,220 @@
+package chess
+
+import (
+	"math/bits"
+	"strings"
+)
+
+type atomic struct {
+	standard
+}
+
+func (atomic) Name() string {
+	return "Atomic"
+}
+
+func (atomic) StartingPosition() *Position {
+	return variantPosition(Atomic, startFEN)
+}
+
+// moves returns the moves that explode the other king or leave the color
+// to move's king on the board and out of check.  Kings can't capture, as
+// they would explode too.
+func (atomic) moves(pos *Position, first bool) []Move {
+	if !atomicHasKings(pos.board) {
+		return []Move{}
+	}
+	us, them := pos.turn, pos.turn.Other()
+	moves := []Move{}
+	for _, m := range append(pseudoMoves(pos, promoPieceTypes), atomicCastles(pos)...) {
+		capture := m.HasTag(Capture) || m.HasTag(EnPassant)
+		if capture && m.piece().Type() == King {
+			continue
+		}
+		b := pos.tempCopyBoard()
+		atomicUpdate(b, m)
+		ours := b.bbForPiece(GetPiece(King, us))
+		theirs := b.bbForPiece(GetPiece(King, them))
+		legal := ours != 0 && (theirs == 0 || !atomicInCheck(b, us))
+		if legal && theirs != 0 && atomicInCheck(b, them) {
+			m = m.addTag(Check)
+		}
+		pos.finishTempCopy(b)
+		if !legal {
+			continue
+		}
+		moves = append(moves, m)
+		if first {
+			return moves
+		}
+	}
+	return moves
+}
+
+func (v atomic) result(pos *Position) (Outcome, Method) {
+	if pos.board.bbForPiece(GetPiece(King, pos.turn)) == 0 {
+		return winner(pos.turn.Other()), VariantWin
+	}
+	if pos.board.bbForPiece(GetPiece(King, pos.turn.Other())) == 0 {
+		return winner(pos.turn), VariantWin
+	}
+	return v.standard.result(pos)
+}
+
+// atomicCastleRights are the castling rights lost when a square explodes.
+var atomicCastleRights = map[Square]string{
+	A1: "Q", E1: "KQ", H1: "K",
+	A8: "q", E8: "kq", H8: "k",
+}
+
+func (atomic) update(prev, next *Position, m Move) {
+	if !m.HasTag(Capture) && !m.HasTag(EnPassant) {
+		return
+	}
+	next.board.explode(m.S2())
+	// the explosion takes away the rights of the kings and rooks it hits
+	blast := bbKingMoves[m.S2()] | bbForSquare(m.S2())
+	rights := string(next.castleRights)
+	for sq, lost := range atomicCastleRights {
+		if blast&bbForSquare(sq) == 0 {
+			continue
+		}
+		rights = strings.Map(func(r rune) rune {
+			if strings.ContainsRune(lost, r) {
+				return -1
+			}
+			return r
+		}, rights)
+	}
+	if rights == "" {
+		rights = "-"
+	}
+	next.castleRights = CastleRights(rights)
+}
+
+// insufficientMaterial returns true if neither side can explode the other
+// king, which a bare king can't do and a lone knight, bishop or rook
+// can't do against a bare king.
+func (atomic) insufficientMaterial(pos *Position) bool {
+	return atomicInsufficient(pos.board, White) && atomicInsufficient(pos.board, Black)
+}
+
+func (atomic) inCheck(b *Board, c Color) bool {
+	return atomicInCheck(b, c)
+}
+
+// atomicInsufficient returns true if the color can't win on the board.
+func atomicInsufficient(b *Board, c Color) bool {
+	king := GetPiece(King, c)
+	otherKing := GetPiece(King, c.Other())
+	ours := b.whiteSqs()
+	theirs := b.blackSqs()
+	if c == Black {
+		ours, theirs = theirs, ours
+	}
+	if b.bbForPiece(otherKing) == 0 {
+		return false
+	}
+	if ours == b.bbForPiece(king) {
+		return true
+	}
+	// the other side's pieces can explode next to their own king
+	if theirs != b.bbForPiece(otherKing) {
+		return false
+	}
+	if b.bbForPiece(GetPiece(Queen, c))|b.bbForPiece(GetPiece(Pawn, c)) != 0 {
+		return false
+	}
+	knights := b.bbForPiece(GetPiece(Knight, c))
+	minors := knights | b.bbForPiece(GetPiece(Bishop, c)) | b.bbForPiece(GetPiece(Rook, c))
+	if bits.OnesCount64(uint64(minors)) == 1 {
+		return true
+	}
+	return minors == knights && bits.OnesCount64(uint64(knights)) <= 2
+}
+
+// atomicHasKings returns true if neither king has exploded.
+func atomicHasKings(b *Board) bool {
+	return b.bbForPiece(WhiteKing) != 0 && b.bbForPiece(BlackKing) != 0
+}
+
+// atomicInCheck returns true if the color's king is attacked by a piece
+// other than the king, and isn't next to the other king, whose capture
+// would explode both.
+func atomicInCheck(b *Board, c Color) bool {
+	king := b.bbForPiece(GetPiece(King, c))
+	if king == 0 {
+		return false
+	}
+	sq := bbGetFirstSquare(king)
+	if bbKingMoves[sq]&b.bbForPiece(GetPiece(King, c.Other())) != 0 {
+		return false
+	}
+	return squaresAreAttacked(b, c, sq)
+}
+
+// atomicUpdate makes the move on the board, exploding it if it's a capture.
+func atomicUpdate(b *Board, m Move) {
+	b.update(m)
+	if m.HasTag(Capture) || m.HasTag(EnPassant) {
+		b.explode(m.S2())
+	}
+}
+
+// explode removes the piece on the square and every piece around it except
+// pawns, after an Atomic capture.
+func (b *Board) explode(sq Square) {
+	blast := bbKingMoves[sq]
+	for i := range b.array {
+		if Piece(i).Type() != Pawn {
+			b.array[i] &^= blast
+		}
+		b.array[i] &^= bbForSquare(sq)
+	}
+	b.occupiedCache = 0
+	b.updateKings(0)
+}
+
+// atomicCastle is a castle, with the squares between the king and rook
+// that must be empty and the squares the king passes that mustn't be
+// attacked.
+type atomicCastle struct {
+	color  Color
+	side   Side
+	tag    MoveTag
+	s1, s2 Square
+	empty  []Square
+	passes []Square
+}
+
+var atomicCastleMoves = []atomicCastle{
+	{White, KingSide, KingSideCastle, E1, G1, []Square{F1, G1}, []Square{E1, F1, G1}},
+	{White, QueenSide, QueenSideCastle, E1, C1, []Square{B1, C1, D1}, []Square{E1, D1, C1}},
+	{Black, KingSide, KingSideCastle, E8, G8, []Square{F8, G8}, []Square{E8, F8, G8}},
+	{Black, QueenSide, QueenSideCastle, E8, C8, []Square{B8, C8, D8}, []Square{E8, D8, C8}},
+}
+
+// atomicCastles returns the castles of the color to move, which can pass
+// attacked squares next to the other king.
+func atomicCastles(pos *Position) []Move {
+	moves := []Move{}
+	king := GetPiece(King, pos.turn)
+	otherKing := pos.board.bbForPiece(GetPiece(King, pos.turn.Other()))
+	occupied := pos.board.occupied()
+	for _, c := range atomicCastleMoves {
+		if c.color != pos.turn || !pos.castleRights.CanCastle(c.color, c.side) {
+			continue
+		}
+		ok := true
+		for _, sq := range c.empty {
+			ok = ok && occupied&bbForSquare(sq) == 0
+		}
+		for _, sq := range c.passes {
+			ok = ok && (bbKingMoves[sq]&otherKing != 0 || !squaresAreAttacked(pos.board, pos.turn, sq))
+		}
+		if ok {
+			moves = append(moves, NewMove(c.s1, c.s2, NoPromo, king).addTag(c.tag))
+		}
+	}
+	return moves
+}
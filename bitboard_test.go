@@ -1,6 +1,11 @@
 package chess
 
-import "testing"
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/barakmich/chess/bitflip"
+)
 
 type bitboardTestPair struct {
 	initial  uint64
@@ -43,6 +48,42 @@ func TestBitboardOccupied(t *testing.T) {
 	}
 }
 
+// forEachKernel runs the test with each bitflip kernel the CPU supports.
+func forEachKernel(t *testing.T, f func(t *testing.T)) {
+	for _, k := range []bitflip.Kernel{bitflip.Generic, bitflip.AVX, bitflip.Magic} {
+		if !bitflip.Supported(k) {
+			continue
+		}
+		t.Run(k.String(), func(t *testing.T) {
+			prev, err := bitflip.SetKernel(k)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer bitflip.SetKernel(prev)
+			f(t)
+		})
+	}
+}
+
+func TestSliderAttacks(t *testing.T) {
+	forEachKernel(t, func(t *testing.T) {
+		r := rand.New(rand.NewSource(1))
+		for n := 0; n < 10000; n++ {
+			sq := Square(r.Intn(64))
+			occ := bitboard(r.Uint64() & r.Uint64())
+			pos := bbForSquare(sq)
+			rook := linearAttack(occ, pos, bbRanks[sq.Rank()]) | linearAttack(occ, pos, bbFiles[sq.File()])
+			bishop := linearAttack(occ, pos, bbDiagonals[sq]) | linearAttack(occ, pos, bbAntiDiagonals[sq])
+			if out := hvAttack(occ, sq); out != rook {
+				t.Fatalf("rook on %s with %016x: expected %016x but got %016x", sq, uint64(occ), uint64(rook), uint64(out))
+			}
+			if out := diaAttack(occ, sq); out != bishop {
+				t.Fatalf("bishop on %s with %016x: expected %016x but got %016x", sq, uint64(occ), uint64(bishop), uint64(out))
+			}
+		}
+	})
+}
+
 func BenchmarkBitboardReverse(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		u := uint64(9223372036854775807)
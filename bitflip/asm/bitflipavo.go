@@ -1,13 +1,39 @@
 package main
 
 import (
+	"flag"
+	"log"
 	"math/bits"
+	"os"
 
 	. "github.com/mmcloughlin/avo/build"
 	. "github.com/mmcloughlin/avo/operand"
+	"github.com/mmcloughlin/avo/printer"
 	"github.com/mmcloughlin/avo/reg"
 )
 
+var generic = flag.String("generic", "", "write the pure Go kernel to `file`")
+
+// genericSource is the pure Go kernel, used when the CPU doesn't have AVX.
+const genericSource = `package bitflip
+
+import "math/bits"
+
+func reverse64Generic(x uint64) uint64 {
+	return bits.Reverse64(x)
+}
+`
+
+func writeGeneric() {
+	if *generic == "" {
+		return
+	}
+	src := "// " + printer.NewGoRunConfig().GeneratedWarning() + "\n\n" + genericSource
+	if err := os.WriteFile(*generic, []byte(src), 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
 //const cm0 = 0x5555555555555555 // 01010101 ...
 //const cm1 = 0x3333333333333333 // 00110011 ...
 //const cm2 = 0x0f0f0f0f0f0f0f0f // 00001111 ...
@@ -72,8 +98,8 @@ func main() {
 	DATA(32, U64(cm2le[1]))
 	DATA(40, U64(cm2le[0]))
 
-	TEXT("Reverse64AVX", NOSPLIT, "func(x uint64) uint64")
-	Doc("Flips the bytes in x, MSB->LSB and vice-versa")
+	TEXT("reverse64AVX", NOSPLIT, "func(x uint64) uint64")
+	Doc("Flips the bits in x, MSB->LSB and vice-versa")
 	x := Load(Param("x"), GP64())
 	out := GP64()
 	data := XMM()
@@ -130,4 +156,5 @@ func main() {
 	Store(out, ReturnIndex(0))
 	RET()
 	Generate()
+	writeGeneric()
 }
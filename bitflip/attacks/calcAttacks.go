@@ -1,11 +1,40 @@
 package main
 
 import (
+	"flag"
+	"log"
+	"os"
+
 	. "github.com/mmcloughlin/avo/build"
 	. "github.com/mmcloughlin/avo/operand"
+	"github.com/mmcloughlin/avo/printer"
 	"github.com/mmcloughlin/avo/reg"
 )
 
+var generic = flag.String("generic", "", "write the pure Go kernel to `file`")
+
+// genericSource is the pure Go kernel, used when the CPU doesn't have AVX.
+const genericSource = `package bitflip
+
+func queenAttacksGeneric(occupied uint64, location uint64, rank uint64, file uint64, diag uint64, antidiag uint64) uint64 {
+	return bishopRookAttacksGeneric(occupied, location, rank, file) | bishopRookAttacksGeneric(occupied, location, diag, antidiag)
+}
+
+func bishopRookAttacksGeneric(occupied uint64, location uint64, rankOrDiag uint64, fileOrAntiDiag uint64) uint64 {
+	return linearAttack(occupied, location, rankOrDiag) | linearAttack(occupied, location, fileOrAntiDiag)
+}
+`
+
+func writeGeneric() {
+	if *generic == "" {
+		return
+	}
+	src := "// " + printer.NewGoRunConfig().GeneratedWarning() + "\n\n" + genericSource
+	if err := os.WriteFile(*generic, []byte(src), 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
 const shufConstA = 0x0001020304050607
 
 const shufConstAle = 0x0706050403020100
@@ -48,7 +77,7 @@ func main() {
 
 	// Rank, Diag, File, AntiDiag -- here's why: the lanes match
 	// Returns Ortho, Diag
-	TEXT("queenAttacks", NOSPLIT, "func(occupied uint64, location uint64, rank, file, diag, antidiag uint64) uint64")
+	TEXT("queenAttacksAVX", NOSPLIT, "func(occupied uint64, location uint64, rank, file, diag, antidiag uint64) uint64")
 	occ := Load(Param("occupied"), GP64())
 	pos := Load(Param("location"), GP64())
 	rank := Load(Param("rank"), GP64())
@@ -90,7 +119,7 @@ func main() {
 	PAND(maskRight, dataR)
 	Comment("Subtract first half")
 	VPSUBQ(posShift, dataL, nonrevL)
-	VPSUBQ(posShift, dataL, nonrevR)
+	VPSUBQ(posShift, dataR, nonrevR)
 	Comment("Reverse pos")
 	reverse64(posX, rev, shuf)
 	Comment("Shift pos")
@@ -126,10 +155,11 @@ func main() {
 	// Bishop Only
 	doBishopAttacks(bytes)
 	Generate()
+	writeGeneric()
 }
 
 func doBishopAttacks(bytes Mem) {
-	TEXT("bishopRookAttacks", NOSPLIT, "func(occupied uint64, location uint64, rankOrDiag, fileOrAntiDiag uint64) uint64")
+	TEXT("bishopRookAttacksAVX", NOSPLIT, "func(occupied uint64, location uint64, rankOrDiag, fileOrAntiDiag uint64) uint64")
 	occ := Load(Param("occupied"), GP64())
 	pos := Load(Param("location"), GP64())
 	diag := Load(Param("rankOrDiag"), GP64())
@@ -2,41 +2,164 @@ package bitflip
 
 import (
 	"math/bits"
+	"math/rand"
 	"testing"
 )
 
-func TestByteFlip(t *testing.T) {
-	//	in := uint64(0x0123456789abcdef)
+// forEachKernel runs the test with each kernel the CPU supports.
+func forEachKernel(t *testing.T, f func(t *testing.T)) {
+	for _, k := range []Kernel{Generic, AVX, Magic} {
+		if !Supported(k) {
+			continue
+		}
+		t.Run(k.String(), func(t *testing.T) {
+			prev, err := SetKernel(k)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer SetKernel(prev)
+			f(t)
+		})
+	}
+}
 
-	in := uint64(0x58)
-	//in = 0x5555555555555555
-	out := Reverse64AVX(in)
-	exp := bits.Reverse64(in)
-	if out != exp {
-		t.Errorf("Bytes didn't match, got %x expected %x", out, exp)
+// useKernel sets the kernel for a benchmark, skipping it if the CPU
+// doesn't support the kernel.
+func useKernel(b *testing.B, k Kernel) {
+	prev, err := SetKernel(k)
+	if err != nil {
+		b.Skip(err)
 	}
+	b.Cleanup(func() { SetKernel(prev) })
+}
+
+func TestByteFlip(t *testing.T) {
+	forEachKernel(t, func(t *testing.T) {
+		in := uint64(0x58)
+		out := Reverse64AVX(in)
+		exp := bits.Reverse64(in)
+		if out != exp {
+			t.Errorf("Bytes didn't match, got %x expected %x", out, exp)
+		}
+	})
 }
 
 func TestQueenAttacks(t *testing.T) {
-	t.Skip()
-	for i := 0; i < 64; i++ {
-		occ := bbForSquare(27) | bbForSquare(i)
-		exp := queenAttack(occ, i)
-		out := queenAttackAVX(occ, i)
-		if exp != out {
-			t.Errorf("Queen Attack mismatch %d: \ngot %064b\nexp %064b\n", i, out, exp)
+	forEachKernel(t, func(t *testing.T) {
+		for i := 0; i < 64; i++ {
+			occ := bbForSquare(27) | bbForSquare(i)
+			exp := queenAttack(occ, i)
+			out := queenAttackAVX(occ, i)
+			if exp != out {
+				t.Errorf("Queen Attack mismatch %d: \ngot %064b\nexp %064b\n", i, out, exp)
+			}
 		}
-	}
+	})
 }
 
 func TestBishopAttacks(t *testing.T) {
-	for i := 0; i < 64; i++ {
-		occ := bbForSquare(i) | bbForSquare(27)
-		exp := diaAttack(occ, i)
-		out := bishopAttackAVX(occ, i)
-		if exp != out {
-			t.Errorf("Bishop mismatch: \ngot %064b\nexp %064b\n", out, exp)
+	forEachKernel(t, func(t *testing.T) {
+		for i := 0; i < 64; i++ {
+			occ := bbForSquare(i) | bbForSquare(27)
+			exp := diaAttack(occ, i)
+			out := bishopAttackAVX(occ, i)
+			if exp != out {
+				t.Errorf("Bishop mismatch: \ngot %064b\nexp %064b\n", out, exp)
+			}
+		}
+	})
+}
+
+func TestKernelsAgree(t *testing.T) {
+	if !Supported(AVX) {
+		t.Skip("AVX kernel isn't supported on this CPU")
+	}
+	r := rand.New(rand.NewSource(1))
+	for n := 0; n < 10000; n++ {
+		sq := r.Intn(64)
+		// sparse boards are closer to real positions than uniform bits
+		occ := (r.Uint64() & r.Uint64() & r.Uint64()) | bbForSquare(sq)
+		pos := bbForSquare(sq)
+		rank, file := bbRanks[sq&0x7], bbFiles[sq>>3]
+		diag, antidiag := bbDiagonals[sq], bbAntiDiagonals[sq]
+		if exp, out := reverse64Generic(occ), reverse64AVX(occ); exp != out {
+			t.Fatalf("reverse of %016x: generic %016x, avx %016x", occ, exp, out)
+		}
+		if exp, out := queenAttacksGeneric(occ, pos, rank, file, diag, antidiag), queenAttacksAVX(occ, pos, rank, file, diag, antidiag); exp != out {
+			t.Fatalf("queen on %d with %016x: generic %016x, avx %016x", sq, occ, exp, out)
+		}
+		if exp, out := bishopRookAttacksGeneric(occ, pos, diag, antidiag), bishopRookAttacksAVX(occ, pos, diag, antidiag); exp != out {
+			t.Fatalf("bishop on %d with %016x: generic %016x, avx %016x", sq, occ, exp, out)
+		}
+		if exp, out := bishopRookAttacksGeneric(occ, pos, rank, file), bishopRookAttacksAVX(occ, pos, rank, file); exp != out {
+			t.Fatalf("rook on %d with %016x: generic %016x, avx %016x", sq, occ, exp, out)
+		}
+	}
+}
+
+func TestMagicAttacks(t *testing.T) {
+	if !Supported(Magic) {
+		t.Skip("built without the magic kernel")
+	}
+	r := rand.New(rand.NewSource(2))
+	for n := 0; n < 100000; n++ {
+		sq := r.Intn(64)
+		occ := r.Uint64() & r.Uint64()
+		if n%2 == 0 {
+			occ &= r.Uint64()
+		}
+		pos := bbForSquare(sq)
+		rank, file := bbRank1<<(sq&^7), bbFileA<<(sq&7)
+		if exp, out := bishopRookAttacksGeneric(occ, pos, rank, file), rookAttacksMagic(occ, sq); exp != out {
+			t.Fatalf("rook on %d with %016x: generic %016x, magic %016x", sq, occ, exp, out)
+		}
+		if exp, out := bishopRookAttacksGeneric(occ, pos, bbDiagonals[sq], bbAntiDiagonals[sq]), bishopAttacksMagic(occ, sq); exp != out {
+			t.Fatalf("bishop on %d with %016x: generic %016x, magic %016x", sq, occ, exp, out)
+		}
+	}
+}
+
+func TestSquareAttacks(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+	type sample struct {
+		occ          uint64
+		sq           int
+		rook, bishop uint64
+	}
+	samples := make([]sample, 1000)
+	prev, _ := SetKernel(Generic)
+	for i := range samples {
+		s := &samples[i]
+		s.occ, s.sq = r.Uint64()&r.Uint64(), r.Intn(64)
+		s.rook, s.bishop = RookAttacks(s.occ, s.sq), BishopAttacks(s.occ, s.sq)
+	}
+	SetKernel(prev)
+	forEachKernel(t, func(t *testing.T) {
+		for _, s := range samples {
+			if out := RookAttacks(s.occ, s.sq); out != s.rook {
+				t.Fatalf("rook on %d with %016x: expected %016x but got %016x", s.sq, s.occ, s.rook, out)
+			}
+			if out := BishopAttacks(s.occ, s.sq); out != s.bishop {
+				t.Fatalf("bishop on %d with %016x: expected %016x but got %016x", s.sq, s.occ, s.bishop, out)
+			}
 		}
+	})
+}
+
+func TestSetKernel(t *testing.T) {
+	prev, err := SetKernel(Generic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer SetKernel(prev)
+	if ActiveKernel() != Generic {
+		t.Fatalf("expected the generic kernel but got %s", ActiveKernel())
+	}
+	if _, err := SetKernel(Kernel(99)); err == nil {
+		t.Fatal("expected an error setting an unknown kernel")
+	}
+	if ActiveKernel() != Generic {
+		t.Fatalf("expected a failed SetKernel to keep the generic kernel but got %s", ActiveKernel())
 	}
 }
 
@@ -48,7 +171,15 @@ func BenchmarkReverse64Go(b *testing.B) {
 	}
 }
 
+func BenchmarkReverse64Generic(b *testing.B) {
+	useKernel(b, Generic)
+	for n := 0; n < b.N; n++ {
+		Reverse64AVX(benchin)
+	}
+}
+
 func BenchmarkReverse64AVX(b *testing.B) {
+	useKernel(b, AVX)
 	for n := 0; n < b.N; n++ {
 		Reverse64AVX(benchin)
 	}
@@ -78,7 +209,26 @@ func BenchmarkRookAttackGo(b *testing.B) {
 	}
 }
 
+func BenchmarkQueenAttackGeneric(b *testing.B) {
+	useKernel(b, Generic)
+	sq := sqInt(4, 4)
+	occ := bbForSquare(sq)
+	for n := 0; n < b.N; n++ {
+		queenAttackAVX(occ, sq)
+	}
+}
+
+func BenchmarkBishopAttackGeneric(b *testing.B) {
+	useKernel(b, Generic)
+	sq := sqInt(4, 4)
+	occ := bbForSquare(sq)
+	for n := 0; n < b.N; n++ {
+		bishopAttackAVX(occ, sq)
+	}
+}
+
 func BenchmarkQueenAttackAVX(b *testing.B) {
+	useKernel(b, AVX)
 	sq := sqInt(4, 4)
 	occ := bbForSquare(sq)
 	for n := 0; n < b.N; n++ {
@@ -87,6 +237,7 @@ func BenchmarkQueenAttackAVX(b *testing.B) {
 }
 
 func BenchmarkBishopAttackAVX(b *testing.B) {
+	useKernel(b, AVX)
 	sq := sqInt(4, 4)
 	occ := bbForSquare(sq)
 	for n := 0; n < b.N; n++ {
@@ -113,3 +264,20 @@ func bishopAttackAVX(occupied uint64, sq int) uint64 {
 	adMask := bbAntiDiagonals[sq]
 	return BishopRookAttacks(occupied, pos, diagMask, adMask)
 }
+
+// benchmarkSquareAttacks times a rook and a bishop lookup on a busy
+// middlegame board.
+func benchmarkSquareAttacks(b *testing.B, k Kernel) {
+	useKernel(b, k)
+	const occ = 0xbdf7_0824_1024_e7bd
+	var sink uint64
+	for n := 0; n < b.N; n++ {
+		sq := n & 63
+		sink ^= RookAttacks(occ, sq) | BishopAttacks(occ, sq)
+	}
+	_ = sink
+}
+
+func BenchmarkSquareAttacksGeneric(b *testing.B) { benchmarkSquareAttacks(b, Generic) }
+func BenchmarkSquareAttacksAVX(b *testing.B)     { benchmarkSquareAttacks(b, AVX) }
+func BenchmarkSquareAttacksMagic(b *testing.B)   { benchmarkSquareAttacks(b, Magic) }
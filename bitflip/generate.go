@@ -1,4 +1,5 @@
 package bitflip
 
-//go:generate go run ./asm/bitflipavo.go -out bitflip_amd64.s -stubs bitflip_amd64.go
-//go:generate go run ./attacks/calcAttacks.go -out attacks_amd64.s -stubs attacks_amd64.go
+//go:generate go run ./asm/bitflipavo.go -out bitflip_amd64.s -stubs bitflip_amd64.go -generic bitflip_generic.go
+//go:generate go run ./attacks/calcAttacks.go -out attacks_amd64.s -stubs attacks_amd64.go -generic attacks_generic.go
+//go:generate go run ./magic/findMagics.go -out magic_numbers.go
@@ -0,0 +1,87 @@
+package bitflip
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Kernel is a set of implementations of the package's functions.
+type Kernel int
+
+const (
+	// Generic is the pure Go kernel, which runs on any CPU.
+	Generic Kernel = iota
+	// AVX is the assembly kernel for amd64 CPUs with AVX and SSE4.1.
+	AVX
+	// Magic looks up the attacks of RookAttacks and BishopAttacks in
+	// tables built when the package is loaded, and runs on any CPU.  The
+	// functions taking masks use the generic kernel with it.  The nomagic
+	// build tag leaves it out.
+	Magic
+)
+
+var kernelNames = map[Kernel]string{
+	Generic: "generic",
+	AVX:     "avx",
+	Magic:   "magic",
+}
+
+func (k Kernel) String() string {
+	if name, ok := kernelNames[k]; ok {
+		return name
+	}
+	return fmt.Sprintf("Kernel(%d)", int(k))
+}
+
+// Supported reports whether the kernel can run on this CPU.
+func Supported(k Kernel) bool {
+	switch k {
+	case Generic:
+		return true
+	case AVX:
+		return hasAVX
+	case Magic:
+		return hasMagic
+	}
+	return false
+}
+
+// kernel is the kernel in use, picked when the package is loaded.
+var kernel = detectKernel()
+
+// detectKernel returns the kernel named by the BITFLIP_KERNEL environment
+// variable if it is supported, or the fastest supported kernel.
+func detectKernel() Kernel {
+	if name := os.Getenv("BITFLIP_KERNEL"); name != "" {
+		for k, n := range kernelNames {
+			if strings.EqualFold(name, n) && Supported(k) {
+				return k
+			}
+		}
+	}
+	for _, k := range []Kernel{Magic, AVX} {
+		if Supported(k) {
+			return k
+		}
+	}
+	return Generic
+}
+
+// ActiveKernel returns the kernel in use.
+func ActiveKernel() Kernel {
+	return kernel
+}
+
+// SetKernel makes the package use the kernel and returns the one used
+// before, so tests and benchmarks can compare them.  It returns an error
+// if the CPU doesn't support the kernel.  It isn't safe to call while
+// other goroutines use the package.
+func SetKernel(k Kernel) (Kernel, error) {
+	if !Supported(k) {
+		return kernel, fmt.Errorf("bitflip: %s kernel isn't supported on this CPU", k)
+	}
+	prev := kernel
+	kernel = k
+	return prev, nil
+}
@@ -0,0 +1,7 @@
+package bitflip
+
+import "golang.org/x/sys/cpu"
+
+// hasAVX reports whether the CPU and OS support the instructions of the
+// AVX kernel.
+var hasAVX = cpu.X86.HasAVX && cpu.X86.HasSSE3 && cpu.X86.HasSSSE3 && cpu.X86.HasSSE41
@@ -0,0 +1,21 @@
+//go:build !amd64
+// +build !amd64
+
+package bitflip
+
+const hasAVX = false
+
+// The AVX kernel only exists on amd64, and Supported keeps these from
+// being called elsewhere.
+
+func reverse64AVX(x uint64) uint64 {
+	panic("bitflip: AVX kernel is not available")
+}
+
+func queenAttacksAVX(occupied uint64, location uint64, rank uint64, file uint64, diag uint64, antidiag uint64) uint64 {
+	panic("bitflip: AVX kernel is not available")
+}
+
+func bishopRookAttacksAVX(occupied uint64, location uint64, rankOrDiag uint64, fileOrAntiDiag uint64) uint64 {
+	panic("bitflip: AVX kernel is not available")
+}
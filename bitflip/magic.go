@@ -0,0 +1,20 @@
+package bitflip
+
+// RookAttacks returns the squares a rook on sq attacks, with squares
+// numbered from a1 to h8 along the ranks.  It looks them up in the magic
+// tables with the Magic kernel and computes them with the others.
+func RookAttacks(occupied uint64, sq int) uint64 {
+	if kernel == Magic {
+		return rookAttacksMagic(occupied, sq)
+	}
+	return BishopRookAttacks(occupied, bbForSquare(sq), bbRank1<<(sq&^7), bbFileA<<(sq&7))
+}
+
+// BishopAttacks returns the squares a bishop on sq attacks, with squares
+// numbered from a1 to h8 along the ranks.
+func BishopAttacks(occupied uint64, sq int) uint64 {
+	if kernel == Magic {
+		return bishopAttacksMagic(occupied, sq)
+	}
+	return BishopRookAttacks(occupied, bbForSquare(sq), bbDiagonals[sq], bbAntiDiagonals[sq])
+}
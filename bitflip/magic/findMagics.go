@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"log"
+	"math/bits"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var out = flag.String("out", "", "write the magic numbers to `file`")
+
+var (
+	rookDirections   = [4][2]int{{1, 0}, {-1, 0}, {0, 1}, {0, -1}}
+	bishopDirections = [4][2]int{{1, 1}, {1, -1}, {-1, 1}, {-1, -1}}
+)
+
+// attacks returns the squares a slider on sq attacks, stopping at the
+// first occupied square in each direction.  Squares are numbered from a1
+// to h8 along the ranks.
+func attacks(sq int, occupied uint64, dirs [4][2]int) uint64 {
+	var a uint64
+	for _, d := range dirs {
+		f, r := sq&7+d[0], sq>>3+d[1]
+		for f >= 0 && f < 8 && r >= 0 && r < 8 {
+			b := uint64(1) << (r<<3 | f)
+			a |= b
+			if occupied&b != 0 {
+				break
+			}
+			f, r = f+d[0], r+d[1]
+		}
+	}
+	return a
+}
+
+// relevantMask returns the squares whose occupancy changes the attacks,
+// which leaves out the last square in each direction.
+func relevantMask(sq int, dirs [4][2]int) uint64 {
+	var m uint64
+	for _, d := range dirs {
+		f, r := sq&7+d[0], sq>>3+d[1]
+		for f+d[0] >= 0 && f+d[0] < 8 && r+d[1] >= 0 && r+d[1] < 8 {
+			m |= uint64(1) << (r<<3 | f)
+			f, r = f+d[0], r+d[1]
+		}
+	}
+	return m
+}
+
+// xorshift is a small deterministic generator so the output is the same
+// on every run.
+type xorshift uint64
+
+func (x *xorshift) next() uint64 {
+	*x ^= *x >> 12
+	*x ^= *x << 25
+	*x ^= *x >> 27
+	return uint64(*x) * 2685821657736338717
+}
+
+// findMagic returns a multiplier that maps every occupancy of the mask to
+// an index of its own, or one sharing the same attacks.
+func findMagic(sq int, dirs [4][2]int, rng *xorshift) uint64 {
+	mask := relevantMask(sq, dirs)
+	n := bits.OnesCount64(mask)
+	shift := 64 - n
+	var occs, atts []uint64
+	for sub := uint64(0); ; {
+		occs = append(occs, sub)
+		atts = append(atts, attacks(sq, sub, dirs))
+		sub = (sub - mask) & mask
+		if sub == 0 {
+			break
+		}
+	}
+	used := make([]uint64, 1<<n)
+	epoch := make([]int, 1<<n)
+	for try := 1; ; try++ {
+		magic := rng.next() & rng.next() & rng.next()
+		if bits.OnesCount64((mask*magic)>>56) < 6 {
+			continue
+		}
+		ok := true
+		for i, occ := range occs {
+			idx := (occ * magic) >> shift
+			if epoch[idx] != try {
+				epoch[idx] = try
+				used[idx] = atts[i]
+			} else if used[idx] != atts[i] {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return magic
+		}
+	}
+}
+
+func main() {
+	flag.Parse()
+	rng := xorshift(0x9e3779b97f4a7c15)
+	var rooks, bishops [64]uint64
+	for sq := 0; sq < 64; sq++ {
+		rooks[sq] = findMagic(sq, rookDirections, &rng)
+		bishops[sq] = findMagic(sq, bishopDirections, &rng)
+	}
+	var buf bytes.Buffer
+	args := append([]string{"go", "run", "findMagics.go"}, os.Args[1:]...)
+	fmt.Fprintf(&buf, "// Code generated by command: %s. DO NOT EDIT.\n\n", strings.Join(args, " "))
+	buf.WriteString("//go:build !nomagic\n// +build !nomagic\n\npackage bitflip\n\n")
+	writeTable(&buf, "rookMagicNumbers", rooks)
+	writeTable(&buf, "bishopMagicNumbers", bishops)
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *out == "" {
+		os.Stdout.Write(src)
+		return
+	}
+	if err := os.WriteFile(filepath.Clean(*out), src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func writeTable(buf *bytes.Buffer, name string, magics [64]uint64) {
+	fmt.Fprintf(buf, "var %s = [64]uint64{\n", name)
+	for i, m := range magics {
+		fmt.Fprintf(buf, "0x%016x,", m)
+		if i%4 == 3 {
+			buf.WriteString("\n")
+		} else {
+			buf.WriteString(" ")
+		}
+	}
+	buf.WriteString("}\n\n")
+}
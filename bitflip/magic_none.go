@@ -0,0 +1,17 @@
+//go:build nomagic
+// +build nomagic
+
+package bitflip
+
+const hasMagic = false
+
+// The magic tables are left out by the nomagic build tag, and Supported
+// keeps these from being called.
+
+func rookAttacksMagic(occupied uint64, sq int) uint64 {
+	panic("bitflip: built without the magic kernel")
+}
+
+func bishopAttacksMagic(occupied uint64, sq int) uint64 {
+	panic("bitflip: built without the magic kernel")
+}
@@ -0,0 +1,90 @@
+//go:build !nomagic
+// +build !nomagic
+
+package bitflip
+
+import "math/bits"
+
+const hasMagic = true
+
+// magicEntry finds the attacks of a slider on one square: the occupancy of
+// the mask times the magic number, shifted down, indexes its attacks.
+type magicEntry struct {
+	mask    uint64
+	magic   uint64
+	shift   uint
+	attacks []uint64
+}
+
+var (
+	rookMagics   [64]magicEntry
+	bishopMagics [64]magicEntry
+
+	rookDirections   = [4][2]int{{1, 0}, {-1, 0}, {0, 1}, {0, -1}}
+	bishopDirections = [4][2]int{{1, 1}, {1, -1}, {-1, 1}, {-1, -1}}
+)
+
+func init() {
+	initMagics(&rookMagics, &rookMagicNumbers, rookDirections)
+	initMagics(&bishopMagics, &bishopMagicNumbers, bishopDirections)
+}
+
+// initMagics fills in the attacks of every occupancy of every square.
+func initMagics(entries *[64]magicEntry, numbers *[64]uint64, dirs [4][2]int) {
+	for sq := range entries {
+		e := &entries[sq]
+		e.mask = relevantMask(sq, dirs)
+		e.magic = numbers[sq]
+		e.shift = uint(64 - bits.OnesCount64(e.mask))
+		e.attacks = make([]uint64, 1<<bits.OnesCount64(e.mask))
+		for sub := uint64(0); ; {
+			e.attacks[(sub*e.magic)>>e.shift] = slidingAttacks(sq, sub, dirs)
+			sub = (sub - e.mask) & e.mask
+			if sub == 0 {
+				break
+			}
+		}
+	}
+}
+
+// slidingAttacks returns the squares a slider on sq attacks, stopping at
+// the first occupied square in each direction.
+func slidingAttacks(sq int, occupied uint64, dirs [4][2]int) uint64 {
+	var a uint64
+	for _, d := range dirs {
+		f, r := sq&7+d[0], sq>>3+d[1]
+		for f >= 0 && f < 8 && r >= 0 && r < 8 {
+			b := uint64(1) << (r<<3 | f)
+			a |= b
+			if occupied&b != 0 {
+				break
+			}
+			f, r = f+d[0], r+d[1]
+		}
+	}
+	return a
+}
+
+// relevantMask returns the squares whose occupancy changes the attacks,
+// which leaves out the last square in each direction.
+func relevantMask(sq int, dirs [4][2]int) uint64 {
+	var m uint64
+	for _, d := range dirs {
+		f, r := sq&7+d[0], sq>>3+d[1]
+		for f+d[0] >= 0 && f+d[0] < 8 && r+d[1] >= 0 && r+d[1] < 8 {
+			m |= uint64(1) << (r<<3 | f)
+			f, r = f+d[0], r+d[1]
+		}
+	}
+	return m
+}
+
+func rookAttacksMagic(occupied uint64, sq int) uint64 {
+	e := &rookMagics[sq]
+	return e.attacks[((occupied&e.mask)*e.magic)>>e.shift]
+}
+
+func bishopAttacksMagic(occupied uint64, sq int) uint64 {
+	e := &bishopMagics[sq]
+	return e.attacks[((occupied&e.mask)*e.magic)>>e.shift]
+}
@@ -1,9 +1,24 @@
 package bitflip
 
+// Reverse64AVX flips the bits in x, MSB->LSB and vice-versa, with the AVX
+// kernel when it is in use.
+func Reverse64AVX(x uint64) uint64 {
+	if kernel == AVX {
+		return reverse64AVX(x)
+	}
+	return reverse64Generic(x)
+}
+
 func QueenAttacks(occupied uint64, location uint64, rank uint64, file uint64, diag uint64, antidiag uint64) uint64 {
-	return queenAttacks(occupied, location, rank, file, diag, antidiag)
+	if kernel == AVX {
+		return queenAttacksAVX(occupied, location, rank, file, diag, antidiag)
+	}
+	return queenAttacksGeneric(occupied, location, rank, file, diag, antidiag)
 }
 
 func BishopRookAttacks(occupied uint64, location uint64, rankOrDiag uint64, fileOrAntiDiag uint64) uint64 {
-	return bishopRookAttacks(occupied, location, rankOrDiag, fileOrAntiDiag)
+	if kernel == AVX {
+		return bishopRookAttacksAVX(occupied, location, rankOrDiag, fileOrAntiDiag)
+	}
+	return bishopRookAttacksGeneric(occupied, location, rankOrDiag, fileOrAntiDiag)
 }
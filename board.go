@@ -226,6 +226,12 @@ func (b *Board) update(m Move) {
 	if p1 == NoPiece {
 		p1 = b.Piece(m.S1())
 	}
+	// place a dropped piece
+	if m.HasTag(Drop) {
+		b.setBBForPiece(p1, b.bbForPiece(p1)|bbForSquare(m.S2()))
+		b.occupiedCache = 0
+		return
+	}
 	s1BB := bbForSquare(m.S1())
 	s2BB := bbForSquare(m.S2())
 
@@ -0,0 +1,260 @@
+package chess
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// An IncrementMode is how a time control gives a player time for each
+// move.
+type IncrementMode uint8
+
+const (
+	// FischerIncrement adds the increment to the player's clock after each
+	// of their moves.  It's written 300+3 in a TimeControl tag.
+	FischerIncrement IncrementMode = iota
+	// SimpleDelay waits for the delay before the player's clock starts
+	// running on each move, as in US delay.  It's written 300d3.
+	SimpleDelay
+	// BronsteinDelay adds the time the player used on each move back to
+	// their clock, up to the delay.  It's written 300b3.
+	BronsteinDelay
+)
+
+// A TimeControlPeriod is a number of moves to be made in a time, with an
+// increment or delay for each move.
+type TimeControlPeriod struct {
+	// Moves is the number of moves of the period, or 0 if it lasts for
+	// the rest of the game.
+	Moves     int
+	Time      time.Duration
+	Increment time.Duration
+	Mode      IncrementMode
+}
+
+// A TimeControl is the periods of a timed game, in the order they're
+// played.  When a player makes the moves of a period, the time of the next
+// one is added to their clock, and the last period repeats.  The zero
+// TimeControl is untimed.
+type TimeControl struct {
+	Periods []TimeControlPeriod
+}
+
+// ParseTimeControl parses the value of a PGN TimeControl tag, which has
+// periods separated by colons: 300+3 is five minutes with a three second
+// increment, and 40/7200:3600 is forty moves in two hours and then an
+// hour for the rest of the game.  A d or b in place of the + is a simple
+// or Bronstein delay, and - is untimed.  Sandclock and unknown ("?") time
+// controls aren't supported.
+func ParseTimeControl(s string) (TimeControl, error) {
+	s = strings.TrimSpace(s)
+	if s == "-" {
+		return TimeControl{}, nil
+	}
+	err := fmt.Errorf("chess: invalid time control %q", s)
+	tc := TimeControl{}
+	for _, field := range strings.Split(s, ":") {
+		p := TimeControlPeriod{}
+		if moves, rest, ok := strings.Cut(field, "/"); ok {
+			n, convErr := strconv.Atoi(moves)
+			if convErr != nil || n <= 0 {
+				return TimeControl{}, err
+			}
+			p.Moves, field = n, rest
+		}
+		base, increment := field, ""
+		if i := strings.IndexAny(field, "+db"); i != -1 {
+			base, increment = field[:i], field[i+1:]
+			p.Mode = map[byte]IncrementMode{'+': FischerIncrement, 'd': SimpleDelay, 'b': BronsteinDelay}[field[i]]
+		}
+		var ok bool
+		if p.Time, ok = parseSeconds(base); !ok || p.Time == 0 {
+			return TimeControl{}, err
+		}
+		if increment != "" {
+			if p.Increment, ok = parseSeconds(increment); !ok {
+				return TimeControl{}, err
+			}
+		}
+		tc.Periods = append(tc.Periods, p)
+	}
+	return tc, nil
+}
+
+func parseSeconds(s string) (time.Duration, bool) {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || f < 0 {
+		return 0, false
+	}
+	return time.Duration(f * float64(time.Second)), true
+}
+
+// Timed returns true if the time control has a period.
+func (tc TimeControl) Timed() bool {
+	return len(tc.Periods) > 0
+}
+
+// String returns the time control in the format of the PGN TimeControl
+// tag.
+func (tc TimeControl) String() string {
+	if !tc.Timed() {
+		return "-"
+	}
+	fields := make([]string, len(tc.Periods))
+	for i, p := range tc.Periods {
+		var sb strings.Builder
+		if p.Moves > 0 {
+			fmt.Fprintf(&sb, "%d/", p.Moves)
+		}
+		sb.WriteString(formatSeconds(p.Time))
+		if p.Increment > 0 || p.Mode != FischerIncrement {
+			sb.WriteByte("+db"[p.Mode])
+			sb.WriteString(formatSeconds(p.Increment))
+		}
+		fields[i] = sb.String()
+	}
+	return strings.Join(fields, ":")
+}
+
+func formatSeconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', -1, 64)
+}
+
+// gameClock is the state of the clocks of a timed game.
+type gameClock struct {
+	control TimeControl
+	// remaining is each player's time at the start of their turn
+	remaining [2]time.Duration
+	// period is the index of each player's period in the time control,
+	// and moves the number of moves they made in it
+	period [2]int
+	moves  [2]int
+	// last is when the player to move started their turn
+	last time.Time
+}
+
+func newGameClock(tc TimeControl, start time.Time) *gameClock {
+	c := &gameClock{control: tc, last: start}
+	c.remaining[White] = tc.Periods[0].Time
+	c.remaining[Black] = tc.Periods[0].Time
+	return c
+}
+
+// timeLeft returns the time the player has left at the time, if it's
+// their turn.
+func (c *gameClock) timeLeft(color Color, at time.Time) time.Duration {
+	elapsed := at.Sub(c.last)
+	p := c.control.Periods[c.period[color]]
+	if p.Mode == SimpleDelay {
+		elapsed -= p.Increment
+		if elapsed < 0 {
+			elapsed = 0
+		}
+	}
+	return c.remaining[color] - elapsed
+}
+
+// move stops the player's clock at the time they made a move, adding the
+// increment of their period and the time of the next one if they finished
+// it, and starts their opponent's.
+func (c *gameClock) move(color Color, at time.Time) {
+	p := c.control.Periods[c.period[color]]
+	left := c.timeLeft(color, at)
+	switch p.Mode {
+	case FischerIncrement:
+		left += p.Increment
+	case BronsteinDelay:
+		used := at.Sub(c.last)
+		if used > p.Increment {
+			used = p.Increment
+		}
+		left += used
+	}
+	c.moves[color]++
+	if p.Moves > 0 && c.moves[color] == p.Moves {
+		if c.period[color] < len(c.control.Periods)-1 {
+			c.period[color]++
+		}
+		c.moves[color] = 0
+		left += c.control.Periods[c.period[color]].Time
+	}
+	c.remaining[color] = left
+	c.last = at
+}
+
+// StartClock starts a clock for each player with the time control, the
+// player to move's running from the start time, and sets the game's
+// TimeControl tag pair.  Moves must then be made with MoveAt or
+// MoveStrAt, with the time they were made, to advance the clocks.
+func (g *Game) StartClock(tc TimeControl, start time.Time) {
+	g.AddTagPair("TimeControl", tc.String())
+	if !tc.Timed() {
+		g.clock = nil
+		return
+	}
+	g.clock = newGameClock(tc, start)
+}
+
+// TimeLeft returns the time the player has left at the time, which only
+// runs down on their turn.  It returns 0 if the game has no clock.
+func (g *Game) TimeLeft(c Color, at time.Time) time.Duration {
+	if g.clock == nil || (c != White && c != Black) {
+		return 0
+	}
+	if c != g.pos.turn {
+		return g.clock.remaining[c]
+	}
+	return g.clock.timeLeft(c, at)
+}
+
+// CheckFlag ends the game by Timeout if the player to move has run out of
+// time at the time, and returns true if they have.  The game is drawn by
+// TimeoutVsInsufficientMaterial instead if their opponent can't
+// checkmate, as with a lone king.
+func (g *Game) CheckFlag(at time.Time) bool {
+	if g.clock == nil || g.outcome != NoOutcome {
+		return false
+	}
+	if g.clock.timeLeft(g.pos.turn, at) > 0 {
+		return false
+	}
+	g.Forfeit(g.pos.turn, Timeout)
+	return true
+}
+
+// clockMove advances the clock for a move made at the time, and returns
+// the [%clk] comment with the time the player has left.  An error is
+// returned if their flag fell first.
+func (g *Game) clockMove(at time.Time) (string, error) {
+	if g.CheckFlag(at) {
+		return "", fmt.Errorf("chess: flag fell before the move at %s", at.Format(time.RFC3339))
+	}
+	turn := g.pos.turn
+	g.clock.move(turn, at)
+	return fmt.Sprintf("[%%clk %s]", formatClock(g.clock.remaining[turn])), nil
+}
+
+// formatClock returns the time like 1:02:03, as in a %clk command, with
+// negative times as 0:00:00.
+func formatClock(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+	s := int(d / time.Second)
+	return fmt.Sprintf("%d:%02d:%02d", s/3600, s/60%60, s%60)
+}
+
+// hasMatingMaterial returns false if the color can't checkmate, because
+// it only has its king or neither side has the material to.
+func hasMatingMaterial(b *Board, c Color) bool {
+	pieces := b.whiteSqs()
+	if c == Black {
+		pieces = b.blackSqs()
+	}
+	if pieces == b.bbForPiece(GetPiece(King, c)) {
+		return false
+	}
+	return b.hasSufficientMaterial()
+}
@@ -0,0 +1,184 @@
+package chess
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseTimeControl(t *testing.T) {
+	tests := []struct {
+		s       string
+		periods []TimeControlPeriod
+	}{
+		{"-", nil},
+		{"300+3", []TimeControlPeriod{{Time: 300 * time.Second, Increment: 3 * time.Second}}},
+		{"40/7200:3600", []TimeControlPeriod{
+			{Moves: 40, Time: 7200 * time.Second},
+			{Time: 3600 * time.Second},
+		}},
+		{"40/5400+30:1800+30", []TimeControlPeriod{
+			{Moves: 40, Time: 5400 * time.Second, Increment: 30 * time.Second},
+			{Time: 1800 * time.Second, Increment: 30 * time.Second},
+		}},
+		{"300d5", []TimeControlPeriod{{Time: 300 * time.Second, Increment: 5 * time.Second, Mode: SimpleDelay}}},
+		{"300b5", []TimeControlPeriod{{Time: 300 * time.Second, Increment: 5 * time.Second, Mode: BronsteinDelay}}},
+		{"15+0.5", []TimeControlPeriod{{Time: 15 * time.Second, Increment: 500 * time.Millisecond}}},
+	}
+	for _, test := range tests {
+		tc, err := ParseTimeControl(test.s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(tc.Periods) != len(test.periods) {
+			t.Fatalf("expected %s to have %d periods but got %v", test.s, len(test.periods), tc.Periods)
+		}
+		for i, p := range tc.Periods {
+			if p != test.periods[i] {
+				t.Fatalf("expected period %d of %s to be %+v but got %+v", i, test.s, test.periods[i], p)
+			}
+		}
+		if tc.String() != test.s {
+			t.Fatalf("expected %s but got %s", test.s, tc.String())
+		}
+	}
+	for _, s := range []string{"", "?", "*180", "abc", "40/", "0/300", "300+x", "-300", "0+3"} {
+		if _, err := ParseTimeControl(s); err == nil {
+			t.Fatalf("expected error from %q", s)
+		}
+	}
+}
+
+func TestGameClock(t *testing.T) {
+	tc, _ := ParseTimeControl("60+2")
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	g := NewGame()
+	g.StartClock(tc, start)
+	if err := g.MoveStrAt("e4", start.Add(5*time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.MoveStrAt("e5", start.Add(15*time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if left := g.TimeLeft(White, start.Add(20*time.Second)); left != 52*time.Second {
+		t.Fatalf("expected white to have 52s left but got %s", left)
+	}
+	if left := g.TimeLeft(Black, start.Add(20*time.Second)); left != 52*time.Second {
+		t.Fatalf("expected black to have 52s left but got %s", left)
+	}
+	if g.CheckFlag(start.Add(60 * time.Second)) {
+		t.Fatal("expected white's flag to still be up")
+	}
+	if err := g.MoveStrAt("Nf3", start.Add(73*time.Second)); err == nil {
+		t.Fatal("expected the flag to fall before Nf3")
+	}
+	if g.Outcome() != BlackWon || g.Method() != Timeout {
+		t.Fatalf("expected black to win on time but got %s by %s", g.Outcome(), g.Method())
+	}
+	if len(g.Moves()) != 2 {
+		t.Fatalf("expected the late move to not be played but got %v", g.Moves())
+	}
+	pgn := g.String()
+	for _, s := range []string{`[TimeControl "60+2"]`, "{ [%clk 0:00:57] }", "{ [%clk 0:00:52] }"} {
+		if !strings.Contains(pgn, s) {
+			t.Fatalf("expected %s in %s", s, pgn)
+		}
+	}
+}
+
+func TestGameClockRejectsMoves(t *testing.T) {
+	tc, _ := ParseTimeControl("60")
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	g := NewGame()
+	g.StartClock(tc, start)
+	var move func(Move) error = g.Move
+	if err := move(NewMove(E2, E4, NoPromo)); err == nil {
+		t.Fatal("expected a move without a time to be rejected")
+	}
+	if err := g.MoveStr("e4"); err == nil {
+		t.Fatal("expected a move string without a time to be rejected")
+	}
+	if err := g.MoveStrAt("e4", start.Add(70*time.Second)); err == nil {
+		t.Fatal("expected the flag to fall before e4")
+	}
+	// retrying the move once the game is over fails too
+	if err := g.MoveStrAt("e4", start.Add(70*time.Second)); err == nil {
+		t.Fatal("expected e4 to be rejected after the flag fell")
+	}
+	if len(g.Moves()) != 0 || strings.Contains(g.String(), "%clk") {
+		t.Fatalf("expected no moves to be played but got %s", g.String())
+	}
+	if s := formatClock(-10 * time.Second); s != "0:00:00" {
+		t.Fatalf("expected a negative time to be formatted as 0:00:00 but got %s", s)
+	}
+}
+
+func TestGameClockPeriods(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		tc    string
+		moves []time.Duration
+		left  time.Duration
+	}{
+		// the second period's time is added after two moves
+		{"2/60:30", []time.Duration{10 * time.Second, 10 * time.Second}, 70 * time.Second},
+		// the last period repeats
+		{"1/60", []time.Duration{10 * time.Second, 10 * time.Second}, 160 * time.Second},
+		{"60d5", []time.Duration{3 * time.Second, 8 * time.Second}, 57 * time.Second},
+		{"60b5", []time.Duration{3 * time.Second, 8 * time.Second}, 57 * time.Second},
+	}
+	for _, test := range tests {
+		tc, err := ParseTimeControl(test.tc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		g := NewGame()
+		g.StartClock(tc, start)
+		at := start
+		// white moves the knight out and back, black waits instantly
+		for i, used := range test.moves {
+			at = at.Add(used)
+			if err := g.MoveStrAt([]string{"Nf3", "Ng1"}[i%2], at); err != nil {
+				t.Fatal(err)
+			}
+			if err := g.MoveStrAt([]string{"Nf6", "Ng8"}[i%2], at); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if left := g.TimeLeft(White, at); left != test.left {
+			t.Fatalf("expected white to have %s left with %s but got %s", test.left, test.tc, left)
+		}
+	}
+}
+
+func TestTimeoutVsInsufficientMaterial(t *testing.T) {
+	tc, _ := ParseTimeControl("60")
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		fen     string
+		outcome Outcome
+		method  Method
+	}{
+		{"8/2k5/8/8/8/3K4/4P3/8 w - - 0 1", Draw, TimeoutVsInsufficientMaterial},
+		{"8/2k5/8/8/8/3K4/4P3/8 b - - 0 1", WhiteWon, Timeout},
+		// a knight can mate with the help of the other side's pieces
+		{"8/2kn4/8/8/8/3K4/4P3/8 w - - 0 1", BlackWon, Timeout},
+		{"8/2kb4/8/8/8/3K4/4R3/8 w - - 0 1", BlackWon, Timeout},
+	}
+	for _, test := range tests {
+		g, err := NewGameFromFEN(test.fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		g.StartClock(tc, start)
+		if !g.CheckFlag(start.Add(time.Minute)) {
+			t.Fatalf("expected the flag to fall in %s", test.fen)
+		}
+		if g.Outcome() != test.outcome || g.Method() != test.method {
+			t.Fatalf("expected %s by %s in %s but got %s by %s", test.outcome, test.method, test.fen, g.Outcome(), g.Method())
+		}
+		if tp := g.GetTagPair("Termination"); tp == nil || tp.Value != "time forfeit" {
+			t.Fatalf("expected a time forfeit termination but got %v", tp)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package chess
+
+import (
+	"fmt"
+	"strings"
+)
+
+// dropPieceTypes are the piece types a pocket can hold.
+var dropPieceTypes = []PieceType{Queen, Rook, Bishop, Knight, Pawn}
+
+type crazyhouse struct {
+	standard
+}
+
+func (crazyhouse) Name() string {
+	return "Crazyhouse"
+}
+
+func (crazyhouse) StartingPosition() *Position {
+	return variantPosition(Crazyhouse, startFEN)
+}
+
+func (crazyhouse) moves(pos *Position, first bool) []Move {
+	moves := engine{}.CalcMoves(pos, first)
+	if first && len(moves) > 0 {
+		return moves
+	}
+	return append(moves, dropMoves(pos, first)...)
+}
+
+func (crazyhouse) update(prev, next *Position, m Move) {
+	c := prev.turn
+	if m.HasTag(Drop) {
+		next.pockets[c][m.piece().Type()]--
+		return
+	}
+	s1BB := bbForSquare(m.S1())
+	s2BB := bbForSquare(m.S2())
+	// captured pieces go to the capturer's pocket, promoted ones as pawns
+	if m.HasTag(EnPassant) {
+		next.pockets[c][Pawn]++
+	} else if m.HasTag(Capture) {
+		t := prev.board.Piece(m.S2()).Type()
+		if prev.promoted&s2BB != 0 {
+			t = Pawn
+		}
+		next.pockets[c][t]++
+	}
+	promoted := prev.promoted &^ s2BB
+	if prev.promoted&s1BB != 0 || m.Promo() != NoPromo {
+		promoted = promoted&^s1BB | s2BB
+	}
+	next.promoted = promoted
+}
+
+// the captured pieces are never lost
+func (crazyhouse) insufficientMaterial(pos *Position) bool {
+	b := pos.board
+	return b.occupied() == b.bbForPiece(WhiteKing)|b.bbForPiece(BlackKing) &&
+		pos.pockets == [2][6]uint8{}
+}
+
+// dropMoves returns the legal drops of the pieces in the pocket of the
+// color to move, or only the first one found if first is true.
+func dropMoves(pos *Position, first bool) []Move {
+	moves := []Move{}
+	empty := ^pos.board.occupied()
+	for _, t := range dropPieceTypes {
+		if pos.pockets[pos.turn][t] == 0 {
+			continue
+		}
+		p := GetPiece(t, pos.turn)
+		s2BB := empty
+		if t == Pawn {
+			s2BB &^= bbRank1 | bbRank8
+		}
+		for s2BB != 0 {
+			s2 := bbGetFirstSquare(s2BB)
+			s2BB ^= bbForSquare(s2)
+			m := addTags(NewDrop(p, s2), pos)
+			// filter out drops that don't stop a check
+			if m.HasTag(inCheck) {
+				continue
+			}
+			moves = append(moves, m)
+			if first {
+				return moves
+			}
+		}
+	}
+	return moves
+}
+
+// dropString returns the drop like N@f3, which is both its SAN and its
+// UCI notation.
+func dropString(m Move) string {
+	return strings.ToUpper(m.piece().Type().String()) + "@" + m.S2().String()
+}
+
+// Pocket returns the number of pieces of each type the color holds in a
+// Crazyhouse game, ready to be dropped.
+func (pos *Position) Pocket(c Color) map[PieceType]int {
+	pocket := map[PieceType]int{}
+	if c != White && c != Black {
+		return pocket
+	}
+	for _, t := range dropPieceTypes {
+		if n := pos.pockets[c][t]; n > 0 {
+			pocket[t] = int(n)
+		}
+	}
+	return pocket
+}
+
+// crazyhouseBoardFEN returns the board section of the position's FEN, with
+// promoted pieces marked by a ~ and the pockets in brackets after it:
+// rnbqkb1r/ppp1pppp/8/8/8/8/PPPP1PPP/RNBQKBNR[Pn].
+func crazyhouseBoardFEN(pos *Position) string {
+	var sb strings.Builder
+	for r := 7; r >= 0; r-- {
+		empty := 0
+		for f := 0; f < numOfSquaresInRow; f++ {
+			sq := NewSquare(File(f), Rank(r))
+			p := pos.board.Piece(sq)
+			if p == NoPiece {
+				empty++
+				continue
+			}
+			if empty > 0 {
+				fmt.Fprintf(&sb, "%d", empty)
+				empty = 0
+			}
+			sb.WriteByte(fenReverseMap[p])
+			if pos.promoted&bbForSquare(sq) != 0 {
+				sb.WriteByte('~')
+			}
+		}
+		if empty > 0 {
+			fmt.Fprintf(&sb, "%d", empty)
+		}
+		if r > 0 {
+			sb.WriteByte('/')
+		}
+	}
+	sb.WriteByte('[')
+	for _, c := range []Color{White, Black} {
+		for _, t := range dropPieceTypes {
+			for i := 0; i < int(pos.pockets[c][t]); i++ {
+				sb.WriteByte(fenReverseMap[GetPiece(t, c)])
+			}
+		}
+	}
+	sb.WriteByte(']')
+	return sb.String()
+}
+
+// fenPockets splits the pockets and promoted pieces from the board section
+// of a Crazyhouse FEN.  The pockets are either in brackets after the board,
+// RNBQKBNR[Qp], or a ninth rank, RNBQKBNR/Qp.
+func fenPockets(boardStr string) (string, [2][6]uint8, bitboard, error) {
+	var pockets [2][6]uint8
+	err := fmt.Errorf("chess: fen invalid crazyhouse board %s", boardStr)
+	holdings := ""
+	if strings.HasSuffix(boardStr, "]") {
+		i := strings.Index(boardStr, "[")
+		if i == -1 {
+			return "", pockets, 0, err
+		}
+		boardStr, holdings = boardStr[:i], boardStr[i+1:len(boardStr)-1]
+	} else if ranks := strings.Split(boardStr, "/"); len(ranks) == 9 {
+		boardStr, holdings = strings.Join(ranks[:8], "/"), ranks[8]
+	}
+	for _, r := range holdings {
+		p, ok := fenPieceMap[string(r)]
+		if r == '-' {
+			continue
+		}
+		if !ok || p.Type() == King {
+			return "", pockets, 0, err
+		}
+		pockets[p.Color()][p.Type()]++
+	}
+	// remove the ~ after promoted pieces, keeping track of their squares
+	var promoted bitboard
+	var sb strings.Builder
+	rank, file := 7, 0
+	for _, r := range boardStr {
+		switch {
+		case r == '/':
+			rank, file = rank-1, 0
+		case r == '~':
+			if file == 0 || rank < 0 {
+				return "", pockets, 0, err
+			}
+			promoted |= bbForSquare(NewSquare(File(file-1), Rank(rank)))
+			continue
+		case r >= '1' && r <= '8':
+			file += int(r - '0')
+		default:
+			file++
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String(), pockets, promoted, nil
+}
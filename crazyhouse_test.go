@@ -0,0 +1,157 @@
+package chess
+
+import (
+	"strings"
+	"testing"
+)
+
+/* https://github.com/niklasf/python-chess/blob/master/examples/perft/crazyhouse.perft */
+var crazyhousePerfResults = []perfTest{
+	{pos: Crazyhouse.StartingPosition(), nodesPerDepth: []int{
+		20, 400, 8902, 197281,
+	}},
+	{pos: unsafeVariantFEN(Crazyhouse, "2k5/8/8/8/8/8/8/4K3[QRBNPqrbnp] w - - 0 1"), nodesPerDepth: []int{
+		301, 75353,
+	}},
+	{pos: unsafeVariantFEN(Crazyhouse, "r1bqk2r/pppp1ppp/2n1p3/4P3/1b1Pn3/2NB1N2/PPP2PPP/R1BQK2R[] b KQkq - 0 1"), nodesPerDepth: []int{
+		42, 1347, 58057,
+	}},
+	{pos: unsafeVariantFEN(Crazyhouse, "4k3/1Q~6/8/8/4b3/8/Kpp5/8/ b - - 0 1"), nodesPerDepth: []int{
+		20, 360,
+	}},
+}
+
+func TestCrazyhousePerfResults(t *testing.T) {
+	for _, perf := range crazyhousePerfResults {
+		countMoves(t, perf.pos, []*Position{perf.pos}, perf.nodesPerDepth, len(perf.nodesPerDepth))
+	}
+}
+
+func TestCrazyhouseFENs(t *testing.T) {
+	fens := map[string]string{
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR[] w KQkq - 0 1":                 "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR[] w KQkq - 0 1",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1":                   "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR[] w KQkq - 0 1",
+		"r1bqk2r/pppp1ppp/2n1p3/4P3/1b1Pn3/2NB1N2/PPP2PPP/R1BQK2R/pnNN b KQkq - 0 1": "r1bqk2r/pppp1ppp/2n1p3/4P3/1b1Pn3/2NB1N2/PPP2PPP/R1BQK2R[NNnp] b KQkq - 0 1",
+		"4k3/1Q~6/8/8/4b3/8/Kpp5/8/ b - - 0 1":                                       "4k3/1Q~6/8/8/4b3/8/Kpp5/8[] b - - 0 1",
+	}
+	for fen, want := range fens {
+		pos, err := decodeVariantFEN(Crazyhouse, fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pos.String() != want {
+			t.Fatalf("expected %s to be %s but got %s", fen, want, pos.String())
+		}
+	}
+	for _, fen := range []string{
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR[K] w KQkq - 0 1",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR[X] w KQkq - 0 1",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNRQ] w KQkq - 0 1",
+	} {
+		if _, err := decodeVariantFEN(Crazyhouse, fen); err == nil {
+			t.Fatalf("expected error from %s", fen)
+		}
+	}
+	if _, err := decodeFEN("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR[] w KQkq - 0 1"); err == nil {
+		t.Fatal("expected pockets to be invalid in standard chess")
+	}
+}
+
+func TestCrazyhouseDrops(t *testing.T) {
+	g := NewVariantGame(Crazyhouse)
+	for _, m := range []string{"e4", "d5", "exd5", "Qxd5", "Nc3", "Qa5"} {
+		if err := g.MoveStr(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if pocket := g.Position().Pocket(White); pocket[Pawn] != 1 || len(pocket) != 1 {
+		t.Fatalf("expected white to hold a pawn but got %v", pocket)
+	}
+	if pocket := g.Position().Pocket(Black); pocket[Pawn] != 1 || len(pocket) != 1 {
+		t.Fatalf("expected black to hold a pawn but got %v", pocket)
+	}
+	if err := g.MoveStr("N@f3"); err == nil {
+		t.Fatal("expected a drop of a knight white doesn't hold to be invalid")
+	}
+	if err := g.MoveStr("P@d8"); err == nil {
+		t.Fatal("expected a pawn drop on the eighth rank to be invalid")
+	}
+	if err := g.MoveStr("P@d7+"); err != nil {
+		t.Fatal(err)
+	}
+	moves := g.Moves()
+	if m := moves[len(moves)-1]; !m.HasTag(Drop) || !m.HasTag(Check) || m.String() != "P@d7" {
+		t.Fatalf("expected a checking pawn drop but got %s", m.StringWithTags())
+	}
+	if len(g.Position().Pocket(White)) != 0 {
+		t.Fatalf("expected white's pocket to be empty but got %s", g.FEN())
+	}
+	if err := g.MoveStr("Bxd7"); err != nil {
+		t.Fatal(err)
+	}
+	want := "rn2kbnr/pppbpppp/8/q7/8/2N5/PPPP1PPP/R1BQKBNR[pp] w KQkq - 0 5"
+	if g.FEN() != want {
+		t.Fatalf("expected %s but got %s", want, g.FEN())
+	}
+
+	// drops decode from UCI notation as well
+	m, err := g.Position().DecodeMove("N@f3", UCINotation)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m != NewDrop(WhiteKnight, F3) || g.Position().EncodeMove(m, UCINotation) != "N@f3" {
+		t.Fatalf("expected a knight drop but got %s", m)
+	}
+}
+
+func TestCrazyhousePromotedPieces(t *testing.T) {
+	g, err := NewVariantGameFromFEN(Crazyhouse, "4k3/1Q~6/8/8/4b3/8/Kpp5/8/ b - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.MoveStr("Bxb7"); err != nil {
+		t.Fatal(err)
+	}
+	if pocket := g.Position().Pocket(Black); pocket[Pawn] != 1 || len(pocket) != 1 {
+		t.Fatalf("expected the promoted queen to go to the pocket as a pawn but got %v", pocket)
+	}
+	for _, m := range []string{"Kb3", "c1=Q", "Ka4"} {
+		if err := g.MoveStr(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := "4k3/1b6/8/8/K7/8/1p6/2q~5[p] b - - 1 3"
+	if g.FEN() != want {
+		t.Fatalf("expected %s but got %s", want, g.FEN())
+	}
+}
+
+func TestCrazyhousePGN(t *testing.T) {
+	pgn := `[Event "Rated Crazyhouse game"]
+[Site "https://lichess.org/abcdefgh"]
+[Result "*"]
+[Variant "Crazyhouse"]
+
+1. e4 d5 2. exd5 Qxd5 3. Nc3 Qa5 4. P@d7+ Bxd7 5. Nf3 P@e2 6. Bxe2 P@e3 *`
+	g, err := NewGameFromPGN(strings.NewReader(pgn))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.Variant() != Crazyhouse {
+		t.Fatalf("expected Crazyhouse but got %s", g.Variant().Name())
+	}
+	want := "rn2kbnr/pppbpppp/8/q7/8/2N1pN2/PPPPBPPP/R1BQK2R[P] w KQkq - 0 7"
+	if g.FEN() != want {
+		t.Fatalf("expected %s but got %s", want, g.FEN())
+	}
+	if !strings.Contains(g.String(), "4. P@d7+ Bxd7 5. Nf3 P@e2 6. Bxe2 P@e3") {
+		t.Fatalf("expected drops in the PGN but got %s", g.String())
+	}
+	again, err := NewGameFromPGN(strings.NewReader(g.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.FEN() != want {
+		t.Fatalf("expected %s after a round trip but got %s", want, again.FEN())
+	}
+}
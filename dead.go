@@ -0,0 +1,86 @@
+package chess
+
+// deadPositionSearchLimit is the number of positions IsDead searches
+// before it gives up on proving that a position is dead.
+const deadPositionSearchLimit = 20000
+
+// IsDead returns true if the position is dead under FIDE article 5.2.2,
+// meaning neither player can checkmate by any series of legal moves.
+// Insufficient material, like same-colored bishops only, is found
+// exactly.  Positions where every pawn is blocked by a pawn and only kings
+// and bishops are left are searched, move by move, until every reachable
+// position is seen without a checkmate, or a move breaks the blockade or
+// the search limit is hit.  A false result only means the position
+// couldn't be proven dead.  Only
+// standard chess positions can be dead.
+func (pos *Position) IsDead() bool {
+	if pos.Variant() != Standard {
+		return false
+	}
+	if !pos.board.hasSufficientMaterial() {
+		return true
+	}
+	if !pos.board.isBlocked() {
+		return false
+	}
+	return !canReachCheckmate(pos, deadPositionSearchLimit)
+}
+
+// isBlocked returns true if the board has only kings, bishops and pawns,
+// and every pawn is blocked head-on by a pawn of the other color.
+func (b *Board) isBlocked() bool {
+	others := b.array[WhiteQueen] | b.array[WhiteRook] | b.array[WhiteKnight] |
+		b.array[BlackQueen] | b.array[BlackRook] | b.array[BlackKnight]
+	if others != 0 {
+		return false
+	}
+	white := b.array[WhitePawn]
+	black := b.array[BlackPawn]
+	if white == 0 {
+		return false
+	}
+	return white<<8 == black
+}
+
+// deadPositionKey is the part of a position that decides its moves.
+type deadPositionKey struct {
+	board           [12]bitboard
+	turn            Color
+	castleRights    CastleRights
+	enPassantSquare Square
+}
+
+func deadKey(pos *Position) deadPositionKey {
+	return deadPositionKey{pos.board.array, pos.turn, pos.castleRights, pos.enPassantSquare}
+}
+
+// canReachCheckmate returns true if a checkmate can be reached from the
+// blocked position, or if it can't tell because a move breaks the
+// blockade or more than limit positions are reachable.
+func canReachCheckmate(pos *Position, limit int) bool {
+	seen := map[deadPositionKey]bool{deadKey(pos): true}
+	queue := []*Position{pos}
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		if p.Status() == Checkmate {
+			return true
+		}
+		for _, m := range p.ValidMoves() {
+			next := p.Update(m)
+			if !next.board.isBlocked() {
+				return true
+			}
+			key := deadKey(next)
+			if seen[key] {
+				continue
+			}
+			if len(seen) >= limit {
+				return true
+			}
+			seen[key] = true
+			queue = append(queue, next)
+		}
+	}
+	return false
+}
@@ -20,7 +20,7 @@ func (engine) Status(pos *Position) Method {
 	if pos.validMoves != nil {
 		hasMove = len(pos.validMoves) > 0
 	} else {
-		hasMove = len(engine{}.CalcMoves(pos, true)) > 0
+		hasMove = len(pos.Variant().moves(pos, true)) > 0
 	}
 	if !pos.inCheck && !hasMove {
 		return Stalemate
@@ -104,6 +104,51 @@ func standardMoves(pos *Position, first bool) []Move {
 	return moves
 }
 
+// pseudoMoves returns the moves of the pieces of the color to move with
+// their Capture and EnPassant tags, whether or not they leave its king in
+// check.  Pawns promote to each of promos, and castles aren't included.
+func pseudoMoves(pos *Position, promos []PromoType) []Move {
+	bbAllowed := ^pos.board.whiteSqs()
+	if pos.Turn() == Black {
+		bbAllowed = ^pos.board.blackSqs()
+	}
+	occupied := pos.board.occupied()
+	moves := []Move{}
+	for _, typ := range allPieceTypes {
+		p := GetPiece(typ, pos.Turn())
+		s1BB := pos.board.bbForPiece(p)
+		for s1BB != 0 {
+			s1 := bbGetFirstSquare(s1BB)
+			s1BB ^= bbForSquare(s1)
+			var s2BB bitboard
+			if typ == Pawn {
+				s2BB = pawnMoves(pos, s1)
+			} else {
+				s2BB = bbForPossiblePieceMoves(occupied, typ, s1)
+			}
+			s2BB &= bbAllowed
+			for s2BB != 0 {
+				s2 := bbGetFirstSquare(s2BB)
+				s2BB ^= bbForSquare(s2)
+				m := NewMove(s1, s2, NoPromo, p)
+				if occupied&bbForSquare(s2) != 0 {
+					m = m.addTag(Capture)
+				} else if typ == Pawn && s2 == pos.enPassantSquare {
+					m = m.addTag(EnPassant)
+				}
+				if (p == WhitePawn && s2.Rank() == Rank8) || (p == BlackPawn && s2.Rank() == Rank1) {
+					for _, pt := range promos {
+						moves = append(moves, m.setPromo(pt))
+					}
+				} else {
+					moves = append(moves, m)
+				}
+			}
+		}
+	}
+	return moves
+}
+
 func addTags(m Move, pos *Position) Move {
 	p := m.piece()
 	if p == NoPiece {
@@ -111,7 +156,7 @@ func addTags(m Move, pos *Position) Move {
 	}
 	if pos.board.isOccupied(m.S2()) {
 		m = m.addTag(Capture)
-	} else if m.S2() == pos.enPassantSquare && p.Type() == Pawn {
+	} else if m.S2() == pos.enPassantSquare && p.Type() == Pawn && !m.HasTag(Drop) {
 		m = m.addTag(EnPassant)
 	}
 	// determine if in check after move (makes move invalid)
@@ -292,34 +337,17 @@ func pawnMoves(pos *Position, sq Square) bitboard {
 }
 
 func diaAttack(occupied bitboard, sq Square) bitboard {
-	pos := bbForSquare(sq)
-	dMask := bbDiagonals[int(sq)]
-	adMask := bbAntiDiagonals[int(sq)]
-	return bitboard(bitflip.BishopRookAttacks(uint64(occupied), uint64(pos), uint64(dMask), uint64(adMask)))
+	return bitboard(bitflip.BishopAttacks(uint64(occupied), int(sq)))
 }
 
 func hvAttack(occupied bitboard, sq Square) bitboard {
-	pos := bbForSquare(sq)
-	rankMask := bbRanks[Square(sq).Rank()]
-	fileMask := bbFiles[Square(sq).File()]
-	return bitboard(bitflip.BishopRookAttacks(uint64(occupied), uint64(pos), uint64(rankMask), uint64(fileMask)))
+	return bitboard(bitflip.RookAttacks(uint64(occupied), int(sq)))
 }
 
 func queenAttack(occupied bitboard, sq Square) bitboard {
-	pos := bbForSquare(sq)
-	rankMask := bbRanks[Square(sq).Rank()]
-	fileMask := bbFiles[Square(sq).File()]
-	dMask := bbDiagonals[int(sq)]
-	adMask := bbAntiDiagonals[int(sq)]
-	return bitboard(bitflip.QueenAttacks(
-		uint64(occupied),
-		uint64(pos),
-		uint64(rankMask),
-		uint64(fileMask),
-		uint64(dMask),
-		uint64(adMask),
-	))
+	return diaAttack(occupied, sq) | hvAttack(occupied, sq)
 }
+
 func linearAttack(occupied, pos, mask bitboard) bitboard {
 	oInMask := occupied & mask
 	return ((oInMask - (pos << 1)) ^ (oInMask.Reverse() - (pos.Reverse() << 1)).Reverse()) & mask
@@ -10,11 +10,36 @@ import (
 // if there is a parsing error.  FEN notation format:
 // rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1
 func decodeFEN(fen string) (*Position, error) {
+	return decodeVariantFEN(Standard, fen)
+}
+
+// decodeVariantFEN decodes the FEN of a position in the variant.
+// Three-check FENs can end with the checks given by each side, +1+0, or
+// have the checks each side has left, 2+3, before the clocks.  Crazyhouse
+// FENs have pockets and promoted pieces in their board section.
+func decodeVariantFEN(v Variant, fen string) (*Position, error) {
 	fen = strings.TrimSpace(fen)
 	parts := strings.Split(fen, " ")
+	var checks [2]int
+	if v == ThreeCheck && len(parts) == 7 {
+		var err error
+		parts, checks, err = fenChecks(parts)
+		if err != nil {
+			return nil, err
+		}
+	}
 	if len(parts) != 6 {
 		return nil, fmt.Errorf("chess: fen invalid notiation %s must have 6 sections", fen)
 	}
+	var pockets [2][6]uint8
+	var promoted bitboard
+	if v == Crazyhouse {
+		var err error
+		parts[0], pockets, promoted, err = fenPockets(parts[0])
+		if err != nil {
+			return nil, err
+		}
+	}
 	b, err := fenBoard(parts[0])
 	if err != nil {
 		return nil, err
@@ -39,15 +64,50 @@ func decodeFEN(fen string) (*Position, error) {
 	if err != nil || moveCount < 1 {
 		return nil, fmt.Errorf("chess: fen invalid move count %s", parts[5])
 	}
-	return &Position{
+	pos := &Position{
 		board:           b,
 		turn:            turn,
 		castleRights:    rights,
 		enPassantSquare: sq,
 		halfMoveClock:   halfMoveClock,
 		moveCount:       moveCount,
-		inCheck:         isInCheck(b, turn),
-	}, nil
+		inCheck:         v.inCheck(b, turn),
+		checks:          checks,
+		pockets:         pockets,
+		promoted:        promoted,
+	}
+	if v != Standard {
+		pos.variant = v
+	}
+	return pos, nil
+}
+
+// fenChecks removes the Three-check counts from the sections of a FEN and
+// returns the checks given by each side.
+func fenChecks(parts []string) ([]string, [2]int, error) {
+	var checks [2]int
+	i := 6
+	given := true
+	if !strings.HasPrefix(parts[i], "+") {
+		i = 4
+		given = false
+	}
+	counts := strings.Split(strings.TrimPrefix(parts[i], "+"), "+")
+	if len(counts) != 2 {
+		return nil, checks, fmt.Errorf("chess: fen invalid check counts %s", parts[i])
+	}
+	for c, s := range counts {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 || n > threeCheckLimit {
+			return nil, checks, fmt.Errorf("chess: fen invalid check counts %s", parts[i])
+		}
+		if !given {
+			n = threeCheckLimit - n
+		}
+		checks[c] = n
+	}
+	rest := append(append([]string{}, parts[:i]...), parts[i+1:]...)
+	return rest, checks, nil
 }
 
 // generates board from fen format: rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"time"
 )
 
 // A Outcome is the result of a game.
@@ -55,6 +56,31 @@ const (
 	// InsufficientMaterial indicates that the game was automatically drawn
 	// because there was insufficient material for checkmate.
 	InsufficientMaterial
+	// VariantWin indicates that the game was won by a rule of its variant,
+	// such as a king reaching the hill in King of the Hill.
+	VariantWin
+	// VariantDraw indicates that the game was drawn by a rule of its
+	// variant, such as both kings reaching the eighth rank in Racing Kings.
+	VariantDraw
+	// DeadPosition indicates that the game was automatically drawn because
+	// neither player could checkmate by any series of legal moves, beyond
+	// insufficient material, like a blocked pawn chain.
+	DeadPosition
+	// Timeout indicates that the game was won by the opponent of a player
+	// who ran out of time.
+	Timeout
+	// Abandoned indicates that the game was won by the opponent of a
+	// player who abandoned it.
+	Abandoned
+	// Adjudication indicates that the game's outcome was decided by an
+	// arbiter or an adjudication rule.
+	Adjudication
+	// RulesInfraction indicates that the game was won by the opponent of
+	// a player who broke the rules, such as by an illegal move.
+	RulesInfraction
+	// TimeoutVsInsufficientMaterial indicates that the game was drawn when
+	// a player ran out of time, because their opponent couldn't checkmate.
+	TimeoutVsInsufficientMaterial
 )
 
 // TagPair represents metadata in a key value pairing used in the PGN format.
@@ -65,7 +91,11 @@ type TagPair struct {
 
 // A Game represents a single chess game.
 type Game struct {
-	Notation             Notation
+	Notation Notation
+	// DetectDeadPositions makes the game end in a draw by DeadPosition
+	// when its position is dead, as found by Position.IsDead.  It's off by
+	// default as blocked positions take a search to prove dead.
+	DetectDeadPositions  bool
 	tagPairs             map[string]string
 	moves                []Move
 	positions            []*Position
@@ -73,6 +103,8 @@ type Game struct {
 	outcome              Outcome
 	method               Method
 	ignoreAutomaticDraws bool
+	annotations          []moveAnnotations
+	clock                *gameClock
 }
 
 // NewGameFromPGN takes a reader and returns a function that creates
@@ -113,6 +145,35 @@ func NewGameFromPosition(pos *Position) (*Game, error) {
 	return g, nil
 }
 
+// NewVariantGame returns a game of the variant in its starting position,
+// with a Variant tag pair if it isn't standard chess.
+func NewVariantGame(v Variant) *Game {
+	g := NewGame()
+	g.pos = v.StartingPosition()
+	g.positions = []*Position{g.pos}
+	if v != Standard {
+		g.AddTagPair("Variant", v.Name())
+	}
+	return g
+}
+
+// NewVariantGameFromFEN is like NewGameFromFEN for a game of the variant.
+// An error is returned if there is a problem parsing the FEN data.
+func NewVariantGameFromFEN(v Variant, fen string) (*Game, error) {
+	pos, err := decodeVariantFEN(v, fen)
+	if err != nil {
+		return nil, err
+	}
+	g, err := NewGameFromPosition(pos)
+	if err != nil {
+		return nil, err
+	}
+	if v != Standard {
+		g.AddTagPair("Variant", v.Name())
+	}
+	return g, nil
+}
+
 // NewGame defaults to returning a game in the standard
 // opening position.  Options can be given to configure
 // the game's initial state.
@@ -131,39 +192,100 @@ func NewGame() *Game {
 
 // Move updates the game with the given move.  An error is returned
 // if the move is invalid or the game has already been completed.
+// Games with a clock must be moved with MoveAt instead.
 func (g *Game) Move(m Move) error {
+	if g.clock != nil {
+		return errors.New("chess: a game with a clock must be moved with MoveAt")
+	}
+	v, err := g.validMove(m)
+	if err != nil {
+		return err
+	}
+	g.appendMove(v)
+	return nil
+}
+
+// MoveAt updates the game with the given move made at the time.  If the
+// game has a clock, the time advances it and is recorded in a [%clk]
+// comment, and the game ends by Timeout instead if the player ran out of
+// time first.  An error is returned if the move is invalid, or if the game
+// has a clock and has been completed.
+func (g *Game) MoveAt(m Move, at time.Time) error {
+	if g.clock == nil {
+		return g.Move(m)
+	}
+	if g.outcome != NoOutcome {
+		return fmt.Errorf("chess: move %s after the game ended by %s", m, g.method)
+	}
+	v, err := g.validMove(m)
+	if err != nil {
+		return err
+	}
+	clk, err := g.clockMove(at)
+	if err != nil {
+		return err
+	}
+	g.appendMove(v)
+	g.AddComment(len(g.moves)-1, clk)
+	return nil
+}
+
+// validMove returns the generated move equal to the move, which has its
+// tags set, or an error if there isn't one.
+func (g *Game) validMove(m Move) (Move, error) {
 	g.pos.ensureValidMoves()
-	valid := false
-	for _, v := range g.pos.validMoves {
-		if v.Eq(m) {
-			valid = true
-			break
+	for _, vm := range g.pos.validMoves {
+		if vm.Eq(m) {
+			return vm, nil
 		}
 	}
-	if !valid {
-		return fmt.Errorf("chess: invalid move %s", m)
-	}
-	v := m
-	g.moves = append(g.moves, v)
-	g.pos = g.pos.Update(v)
+	return 0, fmt.Errorf("chess: invalid move %s", m)
+}
+
+func (g *Game) appendMove(m Move) {
+	g.moves = append(g.moves, m)
+	g.pos = g.pos.Update(m)
 	g.positions = append(g.positions, g.pos)
 	g.updatePosition()
-	return nil
 }
 
-// MoveStr decodes the given string, trying the obvious notations
-// as though it were a PGN, and calls the Move function.
-// To parse with a
+// MoveStr decodes the given string in the game's Notation, or failing
+// that in each registered notation, and calls the Move function.
 // An error is returned if
-// the move can't be decoded or the move is invalid.
+// the move can't be decoded or the move is invalid, which is an
+// *AmbiguousMoveError if the string could be more than one valid move.
 func (g *Game) MoveStr(s string) error {
-	m, err := g.pos.DecodeMove(s)
+	m, err := g.decodeMoveStr(s)
 	if err != nil {
 		return err
 	}
 	return g.Move(m)
 }
 
+// MoveStrAt decodes the given string like MoveStr and calls the MoveAt
+// function with the time.
+func (g *Game) MoveStrAt(s string, at time.Time) error {
+	m, err := g.decodeMoveStr(s)
+	if err != nil {
+		return err
+	}
+	return g.MoveAt(m, at)
+}
+
+func (g *Game) decodeMoveStr(s string) (Move, error) {
+	if g.Notation != nil {
+		m, err := g.pos.DecodeMove(s, g.Notation)
+		if err == nil {
+			return m, nil
+		}
+		var ambiguous *AmbiguousMoveError
+		if errors.As(err, &ambiguous) {
+			return 0, err
+		}
+	}
+	return g.pos.DecodeMove(s)
+}
+
 // ValidMoves returns a list of valid moves in the
 // current position.
 func (g *Game) ValidMoves() []Move {
@@ -197,6 +319,11 @@ func (g *Game) Position() *Position {
 	return g.pos
 }
 
+// Variant returns the variant whose rules the game follows.
+func (g *Game) Variant() Variant {
+	return g.pos.Variant()
+}
+
 // Outcome returns the game outcome.
 func (g *Game) Outcome() Outcome {
 	return g.outcome
@@ -319,12 +446,15 @@ func (g *Game) RemoveTagPair(k string) bool {
 }
 
 // MoveHistory is a move's result from Game's MoveHistory method.
-// It contains the move itself, any comments, and the pre and post
-// positions.
+// It contains the move itself, any comments, NAGs and variations, and
+// the pre and post positions.
 type MoveHistory struct {
 	PrePosition  *Position
 	PostPosition *Position
 	Move         Move
+	Comments     []string
+	NAGs         []NAG
+	Variations   [][]Move
 }
 
 // MoveHistory returns the moves in order along with the pre and post
@@ -336,10 +466,14 @@ func (g *Game) MoveHistory() []*MoveHistory {
 			continue
 		}
 		m := g.moves[i-1]
+		a := g.annotation(i - 1).clone()
 		mh := &MoveHistory{
 			PrePosition:  g.positions[i-1],
 			PostPosition: p,
 			Move:         m,
+			Comments:     a.comments,
+			NAGs:         a.nags,
+			Variations:   g.Variations(i - 1),
 		}
 		h = append(h, mh)
 	}
@@ -347,16 +481,10 @@ func (g *Game) MoveHistory() []*MoveHistory {
 }
 
 func (g *Game) updatePosition() {
-	method := g.pos.Status()
-	if method == Stalemate {
-		g.method = Stalemate
-		g.outcome = Draw
-	} else if method == Checkmate {
-		g.method = Checkmate
-		g.outcome = WhiteWon
-		if g.pos.Turn() == White {
-			g.outcome = BlackWon
-		}
+	outcome, method := g.pos.Variant().result(g.pos)
+	if outcome != NoOutcome {
+		g.outcome = outcome
+		g.method = method
 	}
 	if g.outcome != NoOutcome {
 		return
@@ -375,14 +503,20 @@ func (g *Game) updatePosition() {
 	}
 
 	// insufficient material creates automatic draw
-	if !g.ignoreAutomaticDraws && !g.pos.board.hasSufficientMaterial() {
+	if !g.ignoreAutomaticDraws && g.pos.Variant().insufficientMaterial(g.pos) {
 		g.outcome = Draw
 		g.method = InsufficientMaterial
 	}
+
+	if !g.ignoreAutomaticDraws && g.DetectDeadPositions && g.outcome == NoOutcome && g.pos.IsDead() {
+		g.outcome = Draw
+		g.method = DeadPosition
+	}
 }
 
 func (g *Game) mergeInto(other *Game) {
 	g.Notation = other.Notation
+	g.DetectDeadPositions = other.DetectDeadPositions
 	g.tagPairs = other.tagPairs
 	g.moves = other.moves
 	g.positions = other.positions
@@ -390,26 +524,42 @@ func (g *Game) mergeInto(other *Game) {
 	g.outcome = other.outcome
 	g.method = other.method
 	g.ignoreAutomaticDraws = other.ignoreAutomaticDraws
+	g.annotations = other.annotations
+	g.clock = other.clock
 }
 
 func (g *Game) Clone() *Game {
 	var newTags map[string]string
 
 	if g.tagPairs != nil {
-		newTags := make(map[string]string)
+		newTags = make(map[string]string)
 		for k, v := range g.tagPairs {
 			newTags[k] = v
 		}
 	}
 
+	var annotations []moveAnnotations
+	for _, a := range g.annotations {
+		annotations = append(annotations, a.clone())
+	}
+
+	var clock *gameClock
+	if g.clock != nil {
+		cp := *g.clock
+		clock = &cp
+	}
+
 	return &Game{
-		tagPairs:  newTags,
-		Notation:  g.Notation,
-		moves:     g.Moves(),
-		positions: g.Positions(),
-		pos:       g.pos,
-		outcome:   g.outcome,
-		method:    g.method,
+		tagPairs:            newTags,
+		Notation:            g.Notation,
+		DetectDeadPositions: g.DetectDeadPositions,
+		moves:               g.Moves(),
+		positions:           g.Positions(),
+		pos:                 g.pos,
+		outcome:             g.outcome,
+		method:              g.method,
+		annotations:         annotations,
+		clock:               clock,
 	}
 }
 
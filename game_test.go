@@ -215,6 +215,49 @@ func TestSufficientMaterial(t *testing.T) {
 	}
 }
 
+func TestDeadPosition(t *testing.T) {
+	dead := []string{
+		"8/8/3k4/1p1p1p1p/1P1P1P1P/3K4/8/8 w - - 0 1",
+		"8/8/3k4/1p1p1p1p/1P1P1P1P/3K4/8/2B5 w - - 0 1",
+		"8/2k5/2b5/8/8/3K1B2/8/8 w - - 1 1",
+	}
+	for _, f := range dead {
+		if !unsafeFEN(f).IsDead() {
+			t.Fatalf("%s should be dead", f)
+		}
+	}
+	alive := []string{
+		"8/4k3/8/3p4/3P4/8/8/4K3 w - - 0 1",
+		"8/8/3k4/1p1p1p1p/1P1P1P1P/3K4/8/1B6 w - - 0 1",
+		"8/2k1b3/8/8/8/3K1B2/8/8 w - - 1 1",
+		"8/8/3k4/1p1p1p1p/1P1P1P1P/3K4/8/2N5 w - - 0 1",
+	}
+	for _, f := range alive {
+		if unsafeFEN(f).IsDead() {
+			t.Fatalf("%s should not be dead", f)
+		}
+	}
+
+	fen := "8/8/3k4/1p1p1p1p/1P1P1P1P/3K4/8/8 w - - 0 1"
+	g, err := NewGameFromFEN(fen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.MoveStr("Kc3"); err != nil {
+		t.Fatal(err)
+	}
+	if g.Outcome() != NoOutcome {
+		t.Fatalf("expected dead positions to be ignored by default but got %s by %s", g.Outcome(), g.Method())
+	}
+	g.DetectDeadPositions = true
+	if err := g.MoveStr("Kc7"); err != nil {
+		t.Fatal(err)
+	}
+	if g.Outcome() != Draw || g.Method() != DeadPosition {
+		t.Fatalf("expected a draw by dead position but got %s by %s", g.Outcome(), g.Method())
+	}
+}
+
 func TestSerializationCycle(t *testing.T) {
 	g := NewGame()
 	g.MoveStr("e4")
@@ -280,6 +323,24 @@ func TestMoveHistory(t *testing.T) {
 	}
 }
 
+func TestMoveUsesGeneratedMove(t *testing.T) {
+	g, err := NewGameFromFEN("rnbqkbnr/ppp1p1pp/8/3pPp2/8/8/PPPP1PPP/RNBQKBNR w KQkq f6 0 3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a move made from its squares, like one from a UCI engine, has no tags
+	if err := g.Move(NewMove(E5, F6, NoPromo)); err != nil {
+		t.Fatal(err)
+	}
+	m := g.Moves()[0]
+	if !m.HasTag(EnPassant) {
+		t.Fatalf("expected the move to be tagged en passant but got %s", m.StringWithTags())
+	}
+	if p := g.Position().Board().Piece(F5); p != NoPiece {
+		t.Fatalf("expected the f5 pawn to be captured but got %s", p)
+	}
+}
+
 func BenchmarkStalemateStatus(b *testing.B) {
 	fenStr := "k1K5/8/8/8/8/8/8/1Q6 w - - 0 1"
 	g, err := NewGameFromFEN(fenStr)
@@ -0,0 +1,59 @@
+package chess
+
+import (
+	"fmt"
+	"strings"
+)
+
+type iccfNotation struct{}
+
+// iccfPromos are the promotions in the order of their ICCF digits, from 1.
+var iccfPromos = []PromoType{PromoQueen, PromoRook, PromoBishop, PromoKnight}
+
+func (iccfNotation) Encode(pos *Position, m Move) string {
+	if m.HasTag(Drop) {
+		return dropString(m)
+	}
+	s := iccfSquare(m.S1()) + iccfSquare(m.S2())
+	for i, p := range iccfPromos {
+		if m.Promo() == p {
+			s += fmt.Sprint(i + 1)
+		}
+	}
+	return s
+}
+
+func iccfSquare(sq Square) string {
+	return fmt.Sprintf("%d%d", int(sq.File())+1, int(sq.Rank())+1)
+}
+
+// Decode translates the digits into UCI and decodes that, so moves are
+// tagged the same way.
+func (iccfNotation) Decode(pos *Position, s string) (Move, error) {
+	err := fmt.Errorf(`chess: failed to decode ICCF numeric notation text "%s" for position %s`, s, pos)
+	if len(s) != 4 && len(s) != 5 {
+		return 0, err
+	}
+	var sb strings.Builder
+	for i := 0; i < 4; i++ {
+		if s[i] < '1' || s[i] > '8' {
+			return 0, err
+		}
+		if i%2 == 0 {
+			sb.WriteByte('a' + s[i] - '1')
+		} else {
+			sb.WriteByte(s[i])
+		}
+	}
+	if len(s) == 5 {
+		if s[4] < '1' || s[4] > '4' {
+			return 0, err
+		}
+		sb.WriteString(iccfPromos[s[4]-'1'].PieceType().String())
+	}
+	m, uciErr := pos.DecodeUCI(sb.String())
+	if uciErr != nil {
+		return 0, err
+	}
+	return m, nil
+}
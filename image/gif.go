@@ -0,0 +1,252 @@
+package image
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/barakmich/chess"
+)
+
+// Caption is a kind of text drawn below the board in each frame of a GIF.
+type Caption int
+
+const (
+	// MoveCaption is the move played to reach the frame, like 12... Nf6.
+	MoveCaption Caption = iota
+	// ClockCaption is both players' clocks, from the [%clk] commands in
+	// the comments of their latest moves.
+	ClockCaption
+	// EvalCaption is the evaluation of the frame, from the [%eval]
+	// command in the comments of its move.
+	EvalCaption
+)
+
+const (
+	defaultFrameDelay      = time.Second
+	defaultFinalFrameDelay = 3 * time.Second
+	// captionHeight is the height of the caption strip in board units.
+	captionHeight = 20
+	captionSize   = 9
+)
+
+var (
+	captionBackground = color.RGBA{38, 36, 33, 255}
+	captionText       = color.RGBA{230, 230, 230, 255}
+)
+
+// FrameDelay is designed to be used as an optional argument
+// to the GIF function.  It sets how long each position is shown,
+// which is one second by default.
+func FrameDelay(d time.Duration) func(*encoder) {
+	return func(e *encoder) {
+		e.frameDelay = d
+	}
+}
+
+// FinalFrameDelay is designed to be used as an optional argument
+// to the GIF function.  It sets how long the final position is
+// shown before the animation loops, which is three seconds by default.
+func FinalFrameDelay(d time.Duration) func(*encoder) {
+	return func(e *encoder) {
+		e.finalDelay = d
+	}
+}
+
+// Captions is designed to be used as an optional argument
+// to the GIF function.  It adds a strip below the board with
+// the given captions.
+func Captions(captions ...Caption) func(*encoder) {
+	return func(e *encoder) {
+		e.captions = append(e.captions, captions...)
+	}
+}
+
+// GIF writes an animated GIF of the game into the writer, with a frame for
+// each of its positions and the last move highlighted.  It takes the same
+// options as SVG and PNG, which apply to every frame, along with the GIF
+// options for timing and captions.  An error is returned if there is an
+// error writing data.
+func GIF(w io.Writer, g *chess.Game, opts ...func(*encoder)) error {
+	e := new(w, opts)
+	positions := g.Positions()
+	moves := g.Moves()
+	comments := g.Comments()
+	anim := &gif.GIF{}
+	var clocks [2]string
+	for i, pos := range positions {
+		frame := *e
+		frame.marks = map[chess.Square]squareMark{}
+		for sq, mark := range e.marks {
+			frame.marks[sq] = mark
+		}
+		var left, right []string
+		if i > 0 {
+			LastMove(moves[i-1])(&frame)
+			prev := positions[i-1]
+			var cs []string
+			if i-1 < len(comments) {
+				cs = comments[i-1]
+			}
+			if clk, ok := commentClock(cs); ok {
+				if prev.Turn() == chess.White {
+					clocks[0] = clk
+				} else {
+					clocks[1] = clk
+				}
+			}
+			for _, c := range e.captions {
+				switch c {
+				case MoveCaption:
+					left = append(left, moveCaption(prev, moves[i-1], g.Notation))
+				case EvalCaption:
+					if eval, ok := commentEval(cs); ok {
+						left = append(left, eval)
+					}
+				}
+			}
+		}
+		for _, c := range e.captions {
+			if c == ClockCaption && (clocks[0] != "" || clocks[1] != "") {
+				right = append(right, "W "+clocks[0], "B "+clocks[1])
+			}
+		}
+		img := frame.Image(pos.Board())
+		if len(e.captions) > 0 {
+			img = e.addCaption(img, strings.Join(left, "  "), strings.Join(right, "  "))
+		}
+		delay := e.frameDelay
+		if i == len(positions)-1 {
+			delay = e.finalDelay
+		}
+		anim.Image = append(anim.Image, paletted(img))
+		anim.Delay = append(anim.Delay, int(delay/(10*time.Millisecond)))
+	}
+	return gif.EncodeAll(w, anim)
+}
+
+// addCaption returns the image with a strip below it showing left and
+// right aligned text.
+func (e *encoder) addCaption(board *image.RGBA, left, right string) *image.RGBA {
+	width, height := e.canvasSize()
+	b := board.Bounds()
+	img := image.NewRGBA(image.Rect(0, 0, b.Dx(), e.scale(height+captionHeight)))
+	draw.Draw(img, img.Bounds(), image.NewUniform(captionBackground), image.Point{}, draw.Src)
+	draw.Draw(img, b, board, image.Point{}, draw.Src)
+	s := float64(b.Dx()) / float64(width)
+	baseline := (float64(height) + (captionHeight+captionSize)/2) * s
+	drawText(img, left, 6*s, baseline, captionSize*s, anchorStart, captionText)
+	drawText(img, right, float64(width-6)*s, baseline, captionSize*s, anchorEnd, captionText)
+	return img
+}
+
+// moveCaption returns the move with its number, like 12. Nf3 or 12... Nf6.
+func moveCaption(pos *chess.Position, m chess.Move, n chess.Notation) string {
+	dots := "."
+	if pos.Turn() == chess.Black {
+		dots = "..."
+	}
+	return fmt.Sprintf("%d%s %s", pos.MoveCount(), dots, pos.EncodeMove(m, n))
+}
+
+var (
+	clockCommandRegex = regexp.MustCompile(`\[%clk\s+(\d+):(\d+):(\d+)(?:\.\d+)?\s*\]`)
+	evalCommandRegex  = regexp.MustCompile(`\[%eval\s+([^\s\],]+)`)
+)
+
+// commentClock returns the clock of the first [%clk 1:02:03] command in
+// the comments, without the hours if they are zero.
+func commentClock(comments []string) (string, bool) {
+	for _, c := range comments {
+		match := clockCommandRegex.FindStringSubmatch(c)
+		if match == nil {
+			continue
+		}
+		h, _ := strconv.Atoi(match[1])
+		m, _ := strconv.Atoi(match[2])
+		s, _ := strconv.Atoi(match[3])
+		if h > 0 {
+			return fmt.Sprintf("%d:%02d:%02d", h, m, s), true
+		}
+		return fmt.Sprintf("%d:%02d", m, s), true
+	}
+	return "", false
+}
+
+// commentEval returns the evaluation of the first [%eval 0.35] or
+// [%eval #-3] command in the comments, with a sign for pawn scores.
+func commentEval(comments []string) (string, bool) {
+	for _, c := range comments {
+		match := evalCommandRegex.FindStringSubmatch(c)
+		if match == nil {
+			continue
+		}
+		eval := match[1]
+		if f, err := strconv.ParseFloat(eval, 64); err == nil && f >= 0 && !strings.HasPrefix(eval, "+") {
+			eval = "+" + eval
+		}
+		return eval, true
+	}
+	return "", false
+}
+
+// paletted converts the image to one with a palette of its 256 most common
+// colors, ordered so the result is deterministic.  The boards only have a
+// few colors besides the edges of shapes, so this keeps them exact.
+func paletted(img *image.RGBA) *image.Paletted {
+	counts := map[color.RGBA]int{}
+	b := img.Bounds()
+	for i := 0; i+3 < len(img.Pix); i += 4 {
+		counts[color.RGBA{img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3]}]++
+	}
+	colors := make([]color.RGBA, 0, len(counts))
+	for c := range counts {
+		colors = append(colors, c)
+	}
+	sort.Slice(colors, func(i, j int) bool {
+		a, b := colors[i], colors[j]
+		if counts[a] != counts[b] {
+			return counts[a] > counts[b]
+		}
+		if a.R != b.R {
+			return a.R < b.R
+		}
+		if a.G != b.G {
+			return a.G < b.G
+		}
+		if a.B != b.B {
+			return a.B < b.B
+		}
+		return a.A < b.A
+	})
+	if len(colors) > 256 {
+		colors = colors[:256]
+	}
+	palette := make(color.Palette, len(colors))
+	index := map[color.RGBA]uint8{}
+	for i, c := range colors {
+		palette[i] = c
+		index[c] = uint8(i)
+	}
+	out := image.NewPaletted(b, palette)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := img.RGBAAt(x, y)
+			i, ok := index[c]
+			if !ok {
+				i = uint8(palette.Index(c))
+				index[c] = i
+			}
+			out.SetColorIndex(x, y, i)
+		}
+	}
+	return out
+}
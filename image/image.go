@@ -3,9 +3,11 @@ package image
 
 import (
 	"fmt"
+	"image"
 	"image/color"
 	"io"
 	"strings"
+	"time"
 
 	svg "github.com/ajstarks/svgo"
 	"github.com/barakmich/chess"
@@ -35,19 +37,73 @@ func SquareColors(light, dark color.Color) func(*encoder) {
 // color.  A possible usage includes marking squares of the
 // previous move.
 func MarkSquares(c color.Color, sqs ...chess.Square) func(*encoder) {
+	return HighlightSquares(c, defaultMarkOpacity, sqs...)
+}
+
+// Perspective is designed to be used as an optional argument
+// to the SVG function.  It draws the board from the given
+// color's side, so Black's perspective has rank 8 at the bottom.
+func Perspective(c chess.Color) func(*encoder) {
 	return func(e *encoder) {
-		for _, sq := range sqs {
-			e.marks[sq] = c
-		}
+		e.flipped = c == chess.Black
+	}
+}
+
+// CoordinatesPosition is where the rank and file names are drawn.
+type CoordinatesPosition int
+
+const (
+	// CoordinatesInside draws the names inside the squares of the
+	// left file and the bottom rank.  It is the default.
+	CoordinatesInside CoordinatesPosition = iota
+	// CoordinatesOutside draws the names in a margin to the left of and
+	// below the board.
+	CoordinatesOutside
+	// CoordinatesHidden doesn't draw the names.
+	CoordinatesHidden
+)
+
+// Coordinates is designed to be used as an optional argument
+// to the SVG function.  It sets where the rank and file names
+// are drawn.
+func Coordinates(p CoordinatesPosition) func(*encoder) {
+	return func(e *encoder) {
+		e.coords = p
+	}
+}
+
+// BoardSize is designed to be used as an optional argument
+// to the SVG function.  It sets the width and height of the
+// board in pixels, which is 360 by default.  A margin for
+// outside coordinates is scaled along with the board.
+func BoardSize(px int) func(*encoder) {
+	return func(e *encoder) {
+		e.size = px
 	}
 }
 
 // A Encoder encodes chess boards into images.
 type encoder struct {
-	w     io.Writer
-	light color.Color
-	dark  color.Color
-	marks map[chess.Square]color.Color
+	w       io.Writer
+	light   color.Color
+	dark    color.Color
+	marks   map[chess.Square]squareMark
+	arrows  []Arrow
+	circles []Circle
+	check   chess.Square
+	flipped bool
+	coords  CoordinatesPosition
+	size    int
+	tiles   map[tileKey]*image.RGBA
+	// theme options
+	lightText color.Color
+	darkText  color.Color
+	font      string
+	pieces    *PieceSet
+	// GIF options
+	frameDelay time.Duration
+	finalDelay time.Duration
+	captions   []Caption
 }
 
 // New returns an encoder that writes to the given writer.
@@ -58,7 +114,13 @@ func new(w io.Writer, options []func(*encoder)) *encoder {
 		w:     w,
 		light: color.RGBA{235, 209, 166, 1},
 		dark:  color.RGBA{165, 117, 81, 1},
-		marks: map[chess.Square]color.Color{},
+		marks: map[chess.Square]squareMark{},
+		check: chess.NoSquare,
+		size:  boardWidth,
+		tiles: map[tileKey]*image.RGBA{},
+
+		frameDelay: defaultFrameDelay,
+		finalDelay: defaultFinalFrameDelay,
 	}
 	for _, op := range options {
 		op(e)
@@ -66,11 +128,14 @@ func new(w io.Writer, options []func(*encoder)) *encoder {
 	return e
 }
 
+// The board is drawn in these units and scaled to the board size.
 const (
 	sqWidth     = 45
 	sqHeight    = 45
 	boardWidth  = 8 * sqWidth
 	boardHeight = 8 * sqHeight
+	// coordsMargin is the width of the margin for outside coordinates.
+	coordsMargin = 16
 )
 
 var (
@@ -84,41 +149,95 @@ var (
 func (e *encoder) EncodeSVG(b *chess.Board) error {
 	boardMap := b.SquareMap()
 	canvas := svg.New(e.w)
-	canvas.Start(boardWidth, boardHeight)
-	canvas.Rect(0, 0, boardWidth, boardHeight)
+	width, height := e.canvasSize()
+	if e.size == boardWidth && e.margin() == 0 {
+		canvas.Start(width, height)
+	} else {
+		canvas.Start(e.scale(width), e.scale(height), fmt.Sprintf(`viewBox="0 0 %d %d"`, width, height))
+	}
+	canvas.Rect(e.margin(), 0, boardWidth, boardHeight)
 
 	for i := 0; i < 64; i++ {
 		sq := chess.Square(i)
-		x, y := xyForSquare(sq)
+		x, y := e.xyForSquare(sq)
 		// draw square
 		c := e.colorForSquare(sq)
 		canvas.Rect(x, y, sqWidth, sqHeight, "fill: "+colorToHex(c))
-		markColor, ok := e.marks[sq]
-		if ok {
-			canvas.Rect(x, y, sqWidth, sqHeight, "fill-opacity:0.2;fill: "+colorToHex(markColor))
+		if mark, ok := e.marks[sq]; ok {
+			canvas.Rect(x, y, sqWidth, sqHeight, fmt.Sprintf("fill-opacity:%g;fill: %s", mark.opacity, colorToHex(mark.color)))
+		}
+		if sq == e.check {
+			canvas.Circle(x+sqWidth/2, y+sqHeight/2, sqWidth/2, "fill-opacity:0.6;fill: "+colorToHex(checkColor))
 		}
 		// draw piece
 		p := boardMap[sq]
 		if p != chess.NoPiece {
-			xml := pieceXML(x, y, p)
+			xml := e.pieceSet().xml(x, y, width, height, p)
 			if _, err := io.WriteString(canvas.Writer, xml); err != nil {
 				return err
 			}
 		}
-		// draw rank text on file A
+		e.drawCoordinates(canvas, sq, x, y)
+	}
+	for _, c := range e.circles {
+		x, y := e.center(c.Square)
+		fmt.Fprintf(canvas.Writer, `<circle cx="%g" cy="%g" r="%d" style="fill:none;stroke-width:%d;stroke-opacity:%g;stroke: %s" />`+"\n",
+			x, y, circleRadius, circleWidth, c.opacity(), colorToHex(c.color()))
+	}
+	for _, a := range e.arrows {
+		if _, err := io.WriteString(canvas.Writer, e.arrowXML(a)); err != nil {
+			return err
+		}
+	}
+	canvas.End()
+	return nil
+}
+
+// drawCoordinates draws the rank name next to squares of the left file
+// and the file name next to squares of the bottom rank.
+func (e *encoder) drawCoordinates(canvas *svg.SVG, sq chess.Square, x, y int) {
+	leftFile, bottomRank := chess.FileA, chess.Rank1
+	if e.flipped {
+		leftFile, bottomRank = chess.FileH, chess.Rank8
+	}
+	switch e.coords {
+	case CoordinatesInside:
 		txtColor := e.colorForText(sq)
-		if sq.File() == chess.FileA {
-			style := "font-size:11px;fill: " + colorToHex(txtColor)
+		if sq.File() == leftFile {
+			style := e.fontStyle() + "font-size:11px;fill: " + colorToHex(txtColor)
 			canvas.Text(x+(sqWidth*1/20), y+(sqHeight*5/20), sq.Rank().String(), style)
 		}
-		// draw file text on rank 1
-		if sq.Rank() == chess.Rank1 {
-			style := "text-anchor:end;font-size:11px;fill: " + colorToHex(txtColor)
+		if sq.Rank() == bottomRank {
+			style := "text-anchor:end;" + e.fontStyle() + "font-size:11px;fill: " + colorToHex(txtColor)
 			canvas.Text(x+(sqWidth*19/20), y+sqHeight-(sqHeight*1/15), sq.File().String(), style)
 		}
+	case CoordinatesOutside:
+		style := "text-anchor:middle;" + e.fontStyle() + "font-size:11px;fill: " + colorToHex(e.outsideTextColor())
+		if sq.File() == leftFile {
+			canvas.Text(coordsMargin/2, y+sqHeight/2+4, sq.Rank().String(), style)
+		}
+		if sq.Rank() == bottomRank {
+			canvas.Text(x+sqWidth/2, boardHeight+coordsMargin-4, sq.File().String(), style)
+		}
 	}
-	canvas.End()
-	return nil
+}
+
+// margin returns the width of the margin left of and below the board.
+func (e *encoder) margin() int {
+	if e.coords == CoordinatesOutside {
+		return coordsMargin
+	}
+	return 0
+}
+
+// canvasSize returns the size of the image in board units.
+func (e *encoder) canvasSize() (width, height int) {
+	return boardWidth + e.margin(), boardHeight + e.margin()
+}
+
+// scale converts board units to pixels.
+func (e *encoder) scale(n int) int {
+	return (n*e.size + boardWidth/2) / boardWidth
 }
 
 func (e *encoder) colorForSquare(sq chess.Square) color.Color {
@@ -132,15 +251,47 @@ func (e *encoder) colorForSquare(sq chess.Square) color.Color {
 func (e *encoder) colorForText(sq chess.Square) color.Color {
 	sqSum := int(sq.File()) + int(sq.Rank())
 	if sqSum%2 == 0 {
+		if e.darkText != nil {
+			return e.darkText
+		}
 		return e.light
 	}
+	return e.outsideTextColor()
+}
+
+// outsideTextColor is the color of text on light squares, which is also
+// used for the coordinates outside the board.
+func (e *encoder) outsideTextColor() color.Color {
+	if e.lightText != nil {
+		return e.lightText
+	}
 	return e.dark
 }
 
-func xyForSquare(sq chess.Square) (x, y int) {
+// fontStyle returns the CSS setting the theme's font, if it has one.
+func (e *encoder) fontStyle() string {
+	if e.font == "" {
+		return ""
+	}
+	return "font-family:" + e.font + ";"
+}
+
+// pieceSet returns the pieces to draw.
+func (e *encoder) pieceSet() *PieceSet {
+	if e.pieces == nil {
+		return DefaultPieceSet()
+	}
+	return e.pieces
+}
+
+// xyForSquare returns the top left corner of the square in board units.
+func (e *encoder) xyForSquare(sq chess.Square) (x, y int) {
 	fileIndex := int(sq.File())
 	rankIndex := 7 - int(sq.Rank())
-	return fileIndex * sqWidth, rankIndex * sqHeight
+	if e.flipped {
+		fileIndex, rankIndex = 7-fileIndex, 7-rankIndex
+	}
+	return e.margin() + fileIndex*sqWidth, rankIndex * sqHeight
 }
 
 func colorToHex(c color.Color) string {
@@ -148,11 +299,13 @@ func colorToHex(c color.Color) string {
 	return fmt.Sprintf("#%02x%02x%02x", uint8(float64(r)+0.5), uint8(float64(g)*1.0+0.5), uint8(float64(b)*1.0+0.5))
 }
 
-func pieceXML(x, y int, p chess.Piece) string {
+// pieceXML returns the piece's SVG placed at x, y of a canvas of the given
+// size.
+func pieceXML(x, y, width, height int, p chess.Piece) string {
 	fileName := fmt.Sprintf("pieces/%s%s.svg", p.Color().String(), pieceTypeMap[p.Type()])
 	svgStr := string(internal.MustAsset(fileName))
 	old := `<svg xmlns="http://www.w3.org/2000/svg" version="1.1" width="45" height="45">`
-	new := fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" version="1.1" width="360" height="360" viewBox="%d %d 360 360">`, (-1 * x), (-1 * y))
+	new := fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" version="1.1" width="%d" height="%d" viewBox="%d %d %d %d">`, width, height, (-1 * x), (-1 * y), width, height)
 	return strings.Replace(svgStr, old, new, 1)
 }
 
@@ -5,10 +5,14 @@ import (
 	"crypto/md5"
 	"fmt"
 	"image/color"
+	"image/gif"
+	"image/png"
 	"io"
 	"os"
 	"strings"
 	"testing"
+	"testing/fstest"
+	"time"
 
 	"github.com/barakmich/chess"
 	"github.com/barakmich/chess/image"
@@ -46,3 +50,295 @@ func TestSVG(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestSVGPerspective(t *testing.T) {
+	buf := bytes.NewBuffer([]byte{})
+	board := chess.NewGame().Position().Board()
+	if err := image.SVG(buf, board, image.Perspective(chess.Black)); err != nil {
+		t.Fatal(err)
+	}
+	s := buf.String()
+	for _, want := range []string{
+		// a1 is dark in the top right corner
+		`<rect x="315" y="0" width="45" height="45" style="fill: #a57551" />`,
+		// the white king on e1 and the black queen on d8
+		`viewBox="-135 0 360 360"`,
+		`viewBox="-180 -315 360 360"`,
+		// rank names on the h file and file names on rank 8
+		`<text x="2" y="11" style="font-size:11px;fill: #a57551" >1</text>`,
+		`<text x="42" y="357" style="text-anchor:end;font-size:11px;fill: #ebd1a6" >h</text>`,
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("expected flipped svg to contain %s", want)
+		}
+	}
+}
+
+func TestSVGCoordinatesAndSize(t *testing.T) {
+	board := chess.NewGame().Position().Board()
+	buf := bytes.NewBuffer([]byte{})
+	if err := image.SVG(buf, board, image.Coordinates(image.CoordinatesHidden), image.BoardSize(720)); err != nil {
+		t.Fatal(err)
+	}
+	s := buf.String()
+	if !strings.Contains(s, `width="720" height="720"`) || !strings.Contains(s, `viewBox="0 0 360 360"`) {
+		t.Errorf("expected a 720px board but got %s", s[:200])
+	}
+	if strings.Contains(s, "<text") {
+		t.Error("expected no coordinates")
+	}
+
+	buf.Reset()
+	if err := image.SVG(buf, board, image.Coordinates(image.CoordinatesOutside)); err != nil {
+		t.Fatal(err)
+	}
+	s = buf.String()
+	for _, want := range []string{
+		`width="376" height="376"`,
+		`<rect x="16" y="0" width="360" height="360" />`,
+		`<rect x="16" y="315" width="45" height="45" style="fill: #a57551" />`,
+		`>8</text>`,
+		`>a</text>`,
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("expected svg with outside coordinates to contain %s", want)
+		}
+	}
+}
+
+func TestParseCommentMarks(t *testing.T) {
+	arrows, circles := image.ParseCommentMarks("good move [%csl Re4,Gd5,Xa1] [%cal Ga1a8,Rg1f3,Bh9h1]")
+	if len(arrows) != 2 || arrows[0].From != chess.A1 || arrows[0].To != chess.A8 || arrows[1].Color != image.Red {
+		t.Fatalf("unexpected arrows %+v", arrows)
+	}
+	if len(circles) != 2 || circles[0].Square != chess.E4 || circles[0].Color != image.Red || circles[1].Color != image.Green {
+		t.Fatalf("unexpected circles %+v", circles)
+	}
+}
+
+func TestSVGMarks(t *testing.T) {
+	g := chess.NewGame()
+	for _, m := range []string{"e4", "f5", "Qh5+"} {
+		if err := g.MoveStr(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	last := g.Moves()[2]
+	buf := bytes.NewBuffer([]byte{})
+	err := image.SVG(buf, g.Position().Board(),
+		image.LastMove(last),
+		image.Check(g.Position()),
+		image.Arrows(image.Arrow{From: chess.G1, To: chess.F3}, image.Arrow{From: chess.E2, To: chess.E4, Color: image.Blue, Opacity: 0.5}),
+		image.CommentMarks("[%csl Rf7]"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := buf.String()
+	for _, want := range []string{
+		// d1 and h5
+		`<rect x="135" y="315" width="45" height="45" style="fill-opacity:0.41;fill: #9bc700" />`,
+		`<rect x="315" y="135" width="45" height="45" style="fill-opacity:0.41;fill: #9bc700" />`,
+		// the black king on e8
+		`<circle cx="202" cy="22" r="22" style="fill-opacity:0.6;fill: #ff0000" />`,
+		// the knight arrow goes up to g3 then left to f3
+		`<polyline points="292.5,337.5 292.5,247.5 265.5,247.5"`,
+		`<polygon points="247.5,247.5 265.5,235.5 265.5,259.5" style="fill: #15781b" />`,
+		`<g style="opacity:0.5">`,
+		`<circle cx="247.5" cy="67.5" r="20" style="fill:none;stroke-width:4;stroke-opacity:0.8;stroke: #882020" />`,
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("expected svg to contain %s", want)
+		}
+	}
+}
+
+func TestPNG(t *testing.T) {
+	board := chess.NewGame().Position().Board()
+	tests := []struct {
+		encode        func(io.Writer) error
+		width, height int
+		// the center of e3, a dark square
+		x, y int
+	}{
+		{func(w io.Writer) error { return image.PNG(w, board) }, 360, 360, 202, 247},
+		{func(w io.Writer) error { return image.PNG(w, board, image.Perspective(chess.Black)) }, 360, 360, 157, 112},
+		{func(w io.Writer) error { return image.PNG(w, board, image.BoardSize(720)) }, 720, 720, 405, 495},
+		{func(w io.Writer) error { return image.PNG(w, board, image.Coordinates(image.CoordinatesOutside)) }, 376, 376, 218, 247},
+	}
+	dark := color.RGBA{165, 117, 81, 255}
+	for i, test := range tests {
+		buf := bytes.NewBuffer([]byte{})
+		if err := test.encode(buf); err != nil {
+			t.Fatal(err)
+		}
+		img, err := png.Decode(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b := img.Bounds(); b.Dx() != test.width || b.Dy() != test.height {
+			t.Errorf("test %d: expected %dx%d png but got %dx%d", i, test.width, test.height, b.Dx(), b.Dy())
+		}
+		if c := color.RGBAModel.Convert(img.At(test.x, test.y)); c != dark {
+			t.Errorf("test %d: expected %v at %d,%d but got %v", i, dark, test.x, test.y, c)
+		}
+	}
+}
+
+func TestPNGMarks(t *testing.T) {
+	g := chess.NewGame()
+	for _, m := range []string{"e4", "f5", "Qh5+"} {
+		if err := g.MoveStr(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	encode := func() []byte {
+		buf := bytes.NewBuffer([]byte{})
+		err := image.PNG(buf, g.Position().Board(),
+			image.LastMove(g.Moves()[2]),
+			image.Check(g.Position()),
+			image.Arrows(image.Arrow{From: chess.G1, To: chess.F3}),
+			image.CommentMarks("[%csl Rf7]"),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+	data := encode()
+	if !bytes.Equal(data, encode()) {
+		t.Fatal("expected png output to be deterministic")
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		x, y int
+		c    color.RGBA
+	}{
+		// the empty d1 square with the last move highlight
+		{137, 317, color.RGBA{202, 205, 98, 255}},
+		// the check circle around the black king on e8
+		{183, 22, color.RGBA{247, 84, 66, 255}},
+		// the bend of the knight arrow on g3
+		{292, 247, color.RGBA{50, 119, 38, 255}},
+		// the circle around f7
+		{247, 47, color.RGBA{156, 67, 59, 255}},
+	} {
+		if c := color.RGBAModel.Convert(img.At(test.x, test.y)); c != test.c {
+			t.Errorf("expected %v at %d,%d but got %v", test.c, test.x, test.y, c)
+		}
+	}
+}
+
+func TestGIF(t *testing.T) {
+	pgn := "[Event \"?\"]\n\n1. e4 { [%clk 0:05:00] [%eval 0.3] } 1... e5 { [%clk 0:04:58] } 2. Nf3 *\n"
+	g, err := chess.NewGameFromPGN(strings.NewReader(pgn))
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := bytes.NewBuffer([]byte{})
+	if err := image.GIF(buf, g, image.FrameDelay(500*time.Millisecond), image.FinalFrameDelay(2*time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	anim, err := gif.DecodeAll(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(anim.Image) != 4 {
+		t.Fatalf("expected 4 frames but got %d", len(anim.Image))
+	}
+	if expected := []int{50, 50, 50, 200}; fmt.Sprint(anim.Delay) != fmt.Sprint(expected) {
+		t.Fatalf("expected delays %v but got %v", expected, anim.Delay)
+	}
+	light := color.RGBA{235, 209, 166, 255}
+	highlighted := color.RGBA{202, 205, 98, 255}
+	// the corner of e2, which is only highlighted after 1. e4
+	for i, expected := range []color.RGBA{light, highlighted, light, light} {
+		if c := color.RGBAModel.Convert(anim.Image[i].At(184, 274)); c != expected {
+			t.Errorf("frame %d: expected %v on e2 but got %v", i, expected, c)
+		}
+	}
+
+	buf.Reset()
+	if err := image.GIF(buf, g, image.Captions(image.MoveCaption, image.EvalCaption, image.ClockCaption)); err != nil {
+		t.Fatal(err)
+	}
+	anim, err = gif.DecodeAll(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := anim.Image[0].Bounds(); b.Dx() != 360 || b.Dy() != 380 {
+		t.Fatalf("expected 360x380 frames with captions but got %dx%d", b.Dx(), b.Dy())
+	}
+}
+
+func testPieceSet(skip string) fstest.MapFS {
+	fsys := fstest.MapFS{}
+	for _, c := range []string{"w", "b"} {
+		for _, p := range []string{"K", "Q", "R", "B", "N", "P"} {
+			if c+p == skip {
+				continue
+			}
+			fsys[c+p+".svg"] = &fstest.MapFile{Data: []byte(`<?xml version="1.0"?>
+<svg xmlns="http://www.w3.org/2000/svg" width="100" height="100">
+<rect x="10" y="10" width="80" height="80" fill="#ff0000" />
+</svg>`)}
+		}
+	}
+	return fsys
+}
+
+func TestPieceSet(t *testing.T) {
+	if _, err := image.LoadPieceSet(testPieceSet("bN")); err == nil {
+		t.Fatal("expected an error loading a piece set without bN.svg")
+	}
+	set, err := image.LoadPieceSet(testPieceSet(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	board := chess.NewGame().Position().Board()
+	buf := bytes.NewBuffer([]byte{})
+	if err := image.SVG(buf, board, image.Pieces(set)); err != nil {
+		t.Fatal(err)
+	}
+	want := `<svg xmlns="http://www.w3.org/2000/svg" x="0" y="0" width="45" height="45" viewBox="0 0 100 100">`
+	if !strings.Contains(buf.String(), want) {
+		t.Errorf("expected svg to contain %s", want)
+	}
+	buf.Reset()
+	if err := image.PNG(buf, board, image.Pieces(set)); err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	red := color.RGBA{255, 0, 0, 255}
+	if c := color.RGBAModel.Convert(img.At(22, 337)); c != red {
+		t.Errorf("expected %v on a1 but got %v", red, c)
+	}
+}
+
+func TestTheme(t *testing.T) {
+	theme, ok := image.ThemeByName("Green")
+	if !ok {
+		t.Fatal("expected the green theme")
+	}
+	buf := bytes.NewBuffer([]byte{})
+	if err := image.SVG(buf, chess.NewGame().Position().Board(), image.Theme(theme)); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`<rect x="0" y="315" width="45" height="45" style="fill: #769656" />`,
+		`<text x="2" y="326" style="font-family:Helvetica, Arial, sans-serif;font-size:11px;fill: #eeeed2" >1</text>`,
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("expected svg to contain %s", want)
+		}
+	}
+	if _, ok := image.ThemeByName("purple"); ok {
+		t.Error("expected no purple theme")
+	}
+}
@@ -0,0 +1,266 @@
+package image
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/barakmich/chess"
+)
+
+// Arrow is an arrow drawn from the center of one square to another.
+// Arrows along a knight's move are bent.
+type Arrow struct {
+	From chess.Square
+	To   chess.Square
+	// Color defaults to green and Opacity to 0.8.
+	Color   color.Color
+	Opacity float64
+}
+
+// Circle is a circle drawn around a square.
+type Circle struct {
+	Square chess.Square
+	// Color defaults to green and Opacity to 0.8.
+	Color   color.Color
+	Opacity float64
+}
+
+// The colors of the comment commands, as drawn by Lichess.
+var (
+	Green  = color.RGBA{21, 120, 27, 255}
+	Red    = color.RGBA{136, 32, 32, 255}
+	Blue   = color.RGBA{0, 48, 136, 255}
+	Yellow = color.RGBA{230, 143, 0, 255}
+)
+
+var (
+	lastMoveColor = color.RGBA{155, 199, 0, 255}
+	checkColor    = color.RGBA{255, 0, 0, 255}
+)
+
+const (
+	defaultMarkOpacity  = 0.2
+	defaultArrowOpacity = 0.8
+	lastMoveOpacity     = 0.41
+	arrowWidth          = 9
+	arrowHeadLength     = 18
+	arrowHeadHalfWidth  = 12
+	circleRadius        = 20
+	circleWidth         = 4
+)
+
+type squareMark struct {
+	color   color.Color
+	opacity float64
+}
+
+func (a Arrow) color() color.Color {
+	if a.Color == nil {
+		return Green
+	}
+	return a.Color
+}
+
+func (a Arrow) opacity() float64 {
+	if a.Opacity == 0 {
+		return defaultArrowOpacity
+	}
+	return a.Opacity
+}
+
+func (c Circle) color() color.Color {
+	if c.Color == nil {
+		return Green
+	}
+	return c.Color
+}
+
+func (c Circle) opacity() float64 {
+	if c.Opacity == 0 {
+		return defaultArrowOpacity
+	}
+	return c.Opacity
+}
+
+// HighlightSquares is designed to be used as an optional argument
+// to the SVG function.  It fills the given squares with the color
+// at the given opacity, from 0 to 1.
+func HighlightSquares(c color.Color, opacity float64, sqs ...chess.Square) func(*encoder) {
+	return func(e *encoder) {
+		for _, sq := range sqs {
+			e.marks[sq] = squareMark{color: c, opacity: opacity}
+		}
+	}
+}
+
+// Arrows is designed to be used as an optional argument
+// to the SVG function.  It draws the arrows over the pieces.
+func Arrows(arrows ...Arrow) func(*encoder) {
+	return func(e *encoder) {
+		for _, a := range arrows {
+			if a.From != a.To {
+				e.arrows = append(e.arrows, a)
+			}
+		}
+	}
+}
+
+// Circles is designed to be used as an optional argument
+// to the SVG function.  It draws the circles over the pieces.
+func Circles(circles ...Circle) func(*encoder) {
+	return func(e *encoder) {
+		e.circles = append(e.circles, circles...)
+	}
+}
+
+// LastMove is designed to be used as an optional argument
+// to the SVG function.  It highlights the squares the move
+// was played from and to, or only the square of a Crazyhouse drop.
+func LastMove(m chess.Move) func(*encoder) {
+	if m.HasTag(chess.Drop) {
+		return HighlightSquares(lastMoveColor, lastMoveOpacity, m.S2())
+	}
+	return HighlightSquares(lastMoveColor, lastMoveOpacity, m.S1(), m.S2())
+}
+
+// Check is designed to be used as an optional argument
+// to the SVG function.  It highlights the king of the side
+// to move if it is in check in the position.
+func Check(pos *chess.Position) func(*encoder) {
+	return func(e *encoder) {
+		if !pos.InCheck() {
+			return
+		}
+		king := chess.GetPiece(chess.King, pos.Turn())
+		for sq := chess.A1; sq <= chess.H8; sq++ {
+			if pos.Board().Piece(sq) == king {
+				e.check = sq
+			}
+		}
+	}
+}
+
+// CommentMarks is designed to be used as an optional argument
+// to the SVG function.  It draws the arrows and circles of the
+// [%cal] and [%csl] commands in the PGN comments.
+func CommentMarks(comments ...string) func(*encoder) {
+	return func(e *encoder) {
+		for _, c := range comments {
+			arrows, circles := ParseCommentMarks(c)
+			Arrows(arrows...)(e)
+			Circles(circles...)(e)
+		}
+	}
+}
+
+var commentCommandRegex = regexp.MustCompile(`\[%(cal|csl)\s+([^\]]*)\]`)
+
+var commentColors = map[byte]color.Color{
+	'G': Green,
+	'R': Red,
+	'B': Blue,
+	'Y': Yellow,
+}
+
+// ParseCommentMarks returns the arrows of the [%cal Ga1a8,Rb2b4] commands
+// and the circles of the [%csl Re4] commands in the PGN comment, as
+// written by Lichess and ChessBase.  Invalid entries are skipped.
+func ParseCommentMarks(comment string) ([]Arrow, []Circle) {
+	var arrows []Arrow
+	var circles []Circle
+	for _, match := range commentCommandRegex.FindAllStringSubmatch(comment, -1) {
+		for _, entry := range strings.Split(match[2], ",") {
+			entry = strings.TrimSpace(entry)
+			if entry == "" {
+				continue
+			}
+			c, ok := commentColors[entry[0]]
+			if !ok {
+				continue
+			}
+			switch {
+			case match[1] == "cal" && len(entry) == 5:
+				from, ok1 := parseSquare(entry[1:3])
+				to, ok2 := parseSquare(entry[3:5])
+				if ok1 && ok2 && from != to {
+					arrows = append(arrows, Arrow{From: from, To: to, Color: c})
+				}
+			case match[1] == "csl" && len(entry) == 3:
+				if sq, ok := parseSquare(entry[1:3]); ok {
+					circles = append(circles, Circle{Square: sq, Color: c})
+				}
+			}
+		}
+	}
+	return arrows, circles
+}
+
+func parseSquare(s string) (chess.Square, bool) {
+	if len(s) != 2 || s[0] < 'a' || s[0] > 'h' || s[1] < '1' || s[1] > '8' {
+		return chess.NoSquare, false
+	}
+	return chess.NewSquare(chess.File(s[0]-'a'), chess.Rank(s[1]-'1')), true
+}
+
+// center returns the center of the square in board units.
+func (e *encoder) center(sq chess.Square) (x, y float64) {
+	ix, iy := e.xyForSquare(sq)
+	return float64(ix) + sqWidth/2.0, float64(iy) + sqHeight/2.0
+}
+
+// arrowGeometry returns the points of an arrow's shaft, bent for knight
+// moves, and of its head, which ends at the center of the target square.
+func (e *encoder) arrowGeometry(a Arrow) (shaft, head []point) {
+	fx, fy := e.center(a.From)
+	tx, ty := e.center(a.To)
+	shaft = []point{{fx, fy}}
+	df := math.Abs(float64(int(a.To.File()) - int(a.From.File())))
+	dr := math.Abs(float64(int(a.To.Rank()) - int(a.From.Rank())))
+	if (df == 1 && dr == 2) || (df == 2 && dr == 1) {
+		// go along the longer side first
+		if dr > df {
+			shaft = append(shaft, point{fx, ty})
+		} else {
+			shaft = append(shaft, point{tx, fy})
+		}
+	}
+	prev := shaft[len(shaft)-1]
+	dx, dy := tx-prev.x, ty-prev.y
+	length := math.Hypot(dx, dy)
+	dx, dy = dx/length, dy/length
+	bx, by := tx-dx*arrowHeadLength, ty-dy*arrowHeadLength
+	shaft = append(shaft, point{bx, by})
+	head = []point{
+		{tx, ty},
+		{bx - dy*arrowHeadHalfWidth, by + dx*arrowHeadHalfWidth},
+		{bx + dy*arrowHeadHalfWidth, by - dx*arrowHeadHalfWidth},
+	}
+	return shaft, head
+}
+
+// arrowXML returns the arrow as an SVG polyline and polygon.
+func (e *encoder) arrowXML(a Arrow) string {
+	shaft, head := e.arrowGeometry(a)
+	hex := colorToHex(a.color())
+	return fmt.Sprintf(`<g style="opacity:%g">`+"\n"+
+		`<polyline points="%s" style="fill:none;stroke-width:%d;stroke-linecap:round;stroke-linejoin:round;stroke: %s" />`+"\n"+
+		`<polygon points="%s" style="fill: %s" />`+"\n</g>\n",
+		a.opacity(), formatPoints(shaft), arrowWidth, hex, formatPoints(head), hex)
+}
+
+func formatPoints(points []point) string {
+	strs := make([]string, len(points))
+	for i, p := range points {
+		strs[i] = formatCoord(p.x) + "," + formatCoord(p.y)
+	}
+	return strings.Join(strs, " ")
+}
+
+// formatCoord rounds the coordinate to two decimals so output is stable.
+func formatCoord(f float64) string {
+	return strconv.FormatFloat(math.Round(f*100)/100, 'f', -1, 64)
+}
@@ -0,0 +1,122 @@
+package image
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"math"
+
+	"github.com/barakmich/chess"
+)
+
+// PNG writes the board as a PNG image into the writer.  It takes the
+// same options as SVG and renders the pieces with a built-in rasterizer,
+// so it needs no outside tools.  The output only depends on the board and
+// options, so it can be compared against golden files.
+func PNG(w io.Writer, b *chess.Board, opts ...func(*encoder)) error {
+	e := new(w, opts)
+	return png.Encode(w, e.Image(b))
+}
+
+// Image returns the board drawn as an image.
+func (e *encoder) Image(b *chess.Board) *image.RGBA {
+	width, height := e.canvasSize()
+	img := image.NewRGBA(image.Rect(0, 0, e.scale(width), e.scale(height)))
+	s := float64(img.Bounds().Dx()) / float64(width)
+	m := scaling(s)
+	margin := float64(e.margin())
+	fill(img, transformAll([][]point{rectPolygon(margin, 0, boardWidth, boardHeight)}, m), false, color.Black, 1)
+
+	for i := 0; i < 64; i++ {
+		sq := chess.Square(i)
+		ix, iy := e.xyForSquare(sq)
+		x, y := float64(ix), float64(iy)
+		square := transformAll([][]point{rectPolygon(x, y, sqWidth, sqHeight)}, m)
+		fill(img, square, false, opaque(e.colorForSquare(sq)), 1)
+		if mark, ok := e.marks[sq]; ok {
+			fill(img, square, false, opaque(mark.color), mark.opacity)
+		}
+		if sq == e.check {
+			c := point{float64(ix + sqWidth/2), float64(iy + sqHeight/2)}
+			fill(img, transformAll([][]point{circlePolygon(c, sqWidth/2)}, m), false, checkColor, 0.6)
+		}
+		if p := b.Piece(sq); p != chess.NoPiece {
+			e.drawPiece(img, p, x*s, y*s, s)
+		}
+		e.drawRasterCoordinates(img, sq, x, y, s)
+	}
+	for _, c := range e.circles {
+		x, y := e.center(c.Square)
+		ring := strokePolygons(circlePolygon(point{x, y}, circleRadius), true, circleWidth, capButt)
+		fill(img, transformAll(ring, m), false, opaque(c.color()), c.opacity())
+	}
+	for _, a := range e.arrows {
+		shaft, head := e.arrowGeometry(a)
+		polys := append(strokePolygons(shaft, false, arrowWidth, capRound), head)
+		orient(head)
+		fill(img, transformAll(polys, m), false, opaque(a.color()), a.opacity())
+	}
+	return img
+}
+
+// tileKey identifies a piece drawn at a scale and a fraction of a pixel
+// offset, which is all that changes how it is rasterized.
+type tileKey struct {
+	piece  chess.Piece
+	s      float64
+	fx, fy float64
+}
+
+// drawPiece draws the piece with its top left corner at x, y in pixels and
+// s pixels per board unit.  Rasterized pieces are cached on the encoder so
+// boards drawn with the same encoder, like the frames of a GIF, reuse them.
+func (e *encoder) drawPiece(img *image.RGBA, p chess.Piece, x, y, s float64) {
+	ox, oy := math.Floor(x), math.Floor(y)
+	key := tileKey{piece: p, s: s, fx: x - ox, fy: y - oy}
+	tile, ok := e.tiles[key]
+	if !ok {
+		size := int(math.Ceil(sqWidth*s + 1))
+		tile = image.NewRGBA(image.Rect(0, 0, size, size))
+		v := e.pieceSet().images[p]
+		v.draw(tile, scaling(sqWidth/v.width*s).then(translate(key.fx, key.fy)))
+		e.tiles[key] = tile
+	}
+	r := tile.Bounds().Add(image.Pt(int(ox), int(oy)))
+	draw.Draw(img, r, tile, image.Point{}, draw.Over)
+}
+
+// drawRasterCoordinates draws the coordinates like drawCoordinates, with s
+// pixels per board unit.
+func (e *encoder) drawRasterCoordinates(img *image.RGBA, sq chess.Square, x, y, s float64) {
+	const size = 11 * 0.7
+	leftFile, bottomRank := chess.FileA, chess.Rank1
+	if e.flipped {
+		leftFile, bottomRank = chess.FileH, chess.Rank8
+	}
+	switch e.coords {
+	case CoordinatesInside:
+		c := opaque(e.colorForText(sq))
+		if sq.File() == leftFile {
+			drawText(img, sq.Rank().String(), (x+sqWidth*1/20)*s, (y+sqHeight*5/20)*s, size*s, anchorStart, c)
+		}
+		if sq.Rank() == bottomRank {
+			drawText(img, sq.File().String(), (x+sqWidth*19/20)*s, (y+sqHeight-sqHeight*1/15)*s, size*s, anchorEnd, c)
+		}
+	case CoordinatesOutside:
+		c := opaque(e.outsideTextColor())
+		if sq.File() == leftFile {
+			drawText(img, sq.Rank().String(), coordsMargin/2*s, (y+sqHeight/2+4)*s, size*s, anchorMiddle, c)
+		}
+		if sq.Rank() == bottomRank {
+			drawText(img, sq.File().String(), (x+sqWidth/2)*s, (boardHeight+coordsMargin-4)*s, size*s, anchorMiddle, c)
+		}
+	}
+}
+
+// opaque returns the color without its alpha, as colorToHex does for SVG.
+func opaque(c color.Color) color.RGBA {
+	r, g, b, _ := c.RGBA()
+	return color.RGBA{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), 255}
+}
@@ -0,0 +1,262 @@
+package image
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"sort"
+)
+
+// point is a point in pixels or in the units of a drawing.
+type point struct {
+	x, y float64
+}
+
+// affine is the 2D transform x' = a*x + c*y + e, y' = b*x + d*y + f, with
+// the same parameters as an SVG matrix().
+type affine [6]float64
+
+var identity = affine{1, 0, 0, 1, 0, 0}
+
+// then returns the transform applying m and then n.
+func (m affine) then(n affine) affine {
+	return affine{
+		n[0]*m[0] + n[2]*m[1],
+		n[1]*m[0] + n[3]*m[1],
+		n[0]*m[2] + n[2]*m[3],
+		n[1]*m[2] + n[3]*m[3],
+		n[0]*m[4] + n[2]*m[5] + n[4],
+		n[1]*m[4] + n[3]*m[5] + n[5],
+	}
+}
+
+func (m affine) apply(p point) point {
+	return point{m[0]*p.x + m[2]*p.y + m[4], m[1]*p.x + m[3]*p.y + m[5]}
+}
+
+// scale returns the factor the transform scales lengths by, on average.
+func (m affine) scale() float64 {
+	return math.Sqrt(math.Abs(m[0]*m[3] - m[1]*m[2]))
+}
+
+func translate(x, y float64) affine {
+	return affine{1, 0, 0, 1, x, y}
+}
+
+func scaling(s float64) affine {
+	return affine{s, 0, 0, s, 0, 0}
+}
+
+// subsamples is the number of scanlines sampled per row of pixels.
+const subsamples = 4
+
+// fill composites the polygons onto img with the color at the given
+// opacity.  Overlapping polygons are filled once with the nonzero rule, or
+// cancel out with the even-odd rule.  Edges are anti-aliased by sampling
+// several scanlines per row with exact horizontal coverage.
+func fill(img *image.RGBA, polys [][]point, evenOdd bool, c color.Color, opacity float64) {
+	type edge struct {
+		p0, p1 point
+		dir    int
+	}
+	var edges []edge
+	minY, maxY := math.Inf(1), math.Inf(-1)
+	minX, maxX := math.Inf(1), math.Inf(-1)
+	for _, poly := range polys {
+		for i := range poly {
+			p0, p1 := poly[i], poly[(i+1)%len(poly)]
+			minX, maxX = math.Min(minX, p0.x), math.Max(maxX, p0.x)
+			minY, maxY = math.Min(minY, p0.y), math.Max(maxY, p0.y)
+			if p0.y == p1.y {
+				continue
+			}
+			if p0.y < p1.y {
+				edges = append(edges, edge{p0, p1, 1})
+			} else {
+				edges = append(edges, edge{p1, p0, -1})
+			}
+		}
+	}
+	if len(edges) == 0 {
+		return
+	}
+	b := img.Bounds()
+	y0, y1 := maxInt(b.Min.Y, int(math.Floor(minY))), minInt(b.Max.Y, int(math.Ceil(maxY)))
+	x0, x1 := maxInt(b.Min.X, int(math.Floor(minX))), minInt(b.Max.X, int(math.Ceil(maxX)))
+	if x0 >= x1 {
+		return
+	}
+	r, g, bl, _ := c.RGBA()
+	src := [3]float64{float64(r >> 8), float64(g >> 8), float64(bl >> 8)}
+	cov := make([]float64, x1-x0)
+	type crossing struct {
+		x   float64
+		dir int
+	}
+	var xs []crossing
+	for py := y0; py < y1; py++ {
+		for i := range cov {
+			cov[i] = 0
+		}
+		for j := 0; j < subsamples; j++ {
+			sy := float64(py) + (float64(j)+0.5)/subsamples
+			xs = xs[:0]
+			for _, e := range edges {
+				if sy < e.p0.y || sy >= e.p1.y {
+					continue
+				}
+				t := (sy - e.p0.y) / (e.p1.y - e.p0.y)
+				xs = append(xs, crossing{e.p0.x + t*(e.p1.x-e.p0.x), e.dir})
+			}
+			sort.Slice(xs, func(a, b int) bool { return xs[a].x < xs[b].x })
+			winding := 0
+			for k := 0; k+1 < len(xs); k++ {
+				winding += xs[k].dir
+				inside := winding != 0
+				if evenOdd {
+					inside = winding%2 != 0
+				}
+				if inside {
+					addSpan(cov, x0, xs[k].x, xs[k+1].x)
+				}
+			}
+		}
+		for i, cv := range cov {
+			if cv <= 0 {
+				continue
+			}
+			a := math.Min(cv, 1) * opacity
+			off := img.PixOffset(x0+i, py)
+			for ch := 0; ch < 3; ch++ {
+				img.Pix[off+ch] = uint8(math.Round(src[ch]*a + float64(img.Pix[off+ch])*(1-a)))
+			}
+			img.Pix[off+3] = uint8(math.Round(255*a + float64(img.Pix[off+3])*(1-a)))
+		}
+	}
+}
+
+// addSpan adds the coverage of one scanline from xa to xb to the pixels.
+func addSpan(cov []float64, x0 int, xa, xb float64) {
+	xa, xb = math.Max(xa, float64(x0)), math.Min(xb, float64(x0+len(cov)))
+	for px := int(math.Floor(xa)); float64(px) < xb; px++ {
+		overlap := math.Min(xb, float64(px+1)) - math.Max(xa, float64(px))
+		if overlap > 0 {
+			cov[px-x0] += overlap / subsamples
+		}
+	}
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// lineCap is how the ends of open strokes are drawn.
+type lineCap int
+
+const (
+	capButt lineCap = iota
+	capRound
+	capSquare
+)
+
+// strokePolygons returns polygons covering the stroke of the polyline of the
+// given width, with round joins.  The polygons all wind the same way so they
+// can be filled together with the nonzero rule.
+func strokePolygons(pts []point, closed bool, width float64, cap lineCap) [][]point {
+	hw := width / 2
+	var polys [][]point
+	n := len(pts)
+	if n == 0 {
+		return nil
+	}
+	segments := n - 1
+	if closed {
+		segments = n
+	}
+	for i := 0; i < segments; i++ {
+		p0, p1 := pts[i], pts[(i+1)%n]
+		dx, dy := p1.x-p0.x, p1.y-p0.y
+		l := math.Hypot(dx, dy)
+		if l == 0 {
+			continue
+		}
+		dx, dy = dx/l, dy/l
+		if !closed && cap == capSquare {
+			if i == 0 {
+				p0 = point{p0.x - dx*hw, p0.y - dy*hw}
+			}
+			if i == segments-1 {
+				p1 = point{p1.x + dx*hw, p1.y + dy*hw}
+			}
+		}
+		nx, ny := -dy*hw, dx*hw
+		polys = append(polys, []point{
+			{p0.x + nx, p0.y + ny}, {p1.x + nx, p1.y + ny},
+			{p1.x - nx, p1.y - ny}, {p0.x - nx, p0.y - ny},
+		})
+	}
+	for i, p := range pts {
+		end := !closed && (i == 0 || i == n-1)
+		if end && cap != capRound {
+			continue
+		}
+		polys = append(polys, circlePolygon(p, hw))
+	}
+	for _, poly := range polys {
+		orient(poly)
+	}
+	return polys
+}
+
+// circlePolygon returns a polygon approximating the circle.
+func circlePolygon(c point, r float64) []point {
+	n := int(math.Ceil(2 * math.Pi * r / 1.5))
+	if n < 12 {
+		n = 12
+	}
+	pts := make([]point, n)
+	for i := range pts {
+		a := 2 * math.Pi * float64(i) / float64(n)
+		pts[i] = point{c.x + r*math.Cos(a), c.y + r*math.Sin(a)}
+	}
+	return pts
+}
+
+func rectPolygon(x, y, w, h float64) []point {
+	return []point{{x, y}, {x + w, y}, {x + w, y + h}, {x, y + h}}
+}
+
+// orient reverses the polygon if needed so it winds clockwise on screen.
+func orient(poly []point) {
+	area := 0.0
+	for i := range poly {
+		p0, p1 := poly[i], poly[(i+1)%len(poly)]
+		area += p0.x*p1.y - p1.x*p0.y
+	}
+	if area < 0 {
+		for i, j := 0, len(poly)-1; i < j; i, j = i+1, j-1 {
+			poly[i], poly[j] = poly[j], poly[i]
+		}
+	}
+}
+
+func transformAll(polys [][]point, m affine) [][]point {
+	out := make([][]point, len(polys))
+	for i, poly := range polys {
+		out[i] = make([]point, len(poly))
+		for j, p := range poly {
+			out[i][j] = m.apply(p)
+		}
+	}
+	return out
+}
@@ -0,0 +1,182 @@
+package image
+
+import (
+	"fmt"
+	"image/color"
+	"io/fs"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/barakmich/chess"
+	"github.com/barakmich/chess/image/internal"
+)
+
+// PieceSet is the art for the twelve pieces, as SVG documents.
+type PieceSet struct {
+	svgs    map[chess.Piece][]byte
+	images  map[chess.Piece]*vectorImage
+	builtin bool
+}
+
+var (
+	defaultPiecesOnce sync.Once
+	defaultPieces     *PieceSet
+)
+
+// DefaultPieceSet returns the pieces drawn when no other set is given.
+func DefaultPieceSet() *PieceSet {
+	defaultPiecesOnce.Do(func() {
+		s, err := loadPieceSet(func(name string) ([]byte, error) {
+			return internal.Asset("pieces/" + name)
+		})
+		if err != nil {
+			panic(err)
+		}
+		s.builtin = true
+		defaultPieces = s
+	})
+	return defaultPieces
+}
+
+// LoadPieceSet loads a piece set from the SVG files in the root of fsys,
+// which are named by color and piece like the built-in set: wK.svg, wQ.svg,
+// wR.svg, wB.svg, wN.svg, wP.svg and bK.svg through bP.svg.  Use fs.Sub for
+// a set in a subdirectory.  An error is returned if a file is missing or
+// isn't an SVG document the package can draw.
+func LoadPieceSet(fsys fs.FS) (*PieceSet, error) {
+	return loadPieceSet(func(name string) ([]byte, error) {
+		return fs.ReadFile(fsys, name)
+	})
+}
+
+func loadPieceSet(read func(name string) ([]byte, error)) (*PieceSet, error) {
+	s := &PieceSet{
+		svgs:   map[chess.Piece][]byte{},
+		images: map[chess.Piece]*vectorImage{},
+	}
+	for _, c := range []chess.Color{chess.White, chess.Black} {
+		for _, t := range []chess.PieceType{chess.King, chess.Queen, chess.Rook, chess.Bishop, chess.Knight, chess.Pawn} {
+			p := chess.GetPiece(t, c)
+			name := pieceFileName(p)
+			data, err := read(name)
+			if err != nil {
+				return nil, fmt.Errorf("image: piece set: %w", err)
+			}
+			v, err := parseVectorImage(data)
+			if err != nil {
+				return nil, fmt.Errorf("image: piece set %s: %w", name, err)
+			}
+			s.svgs[p] = data
+			s.images[p] = v
+		}
+	}
+	return s, nil
+}
+
+func pieceFileName(p chess.Piece) string {
+	return p.Color().String() + pieceTypeMap[p.Type()] + ".svg"
+}
+
+var svgSizeAttrRegex = regexp.MustCompile(`\s(x|y|width|height|viewBox)\s*=\s*("[^"]*"|'[^']*')`)
+
+// xml returns the piece's SVG placed at x, y of a canvas of the given
+// size.
+func (s *PieceSet) xml(x, y, width, height int, p chess.Piece) string {
+	if s.builtin {
+		return pieceXML(x, y, width, height, p)
+	}
+	data := string(s.svgs[p])
+	start := strings.Index(data, "<svg")
+	end := start + strings.Index(data[start:], ">")
+	v := s.images[p]
+	tag := svgSizeAttrRegex.ReplaceAllString(data[start:end], "")
+	return fmt.Sprintf(`%s x="%d" y="%d" width="%d" height="%d" viewBox="%g %g %g %g"%s`,
+		tag, x, y, sqWidth, sqHeight, v.minX, v.minY, v.width, v.height, data[end:])
+}
+
+// BoardTheme is the look of a board.  Fields left empty keep their
+// defaults.
+type BoardTheme struct {
+	Name  string
+	Light color.Color
+	Dark  color.Color
+	// LightText and DarkText are the colors of coordinates on light and
+	// dark squares, and default to the other square color.  LightText is
+	// also used for coordinates outside the board.
+	LightText color.Color
+	DarkText  color.Color
+	// Font is the CSS font-family of the coordinates in SVG output.  PNG
+	// and GIF output always use the built-in font.
+	Font   string
+	Pieces *PieceSet
+}
+
+// The built-in themes.
+var (
+	BrownTheme = BoardTheme{
+		Name:  "brown",
+		Light: color.RGBA{235, 209, 166, 1},
+		Dark:  color.RGBA{165, 117, 81, 1},
+	}
+	BlueTheme = BoardTheme{
+		Name:  "blue",
+		Light: color.RGBA{222, 227, 230, 1},
+		Dark:  color.RGBA{140, 162, 173, 1},
+		Font:  "Noto Sans, sans-serif",
+	}
+	GreenTheme = BoardTheme{
+		Name:      "green",
+		Light:     color.RGBA{238, 238, 210, 1},
+		Dark:      color.RGBA{118, 150, 86, 1},
+		LightText: color.RGBA{118, 150, 86, 1},
+		DarkText:  color.RGBA{238, 238, 210, 1},
+		Font:      "Helvetica, Arial, sans-serif",
+	}
+	GrayTheme = BoardTheme{
+		Name:      "gray",
+		Light:     color.RGBA{220, 220, 220, 1},
+		Dark:      color.RGBA{171, 171, 171, 1},
+		LightText: color.RGBA{90, 90, 90, 1},
+		DarkText:  color.RGBA{250, 250, 250, 1},
+		Font:      "monospace",
+	}
+)
+
+// ThemeByName returns the built-in theme with the name, ignoring case.
+func ThemeByName(name string) (BoardTheme, bool) {
+	for _, t := range []BoardTheme{BrownTheme, BlueTheme, GreenTheme, GrayTheme} {
+		if strings.EqualFold(t.Name, name) {
+			return t, true
+		}
+	}
+	return BoardTheme{}, false
+}
+
+// Theme is designed to be used as an optional argument
+// to the SVG function.  It sets the square and text colors,
+// font and pieces of the theme.
+func Theme(t BoardTheme) func(*encoder) {
+	return func(e *encoder) {
+		if t.Light != nil {
+			e.light = t.Light
+		}
+		if t.Dark != nil {
+			e.dark = t.Dark
+		}
+		e.lightText = t.LightText
+		e.darkText = t.DarkText
+		e.font = t.Font
+		if t.Pieces != nil {
+			e.pieces = t.Pieces
+		}
+	}
+}
+
+// Pieces is designed to be used as an optional argument
+// to the SVG function.  It draws the pieces of the set.
+func Pieces(s *PieceSet) func(*encoder) {
+	return func(e *encoder) {
+		e.pieces = s
+	}
+}
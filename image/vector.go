@@ -0,0 +1,625 @@
+package image
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// vectorImage is an SVG document parsed into flattened shapes, so it can be
+// rasterized without outside tools.  Only the subset of SVG used by piece
+// sets is supported: paths, circles, ellipses, rectangles, lines,
+// polylines and polygons, in groups with styles and transforms.
+type vectorImage struct {
+	// viewBox maps the document's coordinates onto its width and height.
+	minX, minY    float64
+	width, height float64
+	shapes        []shape
+}
+
+// shape is a flattened element with its resolved style.
+type shape struct {
+	subpaths      [][]point
+	closed        []bool
+	transform     affine
+	fill          color.Color
+	fillOpacity   float64
+	evenOdd       bool
+	stroke        color.Color
+	strokeOpacity float64
+	strokeWidth   float64
+	cap           lineCap
+}
+
+// svgStyle is the inherited presentation state of an element.
+type svgStyle struct {
+	fill          color.Color
+	fillOpacity   float64
+	evenOdd       bool
+	stroke        color.Color
+	strokeOpacity float64
+	strokeWidth   float64
+	cap           lineCap
+	opacity       float64
+}
+
+type svgNode struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr `xml:",any,attr"`
+	Children []svgNode  `xml:",any"`
+}
+
+func (n svgNode) attr(name string) string {
+	for _, a := range n.Attrs {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// parseVectorImage parses the SVG document.
+func parseVectorImage(data []byte) (*vectorImage, error) {
+	var root svgNode
+	if err := xml.NewDecoder(bytes.NewReader(data)).Decode(&root); err != nil {
+		return nil, fmt.Errorf("image: invalid svg: %w", err)
+	}
+	if root.XMLName.Local != "svg" {
+		return nil, fmt.Errorf("image: invalid svg: root element is %s", root.XMLName.Local)
+	}
+	v := &vectorImage{width: parseLength(root.attr("width")), height: parseLength(root.attr("height"))}
+	if vb := parseNumbers(root.attr("viewBox")); len(vb) == 4 {
+		v.minX, v.minY, v.width, v.height = vb[0], vb[1], vb[2], vb[3]
+	}
+	if v.width <= 0 || v.height <= 0 {
+		return nil, fmt.Errorf("image: svg has no size")
+	}
+	style := svgStyle{fill: color.Black, fillOpacity: 1, strokeWidth: 1, strokeOpacity: 1, opacity: 1}
+	if err := v.addNode(root, style, identity); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func (v *vectorImage) addNode(n svgNode, style svgStyle, m affine) error {
+	style = style.with(n)
+	if t := n.attr("transform"); t != "" {
+		tm, err := parseTransform(t)
+		if err != nil {
+			return err
+		}
+		m = tm.then(m)
+	}
+	var subpaths [][]point
+	var closed []bool
+	switch n.XMLName.Local {
+	case "svg", "g":
+		for _, c := range n.Children {
+			if err := v.addNode(c, style, m); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "path":
+		var err error
+		if subpaths, closed, err = flattenPath(n.attr("d")); err != nil {
+			return err
+		}
+	case "circle", "ellipse":
+		rx, ry := parseLength(n.attr("r")), parseLength(n.attr("r"))
+		if n.XMLName.Local == "ellipse" {
+			rx, ry = parseLength(n.attr("rx")), parseLength(n.attr("ry"))
+		}
+		c := point{parseLength(n.attr("cx")), parseLength(n.attr("cy"))}
+		subpaths, closed = [][]point{ellipsePoints(c, rx, ry)}, []bool{true}
+	case "rect":
+		x, y := parseLength(n.attr("x")), parseLength(n.attr("y"))
+		subpaths = [][]point{rectPolygon(x, y, parseLength(n.attr("width")), parseLength(n.attr("height")))}
+		closed = []bool{true}
+	case "line":
+		subpaths = [][]point{{
+			{parseLength(n.attr("x1")), parseLength(n.attr("y1"))},
+			{parseLength(n.attr("x2")), parseLength(n.attr("y2"))},
+		}}
+		closed = []bool{false}
+	case "polyline", "polygon":
+		nums := parseNumbers(n.attr("points"))
+		var pts []point
+		for i := 0; i+1 < len(nums); i += 2 {
+			pts = append(pts, point{nums[i], nums[i+1]})
+		}
+		subpaths, closed = [][]point{pts}, []bool{n.XMLName.Local == "polygon"}
+	default:
+		// metadata, defs and anything else isn't drawn
+		return nil
+	}
+	v.shapes = append(v.shapes, shape{
+		subpaths:      subpaths,
+		closed:        closed,
+		transform:     m,
+		fill:          style.fill,
+		fillOpacity:   style.fillOpacity * style.opacity,
+		evenOdd:       style.evenOdd,
+		stroke:        style.stroke,
+		strokeOpacity: style.strokeOpacity * style.opacity,
+		strokeWidth:   style.strokeWidth,
+		cap:           style.cap,
+	})
+	return nil
+}
+
+// with returns the style with the node's presentation attributes and style
+// properties applied.  Invalid values are ignored.
+func (s svgStyle) with(n svgNode) svgStyle {
+	props := map[string]string{}
+	for _, a := range n.Attrs {
+		props[a.Name.Local] = a.Value
+	}
+	for _, decl := range strings.Split(n.attr("style"), ";") {
+		if k, val, ok := strings.Cut(decl, ":"); ok {
+			props[strings.TrimSpace(k)] = strings.TrimSpace(val)
+		}
+	}
+	for k, val := range props {
+		val = strings.TrimSpace(val)
+		switch k {
+		case "fill":
+			if c, ok := parsePaint(val); ok {
+				s.fill = c
+			}
+		case "stroke":
+			if c, ok := parsePaint(val); ok {
+				s.stroke = c
+			}
+		case "fill-opacity":
+			s.fillOpacity = parseOpacity(val, s.fillOpacity)
+		case "stroke-opacity":
+			s.strokeOpacity = parseOpacity(val, s.strokeOpacity)
+		case "opacity":
+			s.opacity *= parseOpacity(val, 1)
+		case "stroke-width":
+			if w := parseLength(val); w >= 0 {
+				s.strokeWidth = w
+			}
+		case "fill-rule":
+			s.evenOdd = val == "evenodd"
+		case "stroke-linecap":
+			switch val {
+			case "butt":
+				s.cap = capButt
+			case "round":
+				s.cap = capRound
+			case "square":
+				s.cap = capSquare
+			}
+		}
+	}
+	return s
+}
+
+// draw rasterizes the image onto img, with m mapping the image's width and
+// height onto pixels.
+func (v *vectorImage) draw(img *image.RGBA, m affine) {
+	view := translate(-v.minX, -v.minY)
+	for _, sh := range v.shapes {
+		t := sh.transform.then(view).then(m)
+		var polys [][]point
+		for _, sp := range sh.subpaths {
+			if len(sp) > 1 {
+				polys = append(polys, sp)
+			}
+		}
+		if sh.fill != nil && sh.fillOpacity > 0 {
+			fill(img, transformAll(polys, t), sh.evenOdd, sh.fill, sh.fillOpacity)
+		}
+		if sh.stroke != nil && sh.strokeOpacity > 0 && sh.strokeWidth > 0 {
+			var strokes [][]point
+			for i, sp := range sh.subpaths {
+				strokes = append(strokes, strokePolygons(transformAll([][]point{sp}, t)[0], sh.closed[i], sh.strokeWidth*t.scale(), sh.cap)...)
+			}
+			fill(img, strokes, false, sh.stroke, sh.strokeOpacity)
+		}
+	}
+}
+
+var namedColors = map[string]color.Color{
+	"black": color.Black,
+	"white": color.White,
+	"red":   color.RGBA{255, 0, 0, 255},
+	"green": color.RGBA{0, 128, 0, 255},
+	"blue":  color.RGBA{0, 0, 255, 255},
+	"gray":  color.RGBA{128, 128, 128, 255},
+	"grey":  color.RGBA{128, 128, 128, 255},
+}
+
+// parsePaint parses a fill or stroke value.  It returns a nil color for
+// none, and false if the value isn't understood.
+func parsePaint(s string) (color.Color, bool) {
+	s = strings.ToLower(s)
+	if s == "none" {
+		return nil, true
+	}
+	if c, ok := namedColors[s]; ok {
+		return c, true
+	}
+	if !strings.HasPrefix(s, "#") {
+		return nil, false
+	}
+	hex := s[1:]
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) != 6 {
+		return nil, false
+	}
+	n, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return nil, false
+	}
+	return color.RGBA{uint8(n >> 16), uint8(n >> 8), uint8(n), 255}, true
+}
+
+func parseOpacity(s string, def float64) float64 {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return def
+	}
+	return math.Max(0, math.Min(1, f))
+}
+
+// parseLength parses a length, ignoring px units.  It returns 0 for an
+// invalid length.
+func parseLength(s string) float64 {
+	f, _ := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(s), "px"), 64)
+	return f
+}
+
+// parseNumbers parses a list of numbers separated by spaces or commas.
+func parseNumbers(s string) []float64 {
+	var nums []float64
+	sc := &pathScanner{s: s}
+	for {
+		f, ok := sc.number()
+		if !ok {
+			return nums
+		}
+		nums = append(nums, f)
+	}
+}
+
+// parseTransform parses a list of SVG transforms.
+func parseTransform(s string) (affine, error) {
+	m := identity
+	for {
+		s = strings.TrimLeft(s, " ,\t\n")
+		if s == "" {
+			return m, nil
+		}
+		open := strings.IndexByte(s, '(')
+		end := strings.IndexByte(s, ')')
+		if open < 0 || end < open {
+			return m, fmt.Errorf("image: invalid svg transform %q", s)
+		}
+		name, args := strings.TrimSpace(s[:open]), parseNumbers(s[open+1:end])
+		s = s[end+1:]
+		var t affine
+		switch {
+		case name == "matrix" && len(args) == 6:
+			copy(t[:], args)
+		case name == "translate" && len(args) == 1:
+			t = translate(args[0], 0)
+		case name == "translate" && len(args) == 2:
+			t = translate(args[0], args[1])
+		case name == "scale" && len(args) == 1:
+			t = scaling(args[0])
+		case name == "scale" && len(args) == 2:
+			t = affine{args[0], 0, 0, args[1], 0, 0}
+		case name == "rotate" && (len(args) == 1 || len(args) == 3):
+			a := args[0] * math.Pi / 180
+			t = affine{math.Cos(a), math.Sin(a), -math.Sin(a), math.Cos(a), 0, 0}
+			if len(args) == 3 {
+				t = translate(-args[1], -args[2]).then(t).then(translate(args[1], args[2]))
+			}
+		default:
+			return m, fmt.Errorf("image: unsupported svg transform %s", name)
+		}
+		// transforms in a list apply right to left
+		m = t.then(m)
+	}
+}
+
+// pathScanner reads the numbers and commands of path data.
+type pathScanner struct {
+	s string
+	i int
+}
+
+func (sc *pathScanner) skipSpace() {
+	for sc.i < len(sc.s) && strings.IndexByte(" ,\t\r\n", sc.s[sc.i]) >= 0 {
+		sc.i++
+	}
+}
+
+func (sc *pathScanner) number() (float64, bool) {
+	sc.skipSpace()
+	start := sc.i
+	if sc.i < len(sc.s) && (sc.s[sc.i] == '-' || sc.s[sc.i] == '+') {
+		sc.i++
+	}
+	dot, exp := false, false
+scan:
+	for ; sc.i < len(sc.s); sc.i++ {
+		c := sc.s[sc.i]
+		switch {
+		case c >= '0' && c <= '9':
+		case c == '.' && !dot && !exp:
+			dot = true
+		case (c == 'e' || c == 'E') && !exp && sc.i > start:
+			exp = true
+			if sc.i+1 < len(sc.s) && (sc.s[sc.i+1] == '-' || sc.s[sc.i+1] == '+') {
+				sc.i++
+			}
+		default:
+			break scan
+		}
+	}
+	f, err := strconv.ParseFloat(sc.s[start:sc.i], 64)
+	if err != nil {
+		sc.i = start
+		return 0, false
+	}
+	return f, true
+}
+
+// flag reads an arc flag, which may be written without a separator.
+func (sc *pathScanner) flag() (bool, bool) {
+	sc.skipSpace()
+	if sc.i < len(sc.s) && (sc.s[sc.i] == '0' || sc.s[sc.i] == '1') {
+		sc.i++
+		return sc.s[sc.i-1] == '1', true
+	}
+	return false, false
+}
+
+// curveSegments is the number of lines each Bézier curve is flattened to.
+const curveSegments = 16
+
+// flattenPath converts SVG path data to polylines.
+func flattenPath(d string) ([][]point, []bool, error) {
+	var subpaths [][]point
+	var closed []bool
+	var cur []point
+	var pos, start, lastCtrl point
+	var cmd, lastCmd byte
+	sc := &pathScanner{s: d}
+	finish := func(close bool) {
+		if len(cur) > 0 {
+			subpaths = append(subpaths, cur)
+			closed = append(closed, close)
+		}
+		cur = nil
+	}
+	nums := func(n int) ([]float64, error) {
+		out := make([]float64, n)
+		for i := range out {
+			f, ok := sc.number()
+			if !ok {
+				return nil, fmt.Errorf("image: invalid svg path data %q", d)
+			}
+			out[i] = f
+		}
+		return out, nil
+	}
+	for {
+		sc.skipSpace()
+		if sc.i >= len(sc.s) {
+			break
+		}
+		if c := sc.s[sc.i]; (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') {
+			cmd = c
+			sc.i++
+		} else if cmd == 0 {
+			return nil, nil, fmt.Errorf("image: invalid svg path data %q", d)
+		}
+		rel := cmd >= 'a'
+		abs := func(x, y float64) point {
+			if rel {
+				return point{pos.x + x, pos.y + y}
+			}
+			return point{x, y}
+		}
+		upper := cmd &^ 0x20
+		switch upper {
+		case 'Z':
+			finish(true)
+			pos = start
+		case 'M':
+			a, err := nums(2)
+			if err != nil {
+				return nil, nil, err
+			}
+			finish(false)
+			pos = abs(a[0], a[1])
+			start = pos
+			cur = []point{pos}
+			// further pairs are lines
+			cmd = 'L' | (cmd & 0x20)
+		case 'L', 'H', 'V':
+			var p point
+			switch upper {
+			case 'L':
+				a, err := nums(2)
+				if err != nil {
+					return nil, nil, err
+				}
+				p = abs(a[0], a[1])
+			case 'H':
+				a, err := nums(1)
+				if err != nil {
+					return nil, nil, err
+				}
+				p = point{a[0], pos.y}
+				if rel {
+					p.x += pos.x
+				}
+			case 'V':
+				a, err := nums(1)
+				if err != nil {
+					return nil, nil, err
+				}
+				p = point{pos.x, a[0]}
+				if rel {
+					p.y += pos.y
+				}
+			}
+			cur = lineTo(cur, pos, p)
+			pos = p
+		case 'C', 'S':
+			var c1 point
+			if upper == 'C' {
+				a, err := nums(2)
+				if err != nil {
+					return nil, nil, err
+				}
+				c1 = abs(a[0], a[1])
+			} else {
+				c1 = pos
+				if lu := lastCmd &^ 0x20; lu == 'C' || lu == 'S' {
+					c1 = point{2*pos.x - lastCtrl.x, 2*pos.y - lastCtrl.y}
+				}
+			}
+			a, err := nums(4)
+			if err != nil {
+				return nil, nil, err
+			}
+			c2, p := abs(a[0], a[1]), abs(a[2], a[3])
+			cur = lineTo(cur, pos, pos)
+			for i := 1; i <= curveSegments; i++ {
+				t := float64(i) / curveSegments
+				u := 1 - t
+				cur = append(cur, point{
+					u*u*u*pos.x + 3*u*u*t*c1.x + 3*u*t*t*c2.x + t*t*t*p.x,
+					u*u*u*pos.y + 3*u*u*t*c1.y + 3*u*t*t*c2.y + t*t*t*p.y,
+				})
+			}
+			lastCtrl, pos = c2, p
+		case 'Q', 'T':
+			var c point
+			if upper == 'Q' {
+				a, err := nums(2)
+				if err != nil {
+					return nil, nil, err
+				}
+				c = abs(a[0], a[1])
+			} else {
+				c = pos
+				if lu := lastCmd &^ 0x20; lu == 'Q' || lu == 'T' {
+					c = point{2*pos.x - lastCtrl.x, 2*pos.y - lastCtrl.y}
+				}
+			}
+			a, err := nums(2)
+			if err != nil {
+				return nil, nil, err
+			}
+			p := abs(a[0], a[1])
+			cur = lineTo(cur, pos, pos)
+			for i := 1; i <= curveSegments; i++ {
+				t := float64(i) / curveSegments
+				u := 1 - t
+				cur = append(cur, point{u*u*pos.x + 2*u*t*c.x + t*t*p.x, u*u*pos.y + 2*u*t*c.y + t*t*p.y})
+			}
+			lastCtrl, pos = c, p
+		case 'A':
+			a, err := nums(3)
+			if err != nil {
+				return nil, nil, err
+			}
+			large, ok1 := sc.flag()
+			sweep, ok2 := sc.flag()
+			b, err := nums(2)
+			if err != nil || !ok1 || !ok2 {
+				return nil, nil, fmt.Errorf("image: invalid svg path data %q", d)
+			}
+			p := abs(b[0], b[1])
+			cur = lineTo(cur, pos, pos)
+			cur = append(cur, arcPoints(pos, p, a[0], a[1], a[2], large, sweep)...)
+			pos = p
+		default:
+			return nil, nil, fmt.Errorf("image: unsupported svg path command %c", cmd)
+		}
+		lastCmd = cmd
+	}
+	finish(false)
+	return subpaths, closed, nil
+}
+
+// lineTo appends p to the polyline, starting it at pos if it's empty.
+func lineTo(cur []point, pos, p point) []point {
+	if len(cur) == 0 {
+		cur = append(cur, pos)
+	}
+	if cur[len(cur)-1] != p {
+		cur = append(cur, p)
+	}
+	return cur
+}
+
+// arcPoints flattens an SVG elliptical arc from p0 to p1, excluding p0,
+// using the endpoint to center conversion of the SVG specification.
+func arcPoints(p0, p1 point, rx, ry, rotation float64, large, sweep bool) []point {
+	rx, ry = math.Abs(rx), math.Abs(ry)
+	if rx == 0 || ry == 0 || p0 == p1 {
+		return []point{p1}
+	}
+	phi := rotation * math.Pi / 180
+	cos, sin := math.Cos(phi), math.Sin(phi)
+	dx, dy := (p0.x-p1.x)/2, (p0.y-p1.y)/2
+	x1, y1 := cos*dx+sin*dy, -sin*dx+cos*dy
+	if l := x1*x1/(rx*rx) + y1*y1/(ry*ry); l > 1 {
+		rx, ry = rx*math.Sqrt(l), ry*math.Sqrt(l)
+	}
+	num := rx*rx*ry*ry - rx*rx*y1*y1 - ry*ry*x1*x1
+	den := rx*rx*y1*y1 + ry*ry*x1*x1
+	k := math.Sqrt(math.Max(0, num/den))
+	if large == sweep {
+		k = -k
+	}
+	cx1, cy1 := k*rx*y1/ry, -k*ry*x1/rx
+	cx := cos*cx1 - sin*cy1 + (p0.x+p1.x)/2
+	cy := sin*cx1 + cos*cy1 + (p0.y+p1.y)/2
+	theta := math.Atan2((y1-cy1)/ry, (x1-cx1)/rx)
+	end := math.Atan2((-y1-cy1)/ry, (-x1-cx1)/rx)
+	delta := end - theta
+	if sweep && delta < 0 {
+		delta += 2 * math.Pi
+	} else if !sweep && delta > 0 {
+		delta -= 2 * math.Pi
+	}
+	n := int(math.Ceil(math.Abs(delta) / (math.Pi / 16)))
+	if n < 4 {
+		n = 4
+	}
+	pts := make([]point, 0, n)
+	for i := 1; i <= n; i++ {
+		a := theta + delta*float64(i)/float64(n)
+		x, y := rx*math.Cos(a), ry*math.Sin(a)
+		pts = append(pts, point{cos*x - sin*y + cx, sin*x + cos*y + cy})
+	}
+	pts[n-1] = p1
+	return pts
+}
+
+func ellipsePoints(c point, rx, ry float64) []point {
+	n := 48
+	pts := make([]point, n)
+	for i := range pts {
+		a := 2 * math.Pi * float64(i) / float64(n)
+		pts[i] = point{c.x + rx*math.Cos(a), c.y + ry*math.Sin(a)}
+	}
+	return pts
+}
@@ -0,0 +1,219 @@
+package chess
+
+import (
+	"fmt"
+	"strings"
+)
+
+// LenientNotation decodes moves the way people type them, with
+// Position's DecodeLenient, and encodes them in SAN.  It's registered as
+// "lenient" after the other built in notations, so MoveStr falls back to
+// it.
+var LenientNotation Notation = lenientNotation{}
+
+type lenientNotation struct{}
+
+func (lenientNotation) Encode(pos *Position, m Move) string {
+	return pos.EncodeSAN(m)
+}
+
+func (lenientNotation) Decode(pos *Position, s string) (Move, error) {
+	return pos.DecodeLenient(s)
+}
+
+// An AmbiguousMoveError is returned when move text could be more than one
+// valid move.
+type AmbiguousMoveError struct {
+	// Text is the move text that was decoded.
+	Text string
+	// Candidates are the valid moves the text could be.
+	Candidates []Move
+}
+
+func (e *AmbiguousMoveError) Error() string {
+	moves := make([]string, len(e.Candidates))
+	for i, m := range e.Candidates {
+		moves[i] = m.String()
+	}
+	return fmt.Sprintf(`chess: ambiguous move text "%s" could be %s`, e.Text, strings.Join(moves, ", "))
+}
+
+// DecodeLenient decodes move text typed by a person, forgiving the
+// mistakes SAN doesn't.  Dashes between squares (e2-e4, Ng1-f3), zeros in
+// castling (0-0), lower case pieces (qxf7), promotions without an = (e8Q)
+// and annotations (Nbd2?, e4!!) are accepted, as are pawn captures without
+// the rank (exd) when only one pawn capture fits.  The text is first
+// decoded as SAN, and then matched against the valid moves.  An
+// *AmbiguousMoveError with the candidate moves is returned if it matches
+// more than one of them.
+func (pos *Position) DecodeLenient(s string) (Move, error) {
+	text := normalizeLenient(s)
+	if m, err := parseSAN(text, pos); err == nil {
+		for _, v := range pos.ValidMoves() {
+			if v.Eq(m) {
+				return v, nil
+			}
+		}
+	}
+	var candidates []Move
+	readings := lenientReadings(text)
+	for _, v := range pos.ValidMoves() {
+		for _, r := range readings {
+			if r.matches(pos, v) {
+				candidates = append(candidates, v)
+				break
+			}
+		}
+	}
+	switch len(candidates) {
+	case 0:
+		return 0, fmt.Errorf(`chess: failed to decode move text "%s" for position %s`, s, pos)
+	case 1:
+		return candidates[0], nil
+	}
+	return 0, &AmbiguousMoveError{Text: s, Candidates: candidates}
+}
+
+// normalizeLenient strips the spaces, annotations, check marks and en
+// passant suffix from move text, along with the dashes, captures and =
+// that don't change the move, and writes castling as O-O or O-O-O.
+func normalizeLenient(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(" \t!?+#", r) {
+			return -1
+		}
+		return r
+	}, s)
+	s = strings.TrimSuffix(strings.TrimSuffix(s, "e.p."), "ep")
+	s = strings.Map(func(r rune) rune {
+		if strings.ContainsRune("-xX:=", r) {
+			return -1
+		}
+		return r
+	}, s)
+	switch strings.ToUpper(strings.ReplaceAll(s, "0", "O")) {
+	case "OO":
+		return "O-O"
+	case "OOO":
+		return "O-O-O"
+	}
+	return s
+}
+
+// lenientMove is one reading of normalized move text.  Unknown parts are
+// NoPieceType or -1.
+type lenientMove struct {
+	piece              PieceType
+	fromFile, fromRank int
+	toFile, toRank     int
+	promo              PieceType
+}
+
+// lenientReadings returns the ways the normalized move text can be read,
+// which are two when a lower case b may be a bishop or a file.
+func lenientReadings(s string) []lenientMove {
+	if s == "" {
+		return nil
+	}
+	var readings []lenientMove
+	switch s[0] {
+	case 'K', 'Q', 'R', 'B', 'N', 'k', 'q', 'r', 'n':
+		if r, ok := readLenient(sanPieceType(strings.ToUpper(s[:1])), s[1:]); ok {
+			readings = append(readings, r)
+		}
+	case 'b':
+		if r, ok := readLenient(Bishop, s[1:]); ok {
+			readings = append(readings, r)
+		}
+		if r, ok := readSquares(s); ok {
+			readings = append(readings, r)
+		}
+	case 'P', 'p':
+		if r, ok := readLenient(Pawn, s[1:]); ok {
+			readings = append(readings, r)
+		}
+	default:
+		if r, ok := readSquares(s); ok {
+			readings = append(readings, r)
+		}
+	}
+	return readings
+}
+
+// readSquares reads move text without a piece letter, which is a pawn
+// move unless it's from a full square, like g1f3 or b1c3, which may be by
+// any piece.
+func readSquares(s string) (lenientMove, bool) {
+	r, ok := readLenient(Pawn, s)
+	if ok && r.fromFile != -1 && r.fromRank != -1 {
+		r.piece = NoPieceType
+	}
+	return r, ok
+}
+
+// readLenient reads the squares and promotion of a move by the piece.
+func readLenient(piece PieceType, s string) (lenientMove, bool) {
+	r := lenientMove{piece: piece, fromFile: -1, fromRank: -1, toFile: -1, toRank: -1, promo: NoPieceType}
+	s = strings.ToLower(s)
+	if l := len(s); l > 2 && isRankChar(s[l-2]) {
+		if promo := sanPieceType(strings.ToUpper(s[l-1:])); promo != NoPieceType && promo != Pawn {
+			r.promo = promo
+			s = s[:l-1]
+		}
+	}
+	l := len(s)
+	switch {
+	case l >= 2 && isFileChar(s[l-2]) && isRankChar(s[l-1]):
+		r.toFile, r.toRank = int(s[l-2]-'a'), int(s[l-1]-'1')
+		s = s[:l-2]
+	case l >= 2 && piece == Pawn && isFileChar(s[l-1]):
+		// a pawn capture without the rank, like ed for exd5
+		r.toFile = int(s[l-1] - 'a')
+		s = s[:l-1]
+		if !isFileChar(s[0]) {
+			return r, false
+		}
+	default:
+		return r, false
+	}
+	for i := 0; i < len(s); i++ {
+		switch {
+		case isFileChar(s[i]) && r.fromFile == -1 && r.fromRank == -1:
+			r.fromFile = int(s[i] - 'a')
+		case isRankChar(s[i]) && r.fromRank == -1:
+			r.fromRank = int(s[i] - '1')
+		default:
+			return r, false
+		}
+	}
+	return r, true
+}
+
+func isFileChar(c byte) bool {
+	return c >= 'a' && c <= 'h'
+}
+
+func isRankChar(c byte) bool {
+	return c >= '1' && c <= '8'
+}
+
+// matches returns true if the valid move fits the reading.
+func (r lenientMove) matches(pos *Position, m Move) bool {
+	if m.HasTag(Drop) {
+		return false
+	}
+	if r.piece != NoPieceType && pos.board.Piece(m.S1()).Type() != r.piece {
+		return false
+	}
+	if r.promo != NoPieceType && m.Promo().PieceType() != r.promo {
+		return false
+	}
+	return matchesCoord(r.fromFile, int(m.S1().File())) &&
+		matchesCoord(r.fromRank, int(m.S1().Rank())) &&
+		matchesCoord(r.toFile, int(m.S2().File())) &&
+		matchesCoord(r.toRank, int(m.S2().Rank()))
+}
+
+func matchesCoord(want, got int) bool {
+	return want == -1 || want == got
+}
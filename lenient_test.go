@@ -0,0 +1,120 @@
+package chess
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDecodeLenient(t *testing.T) {
+	tests := []struct {
+		fen  string
+		text string
+		want string
+	}{
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "e2-e4", "e4"},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "Ng1-f3", "Nf3"},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "g1f3", "Nf3"},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "b1-c3", "Nc3"},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "b1c3", "Nc3"},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "b2-b4", "b4"},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "e4!!", "e4"},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "NF3", "Nf3"},
+		{"rnbqkbnr/ppp1pppp/8/3p4/4P3/8/PPPP1PPP/RNBQKBNR w KQkq d6 0 2", "exd", "exd5"},
+		{"rnbqkbnr/ppp1pppp/8/3p4/4P3/8/PPPP1PPP/RNBQKBNR w KQkq d6 0 2", "e:d5", "exd5"},
+		{"r1bqkb1r/pppp1ppp/2n2n2/4p2Q/2B1P3/8/PPPP1PPP/RNB1K1NR w KQkq - 4 4", "qxf7", "Qxf7#"},
+		{"r1bqkbnr/pppp1ppp/2n5/4p3/2B1P3/5N2/PPPP1PPP/RNBQK2R w KQkq - 4 4", "0-0", "O-O"},
+		{"r1bqkbnr/pppp1ppp/2n5/4p3/2B1P3/5N2/PPPP1PPP/RNBQK2R w KQkq - 4 4", "o-o", "O-O"},
+		{"r1bqkbnr/pppp1ppp/2n5/4p3/2B1P3/5N2/PPPP1PPP/RNBQK2R w KQkq - 4 4", "e1-g1", "O-O"},
+		{"8/4P3/8/8/8/2k5/8/1K6 w - - 0 1", "e8Q", "e8=Q"},
+		{"8/4P3/8/8/8/2k5/8/1K6 w - - 0 1", "e7-e8=n", "e8=N"},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "Nbd2?", ""},
+		{"rnbqkb1r/pppppppp/5n2/8/3P4/8/PPP1PPPP/RNBQKBNR w KQkq - 1 2", "Nbd2?", "Nd2"},
+		{"rnbqkbnr/ppp1pppp/8/3p4/8/2P5/PP1PPPPP/RNBQKBNR b KQkq - 0 2", "bc3", ""},
+	}
+	for _, test := range tests {
+		pos := unsafeFEN(test.fen)
+		m, err := pos.DecodeLenient(test.text)
+		if test.want == "" {
+			if err == nil {
+				t.Fatalf("expected %s to not decode in %s but got %s", test.text, test.fen, m)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if san := pos.EncodeSAN(m); san != test.want {
+			t.Fatalf("expected %s to decode as %s in %s but got %s", test.text, test.want, test.fen, san)
+		}
+	}
+}
+
+func TestDecodeLenientAmbiguous(t *testing.T) {
+	tests := []struct {
+		fen        string
+		text       string
+		candidates []string
+	}{
+		// both the c and e pawns can take on d5
+		{"rnbqkbnr/ppp1pppp/8/3p4/2P1P3/8/PP1P1PPP/RNBQKBNR w KQkq - 0 3", "xd5", []string{"cxd5", "exd5"}},
+		{"rnbqkbnr/pppp1ppp/8/4p3/3P4/8/PPP1PPPP/RNBQKBNR b KQkq - 0 2", "ed", []string{"exd4"}},
+		{"8/4P3/8/8/8/2k5/8/1K6 w - - 0 1", "e8", []string{"e8=Q", "e8=R", "e8=B", "e8=N"}},
+		// a lower case b may be the bishop or the b pawn
+		{"rnbqkbnr/pp1ppppp/8/2p5/8/1P6/P1PPPPPP/RNBQKBNR w KQkq - 0 2", "bb2", []string{"Bb2"}},
+		{"rn1qkbnr/pppbpppp/8/3p4/2P5/8/PP1PPPPP/RNBQKBNR b KQkq - 0 2", "bc6", []string{"Bc6"}},
+		{"r1bqkbnr/pppppppp/8/8/8/n7/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "ba3", []string{"bxa3"}},
+		{"rnbqk1nr/pppppppp/8/4B3/8/2b5/PPPPPPPP/RN1QKBNR w KQkq - 0 1", "bc3", []string{"bxc3", "Bxc3"}},
+	}
+	for _, test := range tests {
+		pos := unsafeFEN(test.fen)
+		m, err := pos.DecodeLenient(test.text)
+		if len(test.candidates) == 1 {
+			if err != nil {
+				t.Fatal(err)
+			}
+			if san := pos.EncodeSAN(m); san != test.candidates[0] {
+				t.Fatalf("expected %s to decode as %s in %s but got %s", test.text, test.candidates[0], test.fen, san)
+			}
+			continue
+		}
+		var ambiguous *AmbiguousMoveError
+		if !errors.As(err, &ambiguous) {
+			t.Fatalf("expected %s to be ambiguous in %s but got %v", test.text, test.fen, err)
+		}
+		if len(ambiguous.Candidates) != len(test.candidates) {
+			t.Fatalf("expected %s to be %v but got %v", test.text, test.candidates, ambiguous.Candidates)
+		}
+		for _, want := range test.candidates {
+			found := false
+			for _, c := range ambiguous.Candidates {
+				found = found || pos.EncodeSAN(c) == want
+			}
+			if !found {
+				t.Fatalf("expected %s to have candidate %s but got %v", test.text, want, ambiguous.Candidates)
+			}
+		}
+	}
+}
+
+func TestMoveStrLenient(t *testing.T) {
+	g := NewGame()
+	for _, s := range []string{"e2-e4", "d7-d5", "exd", "Ng8-f6", "Ng1-f3", "b8-c6", "b1-c3"} {
+		if err := g.MoveStr(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	g = NewGame()
+	g.Notation = LenientNotation
+	for _, s := range []string{"e4", "d5", "c4", "c6"} {
+		if err := g.MoveStr(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var ambiguous *AmbiguousMoveError
+	if err := g.MoveStr("xd5"); !errors.As(err, &ambiguous) || len(ambiguous.Candidates) != 2 {
+		t.Fatalf("expected xd5 to be ambiguous but got %v", err)
+	}
+	if err := g.MoveStr("Kd5"); err == nil || errors.As(err, &ambiguous) {
+		t.Fatalf("expected Kd5 to be invalid but got %v", err)
+	}
+}
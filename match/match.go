@@ -0,0 +1,353 @@
+// Package match plays matches between chess engines and bots.
+package match
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/barakmich/chess"
+	"github.com/barakmich/chess/opening"
+	"github.com/barakmich/chess/uci"
+)
+
+// Clock is the time each player has left and the increment each gets after
+// a move.  The zero Clock is untimed.
+type Clock struct {
+	White          time.Duration
+	Black          time.Duration
+	WhiteIncrement time.Duration
+	BlackIncrement time.Duration
+}
+
+// Timed returns true if the clock is running.
+func (c Clock) Timed() bool {
+	return c.White > 0 || c.Black > 0
+}
+
+func (c *Clock) remaining(color chess.Color) *time.Duration {
+	if color == chess.White {
+		return &c.White
+	}
+	return &c.Black
+}
+
+func (c *Clock) increment(color chess.Color) time.Duration {
+	if color == chess.White {
+		return c.WhiteIncrement
+	}
+	return c.BlackIncrement
+}
+
+// Opening is a starting point for the games of a match: the moves played
+// from the position.  A nil Position is the standard starting position.
+type Opening struct {
+	Name     string
+	Position *chess.Position
+	Moves    []chess.Move
+}
+
+// OpeningsFromEPD reads one opening per line from FEN or EPD records.  An
+// EPD id operation, such as id "Sicilian";, names the opening.  Blank lines
+// and lines starting with # are skipped.
+func OpeningsFromEPD(r io.Reader) ([]Opening, error) {
+	var openings []Opening
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 4 {
+			return nil, fmt.Errorf("match: invalid epd on line %d: %q", n, line)
+		}
+		fen, ops := strings.Join(fields[:4], " "), fields[4:]
+		if len(ops) >= 2 && isNumber(ops[0]) && isNumber(ops[1]) {
+			fen += " " + ops[0] + " " + ops[1]
+			ops = ops[2:]
+		} else {
+			fen += " 0 1"
+		}
+		pos := &chess.Position{}
+		if err := pos.UnmarshalText([]byte(fen)); err != nil {
+			return nil, fmt.Errorf("match: invalid epd on line %d: %w", n, err)
+		}
+		openings = append(openings, Opening{Name: epdID(strings.Join(ops, " ")), Position: pos})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return openings, nil
+}
+
+func isNumber(s string) bool {
+	_, err := strconv.Atoi(s)
+	return err == nil
+}
+
+// epdID returns the value of the id operation in the EPD operations.
+func epdID(ops string) string {
+	for _, op := range strings.Split(ops, ";") {
+		op = strings.TrimSpace(op)
+		if strings.HasPrefix(op, "id ") {
+			return strings.Trim(strings.TrimSpace(op[3:]), `"`)
+		}
+	}
+	return ""
+}
+
+// OpeningsFromBook returns every line of the book as an opening.
+func OpeningsFromBook(b opening.Book) []Opening {
+	var openings []Opening
+	for _, o := range b.Possible(nil) {
+		g := o.Game()
+		if g == nil {
+			continue
+		}
+		openings = append(openings, Opening{Name: o.Code() + " " + o.Title(), Moves: g.Moves()})
+	}
+	return openings
+}
+
+// Options configures a match.  Scores used for adjudication are in
+// centipawns from the moving player's point of view, with forced mates
+// counted as uci.EvalCap, and only players that report scores, such as
+// EnginePlayer, can be adjudicated by score.
+type Options struct {
+	// Openings are played in order, each twice with colors reversed.  No
+	// openings means games start from the standard position.
+	Openings []Opening
+	// Rounds is the number of times the openings are played.  Zero means once.
+	Rounds int
+	// Time and Increment are each player's time control.  Zero Time means
+	// the games are untimed.
+	Time      time.Duration
+	Increment time.Duration
+	// MaxMoves adjudicates a game as a draw after that many moves.
+	MaxMoves int
+	// A game is adjudicated as lost by a player once its score has been at
+	// or below -ResignScore for ResignMoves of its moves in a row.  Zero
+	// ResignMoves disables this.
+	ResignScore int
+	ResignMoves int
+	// A game is adjudicated as a draw once both players' scores have been
+	// within +/-DrawScore for DrawMoves of their moves in a row, starting
+	// from move DrawMoveNumber.  Zero DrawMoves disables this.
+	DrawScore      int
+	DrawMoves      int
+	DrawMoveNumber int
+	// Event is used for the Event tag of the games.
+	Event string
+	// PGN, if set, receives every game as it finishes.
+	PGN io.Writer
+	// AfterGame, if set, is called with every finished game and the match
+	// stats so far.  The match stops if it returns false.
+	AfterGame func(g *chess.Game, stats Stats) bool
+}
+
+// timeGrace is how long past its remaining time a player is allowed before
+// its move is canceled.
+const timeGrace = time.Second
+
+// engineCleanupTimeout bounds how long an engine may take to finish a search
+// that was canceled.
+const engineCleanupTimeout = 10 * time.Second
+
+// Run plays a match between the players and returns the stats from first's
+// point of view.  The games are played one at a time.  If ctx is done or a
+// player fails to start a game, Run returns the stats so far with the error.
+func Run(ctx context.Context, first, second Player, opts Options) (Stats, error) {
+	stats := Stats{}
+	openings := opts.Openings
+	if len(openings) == 0 {
+		openings = []Opening{{}}
+	}
+	rounds := opts.Rounds
+	if rounds < 1 {
+		rounds = 1
+	}
+	round := 0
+	for r := 0; r < rounds; r++ {
+		for _, op := range openings {
+			for _, reversed := range []bool{false, true} {
+				round++
+				white, black := first, second
+				if reversed {
+					white, black = second, first
+				}
+				g, err := playGame(ctx, white, black, op, opts, round)
+				if err != nil {
+					return stats, err
+				}
+				switch {
+				case g.Outcome() == chess.Draw:
+					stats.Draws++
+				case (g.Outcome() == chess.WhiteWon) != reversed:
+					stats.Wins++
+				default:
+					stats.Losses++
+				}
+				if opts.PGN != nil {
+					if _, err := fmt.Fprintf(opts.PGN, "%s\n\n", g); err != nil {
+						return stats, err
+					}
+				}
+				if opts.AfterGame != nil && !opts.AfterGame(g, stats) {
+					return stats, nil
+				}
+			}
+		}
+	}
+	return stats, nil
+}
+
+func playGame(ctx context.Context, white, black Player, op Opening, opts Options, round int) (*chess.Game, error) {
+	g := chess.NewGame()
+	if op.Position != nil {
+		g, _ = chess.NewGameFromPosition(op.Position)
+		g.AddTagPair("FEN", op.Position.String())
+		g.AddTagPair("SetUp", "1")
+	}
+	for _, m := range op.Moves {
+		if err := g.Move(m); err != nil {
+			return nil, fmt.Errorf("match: opening %q: %w", op.Name, err)
+		}
+	}
+	event := opts.Event
+	if event == "" {
+		event = "?"
+	}
+	g.AddTagPair("Event", event)
+	g.AddTagPair("Site", "?")
+	g.AddTagPair("Date", time.Now().Format("2006.01.02"))
+	g.AddTagPair("Round", strconv.Itoa(round))
+	g.AddTagPair("White", white.Name())
+	g.AddTagPair("Black", black.Name())
+	if op.Name != "" {
+		g.AddTagPair("Opening", op.Name)
+	}
+	timeControl := "-"
+	if opts.Time > 0 {
+		timeControl = fmt.Sprintf("%g+%g", opts.Time.Seconds(), opts.Increment.Seconds())
+	}
+	g.AddTagPair("TimeControl", timeControl)
+
+	for _, p := range []Player{white, black} {
+		if err := p.NewGame(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	clock := Clock{}
+	if opts.Time > 0 {
+		clock = Clock{
+			White:          opts.Time,
+			Black:          opts.Time,
+			WhiteIncrement: opts.Increment,
+			BlackIncrement: opts.Increment,
+		}
+	}
+	adj := adjudicator{opts: opts}
+	for g.Outcome() == chess.NoOutcome {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		turn := g.Position().Turn()
+		p := white
+		if turn == chess.Black {
+			p = black
+		}
+		moveCtx, cancel := ctx, context.CancelFunc(func() {})
+		if clock.Timed() {
+			moveCtx, cancel = context.WithTimeout(ctx, *clock.remaining(turn)+timeGrace)
+		}
+		start := time.Now()
+		choice, err := p.Move(moveCtx, g, clock)
+		elapsed := time.Since(start)
+		cancel()
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if clock.Timed() {
+			*clock.remaining(turn) -= elapsed
+			if *clock.remaining(turn) < 0 {
+				g.Forfeit(turn, chess.Timeout)
+				break
+			}
+			*clock.remaining(turn) += clock.increment(turn)
+		}
+		if err == nil {
+			err = g.Move(choice.Move)
+		}
+		if err != nil {
+			g.Forfeit(turn, chess.RulesInfraction)
+			break
+		}
+		adj.adjudicate(g, turn, choice)
+	}
+	g.AddTagPair("Result", g.Outcome().String())
+	if g.GetTagPair("Termination") == nil {
+		g.AddTagPair("Termination", g.Method().Termination())
+	}
+	return g, nil
+}
+
+// adjudicator ends games by claiming draws and by the score thresholds of
+// the match options.
+type adjudicator struct {
+	opts        Options
+	resignCount [2]int
+	drawCount   [2]int
+}
+
+// adjudicate is called after the player of the given color made the move of
+// the choice.
+func (a *adjudicator) adjudicate(g *chess.Game, color chess.Color, choice Choice) {
+	if g.Outcome() != chess.NoOutcome {
+		return
+	}
+	for _, method := range g.EligibleDraws() {
+		if method == chess.ThreefoldRepetition || method == chess.FiftyMoveRule {
+			g.Draw(method)
+			return
+		}
+	}
+	plies := len(g.Moves())
+	if a.opts.MaxMoves > 0 && plies >= 2*a.opts.MaxMoves {
+		g.Adjudicate(chess.Draw)
+		return
+	}
+	i := 0
+	if color == chess.Black {
+		i = 1
+	}
+	if !choice.HasScore {
+		a.resignCount[i], a.drawCount[i] = 0, 0
+		return
+	}
+	cp := uci.Eval{CP: choice.Score.CP, Mate: choice.Score.Mate}.Centipawns()
+	a.resignCount[i]++
+	if cp > -a.opts.ResignScore {
+		a.resignCount[i] = 0
+	}
+	if a.opts.ResignMoves > 0 && a.resignCount[i] >= a.opts.ResignMoves {
+		outcome := chess.BlackWon
+		if color == chess.Black {
+			outcome = chess.WhiteWon
+		}
+		g.Adjudicate(outcome)
+		return
+	}
+	a.drawCount[i]++
+	if cp > a.opts.DrawScore || cp < -a.opts.DrawScore || plies < 2*(a.opts.DrawMoveNumber-1) {
+		a.drawCount[i] = 0
+	}
+	if a.opts.DrawMoves > 0 && a.drawCount[0] >= a.opts.DrawMoves && a.drawCount[1] >= a.opts.DrawMoves {
+		g.Adjudicate(chess.Draw)
+	}
+}
@@ -0,0 +1,132 @@
+package match_test
+
+import (
+	"bytes"
+	"context"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/barakmich/chess"
+	"github.com/barakmich/chess/match"
+)
+
+func firstMove(ctx context.Context, g *chess.Game, clock match.Clock) (chess.Move, error) {
+	return g.ValidMoves()[0], nil
+}
+
+func randomPlayer(name string, seed int64) match.Player {
+	r := rand.New(rand.NewSource(seed))
+	return match.Func(name, func(ctx context.Context, g *chess.Game, clock match.Clock) (chess.Move, error) {
+		moves := g.ValidMoves()
+		return moves[r.Intn(len(moves))], nil
+	})
+}
+
+const testEPD = `# two openings
+rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq - id "Open Game";
+rnbqkbnr/pp1ppppp/8/2p5/4P3/8/PPPP1PPP/RNBQKBNR w KQkq c6 0 2
+`
+
+func TestRun(t *testing.T) {
+	openings, err := match.OpeningsFromEPD(strings.NewReader(testEPD))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(openings) != 2 || openings[0].Name != "Open Game" {
+		t.Fatalf("unexpected openings %+v", openings)
+	}
+	pgn := &bytes.Buffer{}
+	games := []*chess.Game{}
+	stats, err := match.Run(context.Background(), randomPlayer("a", 1), randomPlayer("b", 2), match.Options{
+		Openings: openings,
+		MaxMoves: 60,
+		PGN:      pgn,
+		AfterGame: func(g *chess.Game, s match.Stats) bool {
+			games = append(games, g)
+			return true
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Games() != 4 || len(games) != 4 {
+		t.Fatalf("expected 4 games but got %d", stats.Games())
+	}
+	for i, g := range games {
+		if g.Outcome() == chess.NoOutcome {
+			t.Fatalf("game %d has no outcome", i)
+		}
+		white := g.GetTagPair("White").Value
+		if expected := []string{"a", "b"}[i%2]; white != expected {
+			t.Fatalf("game %d: expected %s to have White but got %s", i, expected, white)
+		}
+		if len(g.Moves()) > 120 {
+			t.Fatalf("game %d: expected adjudication after 60 moves but got %d plies", i, len(g.Moves()))
+		}
+	}
+	scanner := chess.NewScanner(pgn)
+	n := 0
+	for scanner.Scan() {
+		g := scanner.Next()
+		if g.Position().String() != games[n].Position().String() {
+			t.Fatalf("game %d: pgn doesn't match the game played", n)
+		}
+		n++
+	}
+	if n != 4 {
+		t.Fatalf("expected 4 games in the pgn but got %d", n)
+	}
+}
+
+func TestRunBookOpenings(t *testing.T) {
+	stats, err := match.Run(context.Background(), match.Func("first", firstMove), randomPlayer("random", 3), match.Options{
+		Openings: []match.Opening{{Name: "Italian", Moves: mustMoves(t, "e4", "e5", "Nf3", "Nc6", "Bc4")}},
+		MaxMoves: 20,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Games() != 2 {
+		t.Fatalf("expected 2 games but got %d", stats.Games())
+	}
+}
+
+func TestTimeForfeit(t *testing.T) {
+	slow := match.Func("slow", func(ctx context.Context, g *chess.Game, clock match.Clock) (chess.Move, error) {
+		select {
+		case <-time.After(200 * time.Millisecond):
+		case <-ctx.Done():
+		}
+		return g.ValidMoves()[0], nil
+	})
+	var games []*chess.Game
+	stats, err := match.Run(context.Background(), match.Func("fast", firstMove), slow, match.Options{
+		Time: 100 * time.Millisecond,
+		AfterGame: func(g *chess.Game, s match.Stats) bool {
+			games = append(games, g)
+			return true
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Wins != 2 {
+		t.Fatalf("expected the slow player to lose both games on time but got %s", stats)
+	}
+	if tc := games[0].GetTagPair("Termination").Value; tc != "time forfeit" {
+		t.Fatalf("expected time forfeit but got %s", tc)
+	}
+}
+
+func mustMoves(t *testing.T, sans ...string) []chess.Move {
+	t.Helper()
+	g := chess.NewGame()
+	for _, s := range sans {
+		if err := g.MoveStr(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return g.Moves()
+}
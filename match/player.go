@@ -0,0 +1,109 @@
+package match
+
+import (
+	"context"
+	"errors"
+
+	"github.com/barakmich/chess"
+	"github.com/barakmich/chess/uci"
+)
+
+// Choice is a move chosen by a Player along with its evaluation, if it has one.
+type Choice struct {
+	Move chess.Move
+	// Score is the player's evaluation from its own point of view, as in UCI.
+	// It is only used for adjudication when HasScore is true.
+	Score    uci.Score
+	HasScore bool
+}
+
+// Player chooses moves for one side of a game.
+type Player interface {
+	// Name is used for the White and Black tags of the games.
+	Name() string
+	// NewGame is called before each game the player plays.
+	NewGame(ctx context.Context) error
+	// Move returns the move to play in the game's current position.  The
+	// clock holds the time both players have left, and is zero in untimed
+	// matches.
+	Move(ctx context.Context, g *chess.Game, clock Clock) (Choice, error)
+}
+
+// Func returns a Player that chooses moves by calling choose.
+func Func(name string, choose func(ctx context.Context, g *chess.Game, clock Clock) (chess.Move, error)) Player {
+	return funcPlayer{name: name, choose: choose}
+}
+
+type funcPlayer struct {
+	name   string
+	choose func(ctx context.Context, g *chess.Game, clock Clock) (chess.Move, error)
+}
+
+func (p funcPlayer) Name() string {
+	return p.name
+}
+
+func (p funcPlayer) NewGame(ctx context.Context) error {
+	return nil
+}
+
+func (p funcPlayer) Move(ctx context.Context, g *chess.Game, clock Clock) (Choice, error) {
+	m, err := p.choose(ctx, g, clock)
+	return Choice{Move: m}, err
+}
+
+// EnginePlayer is a Player backed by a UCI engine.  The engine must have
+// been initialized with CmdUCI and any options before the match.
+type EnginePlayer struct {
+	// Engine is the engine choosing the moves.
+	Engine *uci.Engine
+	// Go is the search to run for each move.  In timed matches the clock
+	// fields of Go are set from the match clock.
+	Go uci.CmdGo
+
+	name string
+}
+
+// NewEnginePlayer returns an EnginePlayer for the engine.  If name is empty
+// the engine's id name is used.
+func NewEnginePlayer(name string, e *uci.Engine, goCmd uci.CmdGo) *EnginePlayer {
+	if name == "" {
+		name = e.ID()["name"]
+	}
+	return &EnginePlayer{Engine: e, Go: goCmd, name: name}
+}
+
+// Name implements the Player interface.
+func (p *EnginePlayer) Name() string {
+	return p.name
+}
+
+// NewGame implements the Player interface.
+func (p *EnginePlayer) NewGame(ctx context.Context) error {
+	return p.Engine.RunContext(ctx, uci.CmdUCINewGame, uci.CmdIsReady)
+}
+
+// Move implements the Player interface.
+func (p *EnginePlayer) Move(ctx context.Context, g *chess.Game, clock Clock) (Choice, error) {
+	cmd := p.Go
+	if clock.Timed() {
+		cmd.WhiteTime = clock.White
+		cmd.BlackTime = clock.Black
+		cmd.WhiteIncrement = clock.WhiteIncrement
+		cmd.BlackIncrement = clock.BlackIncrement
+	}
+	setPos := uci.CmdPosition{Position: g.Positions()[0], Moves: g.Moves()}
+	if err := p.Engine.RunContext(ctx, setPos, cmd); err != nil {
+		if ctx.Err() != nil {
+			// don't let the abandoned search's output leak into the next one
+			p.Engine.Run(uci.CmdStop)
+			p.Engine.Run(uci.WithTimeout(uci.CmdIsReady, engineCleanupTimeout))
+		}
+		return Choice{}, err
+	}
+	results := p.Engine.SearchResults()
+	if results.BestMove == 0 {
+		return Choice{}, errors.New("match: engine returned no move")
+	}
+	return Choice{Move: results.BestMove, Score: results.Info.Score, HasScore: true}, nil
+}
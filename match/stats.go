@@ -0,0 +1,134 @@
+package match
+
+import (
+	"fmt"
+	"math"
+)
+
+// Stats are the results of a match from the first player's point of view.
+type Stats struct {
+	Wins   int
+	Draws  int
+	Losses int
+}
+
+// Games returns the number of games played.
+func (s Stats) Games() int {
+	return s.Wins + s.Draws + s.Losses
+}
+
+// Score returns the first player's score as a fraction of the points
+// available, counting a draw as half a point.
+func (s Stats) Score() float64 {
+	if s.Games() == 0 {
+		return 0.5
+	}
+	return (float64(s.Wins) + float64(s.Draws)/2) / float64(s.Games())
+}
+
+// String implements the fmt.Stringer interface.
+func (s Stats) String() string {
+	diff, margin := s.Elo()
+	if math.IsInf(margin, 1) {
+		return fmt.Sprintf("+%d =%d -%d, Elo %+.1f +/- no estimate", s.Wins, s.Draws, s.Losses, diff)
+	}
+	return fmt.Sprintf("+%d =%d -%d, Elo %+.1f +/- %.1f", s.Wins, s.Draws, s.Losses, diff, margin)
+}
+
+// Elo returns the Elo difference between the first and second player and
+// its 95% confidence margin.  A score of 0% or 100% gives an infinite
+// difference, and no games or a score of 0% or 100% an infinite margin
+// since there's no estimate of it.  When the confidence interval of the
+// score reaches 0% or 100% the margin is estimated from the slope of the
+// Elo difference at the score instead.
+func (s Stats) Elo() (diff, margin float64) {
+	n := float64(s.Games())
+	if n == 0 {
+		return 0, math.Inf(1)
+	}
+	p := s.Score()
+	if p == 0 || p == 1 {
+		return eloDiff(p), math.Inf(1)
+	}
+	stdErr := math.Sqrt(s.variance() / n)
+	lowerP, upperP := p-1.959964*stdErr, p+1.959964*stdErr
+	if lowerP <= 0 || upperP >= 1 {
+		// d/dp of -400*log10(1/p-1) is 400/(ln(10)*p*(1-p))
+		return eloDiff(p), 1.959964 * stdErr * 400 / (math.Ln10 * p * (1 - p))
+	}
+	return eloDiff(p), (eloDiff(upperP) - eloDiff(lowerP)) / 2
+}
+
+// variance returns the variance of a single game's score.
+func (s Stats) variance() float64 {
+	n := float64(s.Games())
+	p := s.Score()
+	return (float64(s.Wins)*(1-p)*(1-p) + float64(s.Draws)*(0.5-p)*(0.5-p) + float64(s.Losses)*p*p) / n
+}
+
+func eloDiff(score float64) float64 {
+	return -400 * math.Log10(1/score-1)
+}
+
+func expectedScore(elo float64) float64 {
+	return 1 / (1 + math.Pow(10, -elo/400))
+}
+
+// SPRTDecision is the state of a sequential probability ratio test.
+type SPRTDecision int
+
+const (
+	// SPRTContinue means more games are needed to decide.
+	SPRTContinue SPRTDecision = iota
+	// SPRTAcceptH0 means the Elo difference is at most Elo0.
+	SPRTAcceptH0
+	// SPRTAcceptH1 means the Elo difference is at least Elo1.
+	SPRTAcceptH1
+)
+
+// String implements the fmt.Stringer interface.
+func (d SPRTDecision) String() string {
+	switch d {
+	case SPRTAcceptH0:
+		return "H0 accepted"
+	case SPRTAcceptH1:
+		return "H1 accepted"
+	}
+	return "continue"
+}
+
+// SPRT is the result of a sequential probability ratio test.
+type SPRT struct {
+	// LLR is the log-likelihood ratio of H1 (the Elo difference is Elo1)
+	// against H0 (the Elo difference is Elo0).
+	LLR float64
+	// Lower and Upper are the bounds at which H0 and H1 are accepted.
+	Lower    float64
+	Upper    float64
+	Decision SPRTDecision
+}
+
+// SPRT runs a sequential probability ratio test of H0, that the first player
+// is elo0 stronger, against H1, that it is elo1 stronger, with false positive
+// rate alpha and false negative rate beta.  The log-likelihood ratio uses a
+// normal approximation of the game scores.
+func (s Stats) SPRT(elo0, elo1, alpha, beta float64) SPRT {
+	r := SPRT{
+		Lower: math.Log(beta / (1 - alpha)),
+		Upper: math.Log((1 - beta) / alpha),
+	}
+	n := float64(s.Games())
+	v := s.variance()
+	if n == 0 || v == 0 {
+		return r
+	}
+	s0, s1 := expectedScore(elo0), expectedScore(elo1)
+	r.LLR = n * (s1 - s0) * (2*s.Score() - s0 - s1) / (2 * v)
+	switch {
+	case r.LLR >= r.Upper:
+		r.Decision = SPRTAcceptH1
+	case r.LLR <= r.Lower:
+		r.Decision = SPRTAcceptH0
+	}
+	return r
+}
@@ -0,0 +1,73 @@
+package match_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/barakmich/chess/match"
+)
+
+func TestElo(t *testing.T) {
+	tests := []struct {
+		Stats  match.Stats
+		Diff   float64
+		Margin float64
+	}{
+		{match.Stats{Wins: 50, Draws: 0, Losses: 50}, 0, 69.0},
+		{match.Stats{Wins: 60, Draws: 20, Losses: 20}, 147.2, 66.0},
+		{match.Stats{Wins: 20, Draws: 60, Losses: 20}, 0, 43.3},
+	}
+	for _, test := range tests {
+		diff, margin := test.Stats.Elo()
+		if math.Abs(diff-test.Diff) > 0.1 || math.Abs(margin-test.Margin) > 0.1 {
+			t.Fatalf("%s: expected %.1f +/- %.1f but got %.1f +/- %.1f", test.Stats, test.Diff, test.Margin, diff, margin)
+		}
+	}
+}
+
+func TestEloLopsided(t *testing.T) {
+	tests := []struct {
+		Stats  match.Stats
+		Diff   float64
+		Margin float64
+		String string
+	}{
+		{match.Stats{Wins: 9, Draws: 1, Losses: 0}, 511.5, 340.0, "+9 =1 -0, Elo +511.5 +/- 340.0"},
+		{match.Stats{Wins: 3, Draws: 0, Losses: 1}, 190.8, 393.2, "+3 =0 -1, Elo +190.8 +/- 393.2"},
+		{match.Stats{Wins: 0, Draws: 1, Losses: 9}, -511.5, 340.0, "+0 =1 -9, Elo -511.5 +/- 340.0"},
+		{match.Stats{Wins: 5, Draws: 0, Losses: 0}, math.Inf(1), math.Inf(1), "+5 =0 -0, Elo +Inf +/- no estimate"},
+		{match.Stats{Wins: 0, Draws: 0, Losses: 5}, math.Inf(-1), math.Inf(1), "+0 =0 -5, Elo -Inf +/- no estimate"},
+	}
+	for _, test := range tests {
+		diff, margin := test.Stats.Elo()
+		if math.IsNaN(diff) || math.IsNaN(margin) {
+			t.Fatalf("%s: expected a number but got %.1f +/- %.1f", test.Stats, diff, margin)
+		}
+		if math.IsInf(test.Diff, 0) && diff != test.Diff || math.IsInf(test.Margin, 0) && margin != test.Margin {
+			t.Fatalf("%s: expected %.1f +/- %.1f but got %.1f +/- %.1f", test.Stats, test.Diff, test.Margin, diff, margin)
+		}
+		if !math.IsInf(test.Diff, 0) && (math.Abs(diff-test.Diff) > 0.1 || math.Abs(margin-test.Margin) > 0.1) {
+			t.Fatalf("%s: expected %.1f +/- %.1f but got %.1f +/- %.1f", test.Stats, test.Diff, test.Margin, diff, margin)
+		}
+		if s := test.Stats.String(); s != test.String {
+			t.Fatalf("expected %q but got %q", test.String, s)
+		}
+	}
+}
+
+func TestSPRT(t *testing.T) {
+	tests := []struct {
+		Stats    match.Stats
+		Decision match.SPRTDecision
+	}{
+		{match.Stats{Wins: 600, Draws: 200, Losses: 200}, match.SPRTAcceptH1},
+		{match.Stats{Wins: 200, Draws: 200, Losses: 600}, match.SPRTAcceptH0},
+		{match.Stats{Wins: 10, Draws: 10, Losses: 10}, match.SPRTContinue},
+	}
+	for _, test := range tests {
+		r := test.Stats.SPRT(0, 10, 0.05, 0.05)
+		if r.Decision != test.Decision {
+			t.Fatalf("%s: expected %s but got %s with LLR %.2f", test.Stats, test.Decision, r.Decision, r.LLR)
+		}
+	}
+}
@@ -24,6 +24,9 @@ const (
 	inCheck
 	// IsCheckmate indicates that the move puts the opposing player in checkmate.
 	IsCheckmate
+	// Drop indicates that the move drops a piece from the player's pocket
+	// in Crazyhouse.
+	Drop
 )
 
 // A Move is the movement of a piece from one square to another.
@@ -55,9 +58,18 @@ func NewMove(s1 Square, s2 Square, promo PromoType, piece ...Piece) Move {
 	return m
 }
 
+// NewDrop returns a move dropping the piece from its player's pocket onto
+// the square in Crazyhouse.
+func NewDrop(p Piece, sq Square) Move {
+	return NewMove(NoSquare, sq, NoPromo, p).addTag(Drop)
+}
+
 // String returns a string useful for debugging.  String doesn't return
 // algebraic notation.
 func (m Move) String() string {
+	if m.HasTag(Drop) {
+		return dropString(m)
+	}
 	return fmt.Sprintf("%s%s%s", m.S1().String(), m.S2().String(), m.Promo().PieceType().String())
 }
 
@@ -120,6 +132,10 @@ func (m Move) setField(mask uint64, offset int, value uint64) Move {
 
 func (m Move) Eq(other Move) bool {
 	toCompare := Move(movePromoMask | moveS1Mask | moveS2Mask)
+	// drops of different pieces only differ by the piece
+	if m.S1() == NoSquare {
+		toCompare |= movePieceMask
+	}
 	return m&toCompare == other&toCompare
 }
 
@@ -3,6 +3,8 @@ package chess
 import (
 	"log"
 	"testing"
+
+	"github.com/barakmich/chess/bitflip"
 )
 
 type moveTest struct {
@@ -244,9 +246,11 @@ var perfResults = []perfTest{
 }
 
 func TestPerfResults(t *testing.T) {
-	for _, perf := range perfResults {
-		countMoves(t, perf.pos, []*Position{perf.pos}, perf.nodesPerDepth, len(perf.nodesPerDepth))
-	}
+	forEachKernel(t, func(t *testing.T) {
+		for _, perf := range perfResults {
+			countMoves(t, perf.pos, []*Position{perf.pos}, perf.nodesPerDepth, len(perf.nodesPerDepth))
+		}
+	})
 }
 
 func countMoves(t *testing.T, originalPosition *Position, positions []*Position, nodesPerDepth []int, maxDepth int) {
@@ -280,6 +284,34 @@ func countMoves(t *testing.T, originalPosition *Position, positions []*Position,
 	countMoves(t, originalPosition, newPositions, nodesPerDepth[1:], maxDepth)
 }
 
+func benchmarkPerft(b *testing.B, k bitflip.Kernel) {
+	prev, err := bitflip.SetKernel(k)
+	if err != nil {
+		b.Skip(err)
+	}
+	defer bitflip.SetKernel(prev)
+	pos := unsafeFEN("r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1")
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		perft(pos, 3)
+	}
+}
+
+func perft(pos *Position, depth int) int {
+	if depth == 0 {
+		return 1
+	}
+	nodes := 0
+	for _, m := range pos.ValidMoves() {
+		nodes += perft(pos.Update(m), depth-1)
+	}
+	return nodes
+}
+
+func BenchmarkPerftGeneric(b *testing.B) { benchmarkPerft(b, bitflip.Generic) }
+func BenchmarkPerftAVX(b *testing.B)     { benchmarkPerft(b, bitflip.AVX) }
+func BenchmarkPerftMagic(b *testing.B)   { benchmarkPerft(b, bitflip.Magic) }
+
 func BenchmarkValidMoves(b *testing.B) {
 	pos := unsafeFEN("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1")
 	b.ResetTimer()
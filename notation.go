@@ -1,64 +1,175 @@
 package chess
 
 import (
+	"errors"
 	"fmt"
 	"regexp"
 	"strings"
+	"sync"
 )
 
-type Notation int
+// A Notation encodes moves as text and decodes text into moves for a
+// position.  Notations can be registered with RegisterNotation so that
+// DecodeMove and Game's MoveStr method try them.
+type Notation interface {
+	Encode(pos *Position, m Move) string
+	Decode(pos *Position, s string) (Move, error)
+}
+
+var (
+	// SANNotation is Standard Algebraic Notation, like Nf3 or e8=Q, and
+	// decodes loosely written moves like Ng1f3.
+	SANNotation Notation = sanNotation{}
+	// StrictSANNotation is Standard Algebraic Notation that only decodes
+	// moves written the way PGNs write them.
+	StrictSANNotation Notation = sanNotation{strict: true}
+	// UCINotation is the notation of the Universal Chess Interface, like
+	// g1f3 or e7e8q.
+	UCINotation Notation = uciNotation{}
+	// LongAlgebraicNotation is algebraic notation with the square a piece
+	// moves from, like Ng1f3.
+	LongAlgebraicNotation Notation = longAlgebraicNotation{}
+	// FANNotation is Figurine Algebraic Notation, which is SAN with the
+	// figurine of the moving side's piece in place of its letter, like
+	// ♘f3 or e8=♕.  Decoding takes the figurines of either color.
+	FANNotation Notation = letterSAN{
+		symbols: [2]string{"♔♕♖♗♘", "♚♛♜♝♞"},
+		pawns:   "♙♟",
+	}
+	// ICCFNotation is the numeric notation of correspondence chess, which
+	// numbers files and ranks from 1 to 8, like 7163 for g1f3.  A
+	// promotion adds 1 for a queen, 2 for a rook, 3 for a bishop or 4 for
+	// a knight, like 57581, and castling is the king's move.  Drops are
+	// written as in UCI.
+	ICCFNotation Notation = iccfNotation{}
+)
+
+type sanNotation struct {
+	strict bool
+}
+
+func (n sanNotation) Encode(pos *Position, m Move) string {
+	return pos.EncodeSAN(m)
+}
+
+func (n sanNotation) Decode(pos *Position, s string) (Move, error) {
+	if n.strict {
+		return parseSAN(s, pos)
+	}
+	return pos.DecodeSAN(s)
+}
+
+type uciNotation struct{}
 
-const (
-	SANNotation = iota
-	StrictSANNotation
-	UCINotation
-	LongAlgebraicNotation
+func (uciNotation) Encode(pos *Position, m Move) string {
+	return pos.EncodeUCI(m)
+}
+
+func (uciNotation) Decode(pos *Position, s string) (Move, error) {
+	return pos.DecodeUCI(s)
+}
+
+type longAlgebraicNotation struct{}
+
+func (longAlgebraicNotation) Encode(pos *Position, m Move) string {
+	return pos.EncodeLongAlgebraic(m)
+}
+
+func (longAlgebraicNotation) Decode(pos *Position, s string) (Move, error) {
+	return pos.DecodeLongAlgebraic(s)
+}
+
+// registeredNotation is a notation with the name it was registered by.
+type registeredNotation struct {
+	name string
+	n    Notation
+}
+
+var (
+	notationsMu sync.RWMutex
+	// notations are tried in order by DecodeMove, so the strictest come
+	// first.
+	notations = []registeredNotation{
+		{"strict-san", StrictSANNotation},
+		{"san", SANNotation},
+		{"long-algebraic", LongAlgebraicNotation},
+		{"uci", UCINotation},
+		{"fan", FANNotation},
+		{"iccf", ICCFNotation},
+		{"lenient", LenientNotation},
+	}
 )
 
+// RegisterNotation registers the notation by name, to be returned by
+// NotationByName and tried by DecodeMove after the notations registered
+// before it.  The built in notations are registered as "strict-san",
+// "san", "long-algebraic", "uci", "fan", "iccf" and "lenient".  An error
+// is returned if the name is already registered.
+func RegisterNotation(name string, n Notation) error {
+	if n == nil {
+		return fmt.Errorf("chess: notation %q is nil", name)
+	}
+	notationsMu.Lock()
+	defer notationsMu.Unlock()
+	for _, r := range notations {
+		if r.name == name {
+			return fmt.Errorf("chess: notation %q is already registered", name)
+		}
+	}
+	notations = append(notations, registeredNotation{name, n})
+	return nil
+}
+
+// NotationByName returns the notation registered by the name, or false if
+// there isn't one.
+func NotationByName(name string) (Notation, bool) {
+	notationsMu.RLock()
+	defer notationsMu.RUnlock()
+	for _, r := range notations {
+		if r.name == name {
+			return r.n, true
+		}
+	}
+	return nil, false
+}
+
+// EncodeMove returns the move's text in the notation.
 func (pos *Position) EncodeMove(m Move, n Notation) string {
-	switch n {
-	case SANNotation:
-		return pos.EncodeSAN(m)
-	case StrictSANNotation:
-		return pos.EncodeSAN(m)
-	case UCINotation:
-		return pos.EncodeUCI(m)
-	case LongAlgebraicNotation:
-		return pos.EncodeLongAlgebraic(m)
-	}
-	panic("unreachable")
+	return n.Encode(pos, m)
 }
 
+// DecodeMove decodes the text in the notation if one is given, and
+// otherwise tries each registered notation in turn, starting with SAN.
+// If none decode it, an *AmbiguousMoveError from one of them is returned
+// in place of the generic error.
 func (pos *Position) DecodeMove(s string, n ...Notation) (Move, error) {
 	if len(n) != 0 {
-		switch n[0] {
-		case SANNotation:
-			return pos.DecodeSAN(s)
-		case StrictSANNotation:
-			return parseSAN(s, pos)
-		case UCINotation:
-			return pos.DecodeUCI(s)
-		case LongAlgebraicNotation:
-			return pos.DecodeLongAlgebraic(s)
+		return n[0].Decode(pos, s)
+	}
+	notationsMu.RLock()
+	tries := append([]registeredNotation(nil), notations...)
+	notationsMu.RUnlock()
+	var ambiguous *AmbiguousMoveError
+	for _, r := range tries {
+		m, err := r.n.Decode(pos, s)
+		if err == nil {
+			return m, nil
+		}
+		if ambiguous == nil {
+			errors.As(err, &ambiguous)
 		}
 	}
-	if m, err := parseSAN(s, pos); err == nil {
-		return m, nil
-	}
-	if m, err := pos.DecodeSAN(s); err == nil {
-		return m, nil
-	}
-	if m, err := pos.DecodeLongAlgebraic(s); err == nil {
-		return m, nil
-	}
-	if m, err := pos.DecodeUCI(s); err == nil {
-		return m, nil
+	if ambiguous != nil {
+		return 0, ambiguous
 	}
 	return 0, fmt.Errorf(`chess: failed to decode notation text "%s" for position %s`, s, pos)
 }
 
 // Encode implements the Encoder interface.
 func (pos *Position) EncodeUCI(m Move) string {
+	if m.HasTag(Drop) {
+		return dropString(m)
+	}
 	return m.S1().String() + m.S2().String() + m.Promo().PieceType().String()
 }
 
@@ -69,6 +180,9 @@ func (pos *Position) DecodeUCI(s string) (Move, error) {
 	if l < 4 || l > 5 {
 		return 0, err
 	}
+	if l == 4 && s[1] == '@' {
+		return pos.decodeUCIDrop(s, err)
+	}
 	s1, ok := strToSquareMap[s[0:2]]
 	if !ok {
 		return 0, err
@@ -108,13 +222,29 @@ func (pos *Position) DecodeUCI(s string) (Move, error) {
 	return m, nil
 }
 
+// decodeUCIDrop decodes a Crazyhouse drop like N@f3 or P@e4.
+func (pos *Position) decodeUCIDrop(s string, err error) (Move, error) {
+	p, ok := fenPieceMap[s[:1]]
+	sq, sqOK := strToSquareMap[s[2:4]]
+	if !ok || !sqOK || p.Color() != White || p.Type() == King {
+		return 0, err
+	}
+	c := White
+	if pos != nil {
+		c = pos.Turn()
+	}
+	return NewDrop(GetPiece(p.Type(), c), sq), nil
+}
+
 func (pos *Position) EncodeSAN(m Move) string {
 	return pos.encodeSANInternal(m, nil)
 }
 
 func (pos *Position) encodeSANInternal(m Move, validMoves []Move) string {
 	checkChar := getCheckChar(pos, m)
-	if m.HasTag(KingSideCastle) {
+	if m.HasTag(Drop) {
+		return dropString(m) + checkChar
+	} else if m.HasTag(KingSideCastle) {
 		return "O-O" + checkChar
 	} else if m.HasTag(QueenSideCastle) {
 		return "O-O-O" + checkChar
@@ -143,7 +273,7 @@ func (pos *Position) encodeSANInternal(m Move, validMoves []Move) string {
 	return sb.String()
 }
 
-var pgnRegex = regexp.MustCompile(`^(?:([RNBQKP]?)([abcdefgh]?)(\d?)(x?)([abcdefgh])(\d)(=[QRBN])?|(O-O(?:-O)?))([+#!?]|e\.p\.)*$`)
+var pgnRegex = regexp.MustCompile(`^(?:([RNBQKP]?)([abcdefgh]?)(\d?)(x?)([abcdefgh])(\d)(=[QRBNK])?|(O-O(?:-O)?))([+#!?]|e\.p\.)*$`)
 
 func algebraicNotationParts(s string) ([]string, error) {
 	submatches := pgnRegex.FindStringSubmatch(s)
@@ -231,7 +361,9 @@ func (pos *Position) DecodeSAN(s string) (Move, error) {
 
 func (pos *Position) EncodeLongAlgebraic(m Move) string {
 	checkChar := getCheckChar(pos, m)
-	if m.HasTag(KingSideCastle) {
+	if m.HasTag(Drop) {
+		return dropString(m) + checkChar
+	} else if m.HasTag(KingSideCastle) {
 		return "O-O" + checkChar
 	} else if m.HasTag(QueenSideCastle) {
 		return "O-O-O" + checkChar
@@ -321,6 +453,8 @@ func charForPromo(p PromoType) string {
 		return "=Q"
 	case PromoRook:
 		return "=R"
+	case PromoKing:
+		return "=K"
 	}
 	return ""
 }
@@ -351,6 +485,8 @@ func pieceTypeFromChar(c string) PieceType {
 		return Bishop
 	case "n":
 		return Knight
+	case "k":
+		return King
 	}
 	return NoPieceType
 }
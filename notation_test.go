@@ -119,6 +119,102 @@ func TestInvalidDecoding(t *testing.T) {
 	}
 }
 
+func TestNotations(t *testing.T) {
+	german, err := LocalizedSAN("de")
+	if err != nil {
+		t.Fatal(err)
+	}
+	french, err := LocalizedSAN("fr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		n    Notation
+		fen  string
+		move string
+		text string
+	}{
+		{FANNotation, "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "Nf3", "♘f3"},
+		{FANNotation, "rnbqkbnr/pppppppp/8/8/8/5N2/PPPPPPPP/RNBQKB1R b KQkq - 1 1", "Nf6", "♞f6"},
+		{FANNotation, "8/4P3/8/8/8/2k5/8/1K6 w - - 0 1", "e8=Q", "e8=♕"},
+		{FANNotation, "r3k3/8/8/8/8/8/8/4K3 b q - 0 1", "O-O-O", "O-O-O"},
+		{german, "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "Nf3", "Sf3"},
+		{german, "8/4P3/8/8/8/2k5/8/1K6 w - - 0 1", "e8=Q", "e8=D"},
+		{german, "6k1/5ppp/8/8/8/8/8/R3K3 w - - 0 1", "Ra8#", "Ta8#"},
+		{french, "rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq - 0 2", "Ke2", "Re2"},
+		{french, "rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq - 0 2", "Bc4", "Fc4"},
+		{ICCFNotation, "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "e4", "5254"},
+		{ICCFNotation, "8/4P3/8/8/8/2k5/8/1K6 w - - 0 1", "e8=N", "57584"},
+		{ICCFNotation, "7k/8/8/8/8/8/8/R3K2R w KQ - 0 1", "O-O", "5171"},
+	}
+	for _, test := range tests {
+		pos := unsafeFEN(test.fen)
+		m, err := pos.DecodeMove(test.move)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if text := pos.EncodeMove(m, test.n); text != test.text {
+			t.Fatalf("expected %s to be encoded as %s but got %s", test.move, test.text, text)
+		}
+		decoded, err := pos.DecodeMove(test.text, test.n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if decoded != m {
+			t.Fatalf("expected %s to be decoded as %s but got %s", test.text, m, decoded)
+		}
+	}
+}
+
+func TestMoveStrNotations(t *testing.T) {
+	german, _ := LocalizedSAN("de")
+	g := NewGame()
+	g.Notation = german
+	// the game's notation comes first, then the registered ones
+	for _, s := range []string{"Sf3", "♞f6", "5254", "e6", "Lc4"} {
+		if err := g.MoveStr(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !strings.Contains(g.String(), "1. Sf3 Sf6 2. e4 e6 3. Lc4") {
+		t.Fatalf("expected German moves but got %s", g.String())
+	}
+}
+
+func TestRegisterNotation(t *testing.T) {
+	if n, ok := NotationByName("iccf"); !ok || n != ICCFNotation {
+		t.Fatal("expected ICCF to be registered")
+	}
+	if err := RegisterNotation("san", SANNotation); err == nil {
+		t.Fatal("expected an error registering san twice")
+	}
+	czech, _ := LocalizedSAN("cs")
+	// the registration outlives the test when it's run more than once
+	if _, ok := NotationByName("test-san-cs"); !ok {
+		if err := RegisterNotation("test-san-cs", czech); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n, ok := NotationByName("test-san-cs"); !ok || n != czech {
+		t.Fatal("expected the Czech notation to be registered")
+	}
+	m, err := StartingPosition().DecodeMove("Jf3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.S1() != G1 || m.S2() != F3 {
+		t.Fatalf("expected Jf3 to move the g1 knight but got %s", m)
+	}
+	for _, letters := range []string{"", "KDTL", "KDTLL", "kdtls", "KDTLO"} {
+		if _, err := NewLocalizedSAN(letters); err == nil {
+			t.Fatalf("expected an error from piece letters %q", letters)
+		}
+	}
+	if _, err := LocalizedSAN("xx"); err == nil {
+		t.Fatal("expected an error from an unknown language")
+	}
+}
+
 func BenchmarkValidAlgebraicDecoding(b *testing.B) {
 	f, err := os.Open("fixtures/valid_notation_tests.json")
 	if err != nil {
@@ -3,10 +3,12 @@ package opening
 import (
 	"bytes"
 	"encoding/csv"
+	"errors"
 	"fmt"
 	"io"
-	"log"
 	"strings"
+	"sync"
+	"sync/atomic"
 
 	"github.com/barakmich/chess"
 )
@@ -14,13 +16,104 @@ import (
 // BookECO represents the Encyclopedia of Chess Openings https://en.wikipedia.org/wiki/Encyclopaedia_of_Chess_Openings
 // BookECO is safe for concurrent use.
 type BookECO struct {
+	mu               sync.RWMutex
 	root             *node
 	startingPosition *chess.Position
+	// positions indexes the nodes by the Polyglot key of their position so
+	// transpositions into book lines are found.
+	positions map[uint64][]*node
 }
 
 // NewBookECO returns a new BookECO.  This operation has to parse 2k rows of CSV data and insert it into a graph
-// so it can take some time.
+// so it can take some time.  Use DefaultBook to share a single book.
 func NewBookECO() *BookECO {
+	b, err := NewBookFromTSV(bytes.NewReader(ecoData))
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+var (
+	defaultBook     *BookECO
+	defaultBookOnce sync.Once
+)
+
+// DefaultBook returns a BookECO shared by the whole program, which is built
+// the first time it's needed.  Lines added to it with AddOpening are seen by
+// every user of the default book.
+func DefaultBook() *BookECO {
+	defaultBookOnce.Do(func() {
+		defaultBook = NewBookECO()
+	})
+	return defaultBook
+}
+
+// NewBookFromTSV returns a book of the openings in the tab separated data,
+// such as the Lichess chess-openings files.  The first row is a header
+// naming the columns: name is required, eco is the optional code, and the
+// moves are read from the uci column if there is one and from the pgn
+// column, in SAN, otherwise.  Other columns are ignored.
+func NewBookFromTSV(r io.Reader) (*BookECO, error) {
+	b := newBook()
+	cr := csv.NewReader(r)
+	cr.Comma = '\t'
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, errors.New("opening: missing tsv header")
+	} else if err != nil {
+		return nil, fmt.Errorf("opening: invalid tsv: %w", err)
+	}
+	cols := map[string]int{}
+	for i, h := range header {
+		cols[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	nameCol, ok := cols["name"]
+	if !ok {
+		return nil, errors.New("opening: tsv has no name column")
+	}
+	movesCol, uci := cols["uci"]
+	if !uci {
+		if movesCol, ok = cols["pgn"]; !ok {
+			return nil, errors.New("opening: tsv has no uci or pgn column")
+		}
+	}
+	codeCol, hasCode := cols["eco"]
+	for line := 2; ; line++ {
+		row, err := cr.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("opening: invalid tsv: %w", err)
+		}
+		field := func(i int) string {
+			if i < len(row) {
+				return strings.TrimSpace(row[i])
+			}
+			return ""
+		}
+		var moves []chess.Move
+		if uci {
+			moves, err = decodeMoves(b.startingPosition, field(movesCol), (*chess.Position).DecodeUCI)
+		} else {
+			moves, err = decodeMoves(b.startingPosition, field(movesCol), (*chess.Position).DecodeSAN)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("opening: tsv line %d: %w", line, err)
+		}
+		code := ""
+		if hasCode {
+			code = field(codeCol)
+		}
+		if _, err := b.AddOpening(code, field(nameCol), moves); err != nil {
+			return nil, fmt.Errorf("opening: tsv line %d: %w", line, err)
+		}
+	}
+	return b, nil
+}
+
+func newBook() *BookECO {
 	startingPosition := &chess.Position{}
 	if err := startingPosition.UnmarshalText([]byte("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1")); err != nil {
 		panic(err)
@@ -28,75 +121,218 @@ func NewBookECO() *BookECO {
 	b := &BookECO{
 		root: &node{
 			children: map[string]*node{},
-			pos:      chess.NewGame().Position(),
+			pos:      startingPosition,
 			label:    label(),
 		},
 		startingPosition: startingPosition,
+		positions:        map[uint64][]*node{},
 	}
-	r := csv.NewReader(bytes.NewBuffer(ecoData))
-	r.Comma = '\t'
-	records, err := r.ReadAll()
-	if err != nil {
-		log.Fatal(err)
+	b.positions[PolyglotKey(b.root.pos)] = []*node{b.root}
+	return b
+}
+
+// decodeMoves decodes the space separated moves from pos, skipping move
+// numbers.
+func decodeMoves(pos *chess.Position, s string, decode func(*chess.Position, string) (chess.Move, error)) ([]chess.Move, error) {
+	var moves []chess.Move
+	for _, tok := range parseMoveList(s) {
+		m, err := decode(pos, tok)
+		if err != nil {
+			return nil, err
+		}
+		moves = append(moves, m)
+		pos = pos.Update(m)
 	}
-	for i, row := range records {
-		if i == 0 {
-			continue
+	return moves, nil
+}
+
+// AddOpening adds a named line to the book, such as a repertoire label, and
+// returns its opening.  A line already in the book is renamed.  The moves
+// must be legal from the starting position.
+func (b *BookECO) AddOpening(code, title string, moves []chess.Move) (*Opening, error) {
+	if len(moves) == 0 {
+		return nil, errors.New("opening: empty line")
+	}
+	posList := []*chess.Position{b.startingPosition}
+	uci := make([]string, len(moves))
+	tagged := make([]chess.Move, len(moves))
+	for i, m := range moves {
+		pos := posList[i]
+		for _, v := range pos.ValidMoves() {
+			if v.Eq(m) {
+				tagged[i] = v
+				break
+			}
+		}
+		if tagged[i] == 0 {
+			return nil, fmt.Errorf("opening: illegal move %s in line %q", m, title)
 		}
-		o := &Opening{code: row[0], title: row[1], pgn: row[3]}
-		b.insert(o)
+		uci[i] = tagged[i].String()
+		posList = append(posList, pos.Update(tagged[i]))
 	}
-	return b
+	o := &Opening{code: code, title: title, pgn: strings.Join(uci, " ")}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.ins(b.root, o, posList[1:], tagged)
+	return o, nil
 }
 
-// Find implements the Book interface
+// Find implements the Book interface.  The opening is that of the deepest
+// position reached in the book, whatever the move order.
 func (b *BookECO) Find(moves []chess.Move) *Opening {
-	for n := b.followPath(b.root, moves); n != nil; n = n.parent {
-		if n.opening != nil {
-			return n.opening
+	return b.find(b.positionsAfter(moves), moves)
+}
+
+// Possible implements the Book interface.  The openings are those whose lines
+// pass through the deepest position reached in the book.
+func (b *BookECO) Possible(moves []chess.Move) []*Opening {
+	return b.possible(b.positionsAfter(moves))
+}
+
+// FindGame returns the opening of the deepest position of the game found in
+// the book, or nil if none is.  Unlike Find it works for games that started
+// from a FEN.
+func (b *BookECO) FindGame(g *chess.Game) *Opening {
+	var moves []chess.Move
+	positions := g.Positions()
+	if PolyglotKey(positions[0]) == PolyglotKey(b.startingPosition) {
+		moves = g.Moves()
+	}
+	return b.find(positions, moves)
+}
+
+// PossibleGame returns the openings whose lines pass through the deepest
+// position of the game found in the book.  If no position is found all
+// openings are returned.
+func (b *BookECO) PossibleGame(g *chess.Game) []*Opening {
+	return b.possible(g.Positions())
+}
+
+// positionsAfter returns the starting position and the positions after each
+// of the moves, stopping at the first illegal move.
+func (b *BookECO) positionsAfter(moves []chess.Move) []*chess.Position {
+	pos := b.startingPosition
+	positions := []*chess.Position{pos}
+	for _, m := range moves {
+		var next *chess.Position
+		for _, v := range pos.ValidMoves() {
+			if v.Eq(m) {
+				next = pos.Update(v)
+				break
+			}
+		}
+		if next == nil {
+			break
 		}
+		pos = next
+		positions = append(positions, pos)
+	}
+	return positions
+}
+
+// find returns the opening of the deepest position in the book, which is
+// the nearest named line leading to it.  If several lines reach the position,
+// the one played by moves from the starting position wins.
+func (b *BookECO) find(positions []*chess.Position, moves []chess.Move) *Opening {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	exact := b.exactPath(moves)
+	for i := len(positions) - 1; i >= 0; i-- {
+		n := b.nodeAt(positions[i], exact)
+		if n == nil {
+			continue
+		}
+		for ; n != nil; n = n.parent {
+			if n.opening != nil {
+				return n.opening
+			}
+		}
+		return nil
 	}
 	return nil
 }
 
-// Possible implements the Book interface
-func (b *BookECO) Possible(moves []chess.Move) []*Opening {
-	n := b.followPath(b.root, moves)
+// Path returns every named opening the game passed through, in the order
+// they were reached.  Book lines that only extend the previous opening under
+// the same name are left out.  Like FindGame it follows transpositions and
+// works for games that started from a FEN.
+func (b *BookECO) Path(g *chess.Game) []*Opening {
+	var moves []chess.Move
+	positions := g.Positions()
+	if PolyglotKey(positions[0]) == PolyglotKey(b.startingPosition) {
+		moves = g.Moves()
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	exact := b.exactPath(moves)
+	seen := map[*Opening]bool{}
 	openings := []*Opening{}
-	for _, n := range b.nodeList(n) {
-		if n.opening != nil {
-			openings = append(openings, n.opening)
+	for _, pos := range positions {
+		n := b.nodeAt(pos, exact)
+		if n == nil || n.opening == nil || seen[n.opening] {
+			continue
 		}
+		seen[n.opening] = true
+		if l := len(openings); l > 0 && openings[l-1].code == n.opening.code && openings[l-1].title == n.opening.title {
+			continue
+		}
+		openings = append(openings, n.opening)
 	}
 	return openings
 }
 
-func (b *BookECO) followPath(n *node, moves []chess.Move) *node {
-	if len(moves) == 0 {
-		return n
+// exactPath returns the nodes on the trie path of the moves from the
+// starting position.
+func (b *BookECO) exactPath(moves []chess.Move) map[*node]bool {
+	exact := map[*node]bool{}
+	n := b.root
+	for _, m := range moves {
+		c, ok := n.children[m.String()]
+		if !ok {
+			break
+		}
+		exact[c] = true
+		n = c
 	}
-	c, ok := n.children[moves[0].String()]
-	if !ok {
-		return n
+	return exact
+}
+
+// nodeAt returns the book node of the position, preferring one on the exact
+// path, or nil if the position isn't in the book.
+func (b *BookECO) nodeAt(pos *chess.Position, exact map[*node]bool) *node {
+	nodes := b.positions[PolyglotKey(pos)]
+	if len(nodes) == 0 {
+		return nil
 	}
-	return b.followPath(c, moves[1:])
+	for _, c := range nodes {
+		if exact[c] {
+			return c
+		}
+	}
+	return nodes[0]
 }
 
-func (b *BookECO) insert(o *Opening) error {
-	posList := []*chess.Position{b.startingPosition}
-	moves := []chess.Move{}
-	for _, s := range parseMoveList(o.pgn) {
-		pos := posList[len(posList)-1]
-		m, err := pos.DecodeUCI(s)
-		if err != nil {
-			panic(err)
+func (b *BookECO) possible(positions []*chess.Position) []*Opening {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	roots := []*node{b.root}
+	for i := len(positions) - 1; i >= 0; i-- {
+		if nodes, ok := b.positions[PolyglotKey(positions[i])]; ok {
+			roots = nodes
+			break
 		}
-		moves = append(moves, m)
-		posList = append(posList, pos.Update(m))
 	}
-	n := b.root
-	b.ins(n, o, posList[1:], moves)
-	return nil
+	seen := map[*Opening]bool{}
+	openings := []*Opening{}
+	for _, root := range roots {
+		for _, n := range b.nodeList(root) {
+			if n.opening != nil && !seen[n.opening] {
+				seen[n.opening] = true
+				openings = append(openings, n.opening)
+			}
+		}
+	}
+	return openings
 }
 
 func (b *BookECO) ins(n *node, o *Opening, posList []*chess.Position, moves []chess.Move) {
@@ -118,6 +354,8 @@ func (b *BookECO) ins(n *node, o *Opening, posList []*chess.Position, moves []ch
 			label:    label(),
 		}
 		n.children[moveStr] = child
+		key := PolyglotKey(pos)
+		b.positions[key] = append(b.positions[key], child)
 	}
 	if len(posList) == 1 {
 		child.opening = o
@@ -175,26 +413,24 @@ func (b *BookECO) nodeList(root *node) []*node {
 }
 
 var (
-	labelCount = 0
+	labelCount int64
 	alphabet   = "abcdefghijklmnopqrstuvwxyz"
 )
 
 func label() string {
-	s := "a" + fmt.Sprint(labelCount)
-	labelCount++
-	return s
+	return "a" + fmt.Sprint(atomic.AddInt64(&labelCount, 1)-1)
 }
 
 // 1.b2b4 e7e5 2.c1b2 f7f6 3.e2e4 f8b4 4.f1c4 b8c6 5.f2f4 d8e7 6.f4f5 g7g6
 func parseMoveList(pgn string) []string {
-	strs := strings.Split(pgn, " ")
+	strs := strings.Fields(pgn)
 	cp := []string{}
 	for _, s := range strs {
-		i := strings.Index(s, ".")
-		if i == -1 {
+		if i := strings.LastIndex(s, "."); i != -1 {
+			s = s[i+1:]
+		}
+		if s != "" {
 			cp = append(cp, s)
-		} else {
-			cp = append(cp, s[i+1:])
 		}
 	}
 	return cp
@@ -0,0 +1,573 @@
+package opening
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/barakmich/chess"
+)
+
+// Speed is the time control category of a game, as used by Lichess.
+type Speed int
+
+const (
+	// UnknownSpeed is a game without a TimeControl tag.
+	UnknownSpeed Speed = iota
+	UltraBullet
+	Bullet
+	Blitz
+	Rapid
+	Classical
+	Correspondence
+)
+
+// String implements the fmt.Stringer interface.
+func (s Speed) String() string {
+	switch s {
+	case UltraBullet:
+		return "ultraBullet"
+	case Bullet:
+		return "bullet"
+	case Blitz:
+		return "blitz"
+	case Rapid:
+		return "rapid"
+	case Classical:
+		return "classical"
+	case Correspondence:
+		return "correspondence"
+	}
+	return "unknown"
+}
+
+// SpeedOf returns the speed of a PGN TimeControl tag value such as 300+3.
+// Like Lichess, the speed is based on the estimated game duration of the
+// initial time plus 40 increments.
+func SpeedOf(timeControl string) Speed {
+	if timeControl == "-" {
+		return Correspondence
+	}
+	base, inc, _ := strings.Cut(timeControl, "+")
+	b, err := strconv.Atoi(base)
+	if err != nil {
+		return UnknownSpeed
+	}
+	i, _ := strconv.Atoi(inc)
+	switch d := b + 40*i; {
+	case d < 30:
+		return UltraBullet
+	case d < 180:
+		return Bullet
+	case d < 480:
+		return Blitz
+	case d < 1500:
+		return Rapid
+	}
+	return Classical
+}
+
+// ExplorerOptions selects the games and moves added to an Explorer.
+type ExplorerOptions struct {
+	// MinRating and MaxRating bound the average rating of the players.
+	// Zero means no bound.  Games without ratings are skipped if either
+	// bound is set.
+	MinRating int
+	MaxRating int
+	// Speeds are the time controls of the games to add.  Empty means all.
+	Speeds []Speed
+	// MaxPly is the number of moves from the start of each game that are
+	// added.  Zero means all of them.
+	MaxPly int
+	// RecentGames is the number of most recent games kept for each position.
+	// Zero means five.
+	RecentGames int
+}
+
+const defaultRecentGames = 5
+
+// GameRef identifies a game of an Explorer's database.
+type GameRef struct {
+	// ID is the game's Site tag, such as its Lichess URL.
+	ID       string
+	White    string
+	Black    string
+	WhiteElo int
+	BlackElo int
+	// Date is the game's Date and UTCTime tags, such as 2021.06.23 17:46:28.
+	Date    string
+	Outcome chess.Outcome
+}
+
+// ExplorerMove is a move played in an explorer position and its results.
+type ExplorerMove struct {
+	Move      chess.Move
+	White     int
+	Draws     int
+	Black     int
+	AvgRating int
+}
+
+// Games returns the number of games the move was played in.
+func (m ExplorerMove) Games() int {
+	return m.White + m.Draws + m.Black
+}
+
+// ExplorerResult is the result of querying an Explorer for a position.
+type ExplorerResult struct {
+	White int
+	Draws int
+	Black int
+	// Moves are the moves played in the position, most played first.
+	Moves []ExplorerMove
+	// RecentGames are the most recent games reaching the position, newest
+	// first.
+	RecentGames []GameRef
+}
+
+// Explorer is a tree of the moves played in a database of games, keyed by
+// position like the Lichess opening explorer, so transpositions share their
+// statistics.  Explorer is safe for concurrent use.
+type Explorer struct {
+	opts ExplorerOptions
+
+	mu        sync.RWMutex
+	positions map[uint64]*explorerNode
+}
+
+type explorerNode struct {
+	white, draws, black int
+	// recent holds at most RecentGames games, so games that are no
+	// longer among any position's most recent aren't kept.
+	recent []GameRef
+	moves  map[uint16]*explorerMoveStats
+}
+
+type explorerMoveStats struct {
+	white, draws, black int
+	ratingSum           int
+	ratingCount         int
+}
+
+// NewExplorer returns an empty Explorer that adds games selected by opts.
+func NewExplorer(opts ExplorerOptions) *Explorer {
+	if opts.RecentGames == 0 {
+		opts.RecentGames = defaultRecentGames
+	}
+	return &Explorer{opts: opts, positions: map[uint64]*explorerNode{}}
+}
+
+// AddGame adds the game's moves to the tree and returns true, or returns
+// false if the game doesn't match the explorer's filters.
+func (e *Explorer) AddGame(g *chess.Game) bool {
+	ref := gameRef(g)
+	if !e.accepts(g, ref) {
+		return false
+	}
+	rating := 0
+	if ref.WhiteElo > 0 && ref.BlackElo > 0 {
+		rating = (ref.WhiteElo + ref.BlackElo) / 2
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	positions := g.Positions()
+	for i, m := range g.Moves() {
+		if e.opts.MaxPly > 0 && i >= e.opts.MaxPly {
+			break
+		}
+		n := e.node(PolyglotKey(positions[i]))
+		n.add(ref.Outcome, 1)
+		e.addRecent(n, ref)
+		code := explorerMoveCode(m)
+		ms, ok := n.moves[code]
+		if !ok {
+			ms = &explorerMoveStats{}
+			n.moves[code] = ms
+		}
+		switch ref.Outcome {
+		case chess.WhiteWon:
+			ms.white++
+		case chess.BlackWon:
+			ms.black++
+		default:
+			ms.draws++
+		}
+		if rating > 0 {
+			ms.ratingSum += rating
+			ms.ratingCount++
+		}
+	}
+	return true
+}
+
+// AddPGN decodes the games of the PGN database in parallel and adds them.
+// It returns the number of games added.
+func (e *Explorer) AddPGN(ctx context.Context, r io.Reader) (int, error) {
+	scanner := chess.NewParallelScanner(r)
+	games := make(chan *chess.Game)
+	errc := make(chan error, 1)
+	go func() {
+		errc <- scanner.Begin(ctx, games)
+	}()
+	added := 0
+	for g := range games {
+		if e.AddGame(g) {
+			added++
+		}
+	}
+	if err := <-errc; err != nil {
+		return added, err
+	}
+	if err := scanner.Err(); err != io.EOF {
+		return added, err
+	}
+	return added, nil
+}
+
+// Query returns the statistics of the position.  A position that isn't in
+// the tree returns an empty result.
+func (e *Explorer) Query(pos *chess.Position) *ExplorerResult {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	r := &ExplorerResult{}
+	n, ok := e.positions[PolyglotKey(pos)]
+	if !ok {
+		return r
+	}
+	r.White, r.Draws, r.Black = n.white, n.draws, n.black
+	for code, ms := range n.moves {
+		m, ok := decodeExplorerMove(pos, code)
+		if !ok {
+			continue
+		}
+		em := ExplorerMove{Move: m, White: ms.white, Draws: ms.draws, Black: ms.black}
+		if ms.ratingCount > 0 {
+			em.AvgRating = ms.ratingSum / ms.ratingCount
+		}
+		r.Moves = append(r.Moves, em)
+	}
+	sort.Slice(r.Moves, func(i, j int) bool {
+		if r.Moves[i].Games() != r.Moves[j].Games() {
+			return r.Moves[i].Games() > r.Moves[j].Games()
+		}
+		return r.Moves[i].Move.String() < r.Moves[j].Move.String()
+	})
+	r.RecentGames = append(r.RecentGames, n.recent...)
+	return r
+}
+
+func (e *Explorer) accepts(g *chess.Game, ref GameRef) bool {
+	if e.opts.MinRating > 0 || e.opts.MaxRating > 0 {
+		if ref.WhiteElo == 0 || ref.BlackElo == 0 {
+			return false
+		}
+		avg := (ref.WhiteElo + ref.BlackElo) / 2
+		if e.opts.MinRating > 0 && avg < e.opts.MinRating {
+			return false
+		}
+		if e.opts.MaxRating > 0 && avg > e.opts.MaxRating {
+			return false
+		}
+	}
+	if len(e.opts.Speeds) > 0 {
+		speed := UnknownSpeed
+		if tp := g.GetTagPair("TimeControl"); tp != nil {
+			speed = SpeedOf(tp.Value)
+		}
+		found := false
+		for _, s := range e.opts.Speeds {
+			found = found || s == speed
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *Explorer) node(key uint64) *explorerNode {
+	n, ok := e.positions[key]
+	if !ok {
+		n = &explorerNode{moves: map[uint16]*explorerMoveStats{}}
+		e.positions[key] = n
+	}
+	return n
+}
+
+// addRecent keeps the node's most recent games, newest first.
+func (e *Explorer) addRecent(n *explorerNode, ref GameRef) {
+	i := sort.Search(len(n.recent), func(i int) bool {
+		return n.recent[i].Date < ref.Date
+	})
+	if i >= e.opts.RecentGames {
+		return
+	}
+	n.recent = append(n.recent, GameRef{})
+	copy(n.recent[i+1:], n.recent[i:])
+	n.recent[i] = ref
+	if len(n.recent) > e.opts.RecentGames {
+		n.recent = n.recent[:e.opts.RecentGames]
+	}
+}
+
+func (n *explorerNode) add(o chess.Outcome, count int) {
+	switch o {
+	case chess.WhiteWon:
+		n.white += count
+	case chess.BlackWon:
+		n.black += count
+	default:
+		n.draws += count
+	}
+}
+
+func gameRef(g *chess.Game) GameRef {
+	tag := func(k string) string {
+		if tp := g.GetTagPair(k); tp != nil {
+			return tp.Value
+		}
+		return ""
+	}
+	ref := GameRef{
+		ID:      tag("Site"),
+		White:   tag("White"),
+		Black:   tag("Black"),
+		Date:    strings.TrimSpace(tag("Date") + " " + tag("UTCTime")),
+		Outcome: g.Outcome(),
+	}
+	ref.WhiteElo, _ = strconv.Atoi(tag("WhiteElo"))
+	ref.BlackElo, _ = strconv.Atoi(tag("BlackElo"))
+	return ref
+}
+
+// explorerMoveCode packs a move's squares and promotion into 16 bits.
+func explorerMoveCode(m chess.Move) uint16 {
+	return uint16(m.S1()) | uint16(m.S2())<<6 | uint16(m.Promo())<<12
+}
+
+func decodeExplorerMove(pos *chess.Position, code uint16) (chess.Move, bool) {
+	for _, v := range pos.ValidMoves() {
+		if explorerMoveCode(v) == code {
+			return v, true
+		}
+	}
+	return 0, false
+}
+
+// explorerMagic starts the on-disk format written by Explorer.WriteTo.
+const explorerMagic = "CHEX\x01"
+
+// WriteTo implements the io.WriterTo interface and saves the explorer in a
+// compact binary format that ReadExplorer loads.  The filters aren't saved.
+//
+// The format is the magic bytes followed by varint encoded records: the
+// games referenced as recent games, then each position by key with its
+// results, recent games and moves.
+func (e *Explorer) WriteTo(w io.Writer) (int64, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	cw := &countingWriter{w: bufio.NewWriter(w)}
+	cw.writeString(explorerMagic)
+
+	keys := make([]uint64, 0, len(e.positions))
+	for k := range e.positions {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	// games are written once, in the order positions first reference them
+	gameIdx := map[GameRef]int{}
+	var games []GameRef
+	for _, k := range keys {
+		for _, g := range e.positions[k].recent {
+			if _, ok := gameIdx[g]; !ok {
+				gameIdx[g] = len(games)
+				games = append(games, g)
+			}
+		}
+	}
+	cw.uvarint(uint64(len(games)))
+	for _, g := range games {
+		cw.str(g.ID)
+		cw.str(g.White)
+		cw.str(g.Black)
+		cw.uvarint(uint64(g.WhiteElo))
+		cw.uvarint(uint64(g.BlackElo))
+		cw.str(g.Date)
+		cw.str(string(g.Outcome))
+	}
+	cw.uvarint(uint64(len(keys)))
+	for _, k := range keys {
+		n := e.positions[k]
+		var key [8]byte
+		binary.BigEndian.PutUint64(key[:], k)
+		cw.write(key[:])
+		cw.uvarint(uint64(n.white))
+		cw.uvarint(uint64(n.draws))
+		cw.uvarint(uint64(n.black))
+		cw.uvarint(uint64(len(n.recent)))
+		for _, g := range n.recent {
+			cw.uvarint(uint64(gameIdx[g]))
+		}
+		codes := make([]int, 0, len(n.moves))
+		for code := range n.moves {
+			codes = append(codes, int(code))
+		}
+		sort.Ints(codes)
+		cw.uvarint(uint64(len(codes)))
+		for _, code := range codes {
+			ms := n.moves[uint16(code)]
+			cw.uvarint(uint64(code))
+			cw.uvarint(uint64(ms.white))
+			cw.uvarint(uint64(ms.draws))
+			cw.uvarint(uint64(ms.black))
+			cw.uvarint(uint64(ms.ratingSum))
+			cw.uvarint(uint64(ms.ratingCount))
+		}
+	}
+	if cw.err == nil {
+		cw.err = cw.w.(*bufio.Writer).Flush()
+	}
+	return cw.n, cw.err
+}
+
+// ReadExplorer loads an explorer saved by Explorer.WriteTo.  Games added to
+// it use opts.
+func ReadExplorer(r io.Reader, opts ExplorerOptions) (*Explorer, error) {
+	br := &explorerReader{r: bufio.NewReader(r)}
+	magic := make([]byte, len(explorerMagic))
+	if _, err := io.ReadFull(br.r, magic); err != nil || string(magic) != explorerMagic {
+		return nil, errors.New("opening: not an explorer file")
+	}
+	e := NewExplorer(opts)
+	// slices grow as records are read, as the counts can't be trusted
+	var games []GameRef
+	for i, n := 0, br.count(); i < n && br.err == nil; i++ {
+		var g GameRef
+		g.ID, g.White, g.Black = br.str(), br.str(), br.str()
+		g.WhiteElo, g.BlackElo = int(br.uvarint()), int(br.uvarint())
+		g.Date, g.Outcome = br.str(), chess.Outcome(br.str())
+		games = append(games, g)
+	}
+	for i, n := 0, br.count(); i < n && br.err == nil; i++ {
+		var key [8]byte
+		if _, err := io.ReadFull(br.r, key[:]); err != nil {
+			br.err = err
+			break
+		}
+		node := e.node(binary.BigEndian.Uint64(key[:]))
+		node.white, node.draws, node.black = int(br.uvarint()), int(br.uvarint()), int(br.uvarint())
+		for j, nr := 0, br.count(); j < nr && br.err == nil; j++ {
+			idx := br.uvarint()
+			if idx >= uint64(len(games)) {
+				br.err = fmt.Errorf("opening: explorer game %d out of range", idx)
+				break
+			}
+			if len(node.recent) < e.opts.RecentGames {
+				node.recent = append(node.recent, games[idx])
+			}
+		}
+		for j, nm := 0, br.count(); j < nm && br.err == nil; j++ {
+			code := uint16(br.uvarint())
+			node.moves[code] = &explorerMoveStats{
+				white:       int(br.uvarint()),
+				draws:       int(br.uvarint()),
+				black:       int(br.uvarint()),
+				ratingSum:   int(br.uvarint()),
+				ratingCount: int(br.uvarint()),
+			}
+		}
+	}
+	if br.err != nil {
+		return nil, fmt.Errorf("opening: invalid explorer file: %w", br.err)
+	}
+	return e, nil
+}
+
+type countingWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+	buf [binary.MaxVarintLen64]byte
+}
+
+func (cw *countingWriter) write(b []byte) {
+	if cw.err != nil {
+		return
+	}
+	n, err := cw.w.Write(b)
+	cw.n += int64(n)
+	cw.err = err
+}
+
+func (cw *countingWriter) writeString(s string) {
+	cw.write([]byte(s))
+}
+
+func (cw *countingWriter) uvarint(v uint64) {
+	n := binary.PutUvarint(cw.buf[:], v)
+	cw.write(cw.buf[:n])
+}
+
+func (cw *countingWriter) str(s string) {
+	cw.uvarint(uint64(len(s)))
+	cw.writeString(s)
+}
+
+type explorerReader struct {
+	r   *bufio.Reader
+	err error
+}
+
+func (br *explorerReader) uvarint() uint64 {
+	if br.err != nil {
+		return 0
+	}
+	v, err := binary.ReadUvarint(br.r)
+	if err != nil {
+		br.err = err
+	}
+	return v
+}
+
+// maxExplorerCount bounds the number of records a count may announce, so
+// that it fits in an int on 32-bit platforms.  Records are read one at a
+// time, so a corrupt count below it only makes the reader run out of input.
+const maxExplorerCount = math.MaxInt32
+
+// maxExplorerString bounds the length of a string field, which are tag
+// values like a game's Site or a player's name.
+const maxExplorerString = 1 << 12
+
+func (br *explorerReader) count() int {
+	v := br.uvarint()
+	if v > maxExplorerCount {
+		br.err = errors.New("count out of range")
+		return 0
+	}
+	return int(v)
+}
+
+func (br *explorerReader) str() string {
+	n := br.uvarint()
+	if br.err != nil || n == 0 {
+		return ""
+	}
+	if n > maxExplorerString {
+		br.err = fmt.Errorf("string length %d out of range", n)
+		return ""
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(br.r, b); err != nil {
+		br.err = err
+		return ""
+	}
+	return string(b)
+}
@@ -0,0 +1,244 @@
+package opening_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"strings"
+	"testing"
+
+	"github.com/barakmich/chess"
+	"github.com/barakmich/chess/opening"
+)
+
+const explorerPGN = `[Event "Rated Blitz game"]
+[Site "https://lichess.org/a"]
+[Date "2022.06.01"]
+[UTCTime "10:00:00"]
+[White "alice"]
+[Black "bob"]
+[Result "1-0"]
+[WhiteElo "2000"]
+[BlackElo "1800"]
+[TimeControl "300+3"]
+
+1. e4 e5 2. Nf3 Nc6 3. Bb5 a6 1-0
+
+[Event "Rated Bullet game"]
+[Site "https://lichess.org/b"]
+[Date "2022.06.02"]
+[UTCTime "09:00:00"]
+[White "carol"]
+[Black "dave"]
+[Result "1/2-1/2"]
+[WhiteElo "1500"]
+[BlackElo "1500"]
+[TimeControl "60+0"]
+
+1. Nf3 Nc6 2. e4 e5 3. Bc4 1/2-1/2
+
+[Event "Rated Classical game"]
+[Site "https://lichess.org/c"]
+[Date "2022.06.01"]
+[UTCTime "12:00:00"]
+[White "erin"]
+[Black "frank"]
+[Result "0-1"]
+[WhiteElo "2400"]
+[BlackElo "2200"]
+[TimeControl "1800+0"]
+
+1. d4 d5 0-1
+`
+
+func explorerPosition(t *testing.T, moves ...string) *chess.Position {
+	t.Helper()
+	g := chess.NewGame()
+	for _, m := range moves {
+		if err := g.MoveStr(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return g.Position()
+}
+
+func TestExplorer(t *testing.T) {
+	e := opening.NewExplorer(opening.ExplorerOptions{})
+	n, err := e.AddPGN(context.Background(), strings.NewReader(explorerPGN))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Fatalf("expected 3 games added but got %d", n)
+	}
+
+	r := e.Query(explorerPosition(t))
+	if r.White != 1 || r.Draws != 1 || r.Black != 1 {
+		t.Fatalf("expected +1 =1 -1 at the start but got %+v", r)
+	}
+	if len(r.Moves) != 3 {
+		t.Fatalf("expected 3 moves at the start but got %d", len(r.Moves))
+	}
+	if len(r.RecentGames) != 3 || r.RecentGames[0].ID != "https://lichess.org/b" || r.RecentGames[2].ID != "https://lichess.org/a" {
+		t.Fatalf("unexpected recent games %+v", r.RecentGames)
+	}
+
+	// both Ruy Lopez and the Nf3 game transpose into the same position
+	r = e.Query(explorerPosition(t, "e4", "e5", "Nf3", "Nc6"))
+	if r.White != 1 || r.Draws != 1 || r.Black != 0 {
+		t.Fatalf("expected +1 =1 -0 after the transposition but got %+v", r)
+	}
+	if len(r.Moves) != 2 {
+		t.Fatalf("expected 2 moves after the transposition but got %+v", r.Moves)
+	}
+	for _, m := range r.Moves {
+		switch m.Move.String() {
+		case "f1b5":
+			if m.White != 1 || m.AvgRating != 1900 {
+				t.Fatalf("unexpected Bb5 stats %+v", m)
+			}
+		case "f1c4":
+			if m.Draws != 1 || m.AvgRating != 1500 {
+				t.Fatalf("unexpected Bc4 stats %+v", m)
+			}
+		default:
+			t.Fatalf("unexpected move %s", m.Move)
+		}
+	}
+
+	r = e.Query(explorerPosition(t, "a4"))
+	if r.White+r.Draws+r.Black != 0 || len(r.Moves) != 0 {
+		t.Fatalf("expected an empty result for an unknown position but got %+v", r)
+	}
+}
+
+func TestExplorerFilters(t *testing.T) {
+	tests := []struct {
+		opts  opening.ExplorerOptions
+		games int
+	}{
+		{opening.ExplorerOptions{MinRating: 1800}, 2},
+		{opening.ExplorerOptions{MaxRating: 2000}, 2},
+		{opening.ExplorerOptions{MinRating: 1800, MaxRating: 2000}, 1},
+		{opening.ExplorerOptions{Speeds: []opening.Speed{opening.Bullet, opening.Classical}}, 2},
+		{opening.ExplorerOptions{Speeds: []opening.Speed{opening.Rapid}}, 0},
+	}
+	for _, test := range tests {
+		e := opening.NewExplorer(test.opts)
+		n, err := e.AddPGN(context.Background(), strings.NewReader(explorerPGN))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != test.games {
+			t.Fatalf("%+v: expected %d games but got %d", test.opts, test.games, n)
+		}
+	}
+
+	e := opening.NewExplorer(opening.ExplorerOptions{MaxPly: 1})
+	if _, err := e.AddPGN(context.Background(), strings.NewReader(explorerPGN)); err != nil {
+		t.Fatal(err)
+	}
+	if r := e.Query(explorerPosition(t, "e4")); len(r.Moves) != 0 {
+		t.Fatalf("expected no moves past MaxPly but got %+v", r.Moves)
+	}
+}
+
+func TestSpeedOf(t *testing.T) {
+	tests := map[string]opening.Speed{
+		"15+0":   opening.UltraBullet,
+		"60+0":   opening.Bullet,
+		"120+1":  opening.Bullet,
+		"180+0":  opening.Blitz,
+		"300+3":  opening.Blitz,
+		"600+5":  opening.Rapid,
+		"1800+0": opening.Classical,
+		"-":      opening.Correspondence,
+		"?":      opening.UnknownSpeed,
+	}
+	for tc, speed := range tests {
+		if s := opening.SpeedOf(tc); s != speed {
+			t.Fatalf("%s: expected %s but got %s", tc, speed, s)
+		}
+	}
+}
+
+func TestExplorerWriteRead(t *testing.T) {
+	e := opening.NewExplorer(opening.ExplorerOptions{RecentGames: 2})
+	if _, err := e.AddPGN(context.Background(), strings.NewReader(explorerPGN)); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	n, err := e.WriteTo(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(buf.Len()) {
+		t.Fatalf("WriteTo returned %d but wrote %d bytes", n, buf.Len())
+	}
+	e2, err := opening.ReadExplorer(bytes.NewReader(buf.Bytes()), opening.ExplorerOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, moves := range [][]string{nil, {"e4", "e5", "Nf3", "Nc6"}, {"d4", "d5"}} {
+		pos := explorerPosition(t, moves...)
+		want, got := e.Query(pos), e2.Query(pos)
+		if len(want.RecentGames) > 2 {
+			t.Fatalf("expected at most 2 recent games but got %d", len(want.RecentGames))
+		}
+		if !explorerResultsEqual(want, got) {
+			t.Fatalf("%v: expected %+v but got %+v", moves, want, got)
+		}
+	}
+	if _, err := opening.ReadExplorer(bytes.NewReader(buf.Bytes()[:buf.Len()-3]), opening.ExplorerOptions{}); err == nil {
+		t.Fatal("expected an error for a truncated file")
+	}
+}
+
+func TestReadExplorerCorrupt(t *testing.T) {
+	file := func(parts ...interface{}) []byte {
+		b := []byte("CHEX\x01")
+		for _, p := range parts {
+			switch p := p.(type) {
+			case []byte:
+				b = append(b, p...)
+			case uint64:
+				var buf [binary.MaxVarintLen64]byte
+				b = append(b, buf[:binary.PutUvarint(buf[:], p)]...)
+			}
+		}
+		return b
+	}
+	var zero uint64
+	for _, b := range [][]byte{
+		// a billion games in a file that ends
+		file(uint64(1e9)),
+		// more games than fit in an int on 32-bit platforms
+		file(uint64(1 << 40)),
+		// a game ID of four gigabytes
+		file(uint64(1), uint64(4e9)),
+		// a position, with its key and results, with a billion recent games
+		file(zero, uint64(1), make([]byte, 8), zero, zero, zero, uint64(1e9)),
+	} {
+		if _, err := opening.ReadExplorer(bytes.NewReader(b), opening.ExplorerOptions{}); err == nil {
+			t.Fatalf("expected an error reading %q", b)
+		}
+	}
+}
+
+func explorerResultsEqual(a, b *opening.ExplorerResult) bool {
+	if a.White != b.White || a.Draws != b.Draws || a.Black != b.Black ||
+		len(a.Moves) != len(b.Moves) || len(a.RecentGames) != len(b.RecentGames) {
+		return false
+	}
+	for i := range a.Moves {
+		if a.Moves[i] != b.Moves[i] {
+			return false
+		}
+	}
+	for i := range a.RecentGames {
+		if a.RecentGames[i] != b.RecentGames[i] {
+			return false
+		}
+	}
+	return true
+}
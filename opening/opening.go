@@ -2,8 +2,6 @@
 package opening
 
 import (
-	"bytes"
-
 	"github.com/barakmich/chess"
 )
 
@@ -33,7 +31,17 @@ func (o *Opening) PGN() string {
 // Game returns the opening as a game.
 func (o *Opening) Game() *chess.Game {
 	if o.game == nil {
-		o.game, _ = chess.NewGameFromPGN(bytes.NewBufferString(o.pgn))
+		g := chess.NewGame()
+		for _, s := range parseMoveList(o.pgn) {
+			m, err := g.Position().DecodeUCI(s)
+			if err != nil {
+				return nil
+			}
+			if err := g.Move(m); err != nil {
+				return nil
+			}
+		}
+		o.game = g
 	}
 	return o.game
 }
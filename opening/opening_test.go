@@ -2,13 +2,14 @@ package opening_test
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/barakmich/chess"
 	"github.com/barakmich/chess/opening"
 )
 
-func ExampleFind() {
+func ExampleBookECO_Find() {
 	g := chess.NewGame()
 	g.MoveStr("e4")
 	g.MoveStr("e6")
@@ -19,7 +20,7 @@ func ExampleFind() {
 	fmt.Println(o.Title())
 }
 
-func ExamplePossible() {
+func ExampleBookECO_Possible() {
 	g := chess.NewGame()
 	g.MoveStr("e4")
 	g.MoveStr("d5")
@@ -60,6 +61,143 @@ func TestPossible(t *testing.T) {
 	}
 }
 
+func TestOpeningGame(t *testing.T) {
+	g := chess.NewGame()
+	for _, s := range []string{"e4", "d5"} {
+		if err := g.MoveStr(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	o := opening.NewBookECO().Find(g.Moves())
+	game := o.Game()
+	if game == nil {
+		t.Fatalf("expected a game for %s", o.Title())
+	}
+	if game.Position().String() != g.Position().String() {
+		t.Fatalf("expected the game of %s to reach %s but got %s", o.Title(), g.Position(), game.Position())
+	}
+}
+
+func TestFindTransposition(t *testing.T) {
+	book := opening.NewBookECO()
+	var found []*opening.Opening
+	for _, line := range [][]string{{"d4", "Nf6", "c4", "e6"}, {"c4", "e6", "d4", "Nf6"}} {
+		g := chess.NewGame()
+		for _, m := range line {
+			if err := g.MoveStr(m); err != nil {
+				t.Fatal(err)
+			}
+		}
+		o := book.Find(g.Moves())
+		if o == nil {
+			t.Fatalf("%v: expected an opening", line)
+		}
+		found = append(found, o)
+	}
+	if found[0].Title() != found[1].Title() {
+		t.Fatalf("expected transposed lines to find the same opening but got %s and %s", found[0].Title(), found[1].Title())
+	}
+}
+
+func TestFindGameFromFEN(t *testing.T) {
+	// the French Defense after 1.e4 e6 2.d4 d5
+	g, err := chess.NewGameFromFEN("rnbqkbnr/ppp2ppp/4p3/3p4/3PP3/8/PPP2PPP/RNBQKBNR w KQkq d6 0 3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.MoveStr("e5"); err != nil {
+		t.Fatal(err)
+	}
+	book := opening.NewBookECO()
+	o := book.FindGame(g)
+	if o == nil || o.Title() != "French Defense: Advance Variation" {
+		t.Fatalf("expected the Advance Variation but got %v", o)
+	}
+	if n := len(book.PossibleGame(g)); n < 2 {
+		t.Fatalf("expected several Advance Variation lines but got %d", n)
+	}
+}
+
+func TestNewBookFromTSV(t *testing.T) {
+	tsv := "name\tpgn\nKing's Pawn\t1. e4\nOpen Game\t1. e4 e5\nItalian\t1. e4 e5 2. Nf3 Nc6 3. Bc4\n"
+	book, err := opening.NewBookFromTSV(strings.NewReader(tsv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := gameFromMoves(t, "e4", "e5", "Nf3", "Nc6", "Bc4", "Bc5")
+	if o := book.Find(g.Moves()); o == nil || o.Title() != "Italian" || o.Code() != "" {
+		t.Fatalf("expected the Italian but got %v", o)
+	}
+	if o := book.Find(g.Moves()); o.PGN() != "e2e4 e7e5 g1f3 b8c6 f1c4" {
+		t.Fatalf("unexpected line %q", o.PGN())
+	}
+
+	for _, bad := range []string{
+		"",
+		"eco\tpgn\nA00\t1. e4\n",
+		"name\tuci\nBad\te2e5\n",
+	} {
+		if _, err := opening.NewBookFromTSV(strings.NewReader(bad)); err == nil {
+			t.Fatalf("expected an error for %q", bad)
+		}
+	}
+}
+
+func TestDefaultBook(t *testing.T) {
+	if opening.DefaultBook() != opening.DefaultBook() {
+		t.Fatal("expected the default book to be shared")
+	}
+	g := gameFromMoves(t, "e4", "e6")
+	if o := opening.DefaultBook().Find(g.Moves()); o == nil || o.Title() != "French Defense" {
+		t.Fatalf("expected the French Defense but got %v", o)
+	}
+}
+
+func TestAddOpening(t *testing.T) {
+	book := opening.NewBookECO()
+	g := gameFromMoves(t, "e4", "e6", "d4", "d5", "e5", "c5", "c3", "Nc6", "Nf3")
+	if _, err := book.AddOpening("", "My French Advance", g.Moves()[:8]); err != nil {
+		t.Fatal(err)
+	}
+	if o := book.Find(g.Moves()); o == nil || o.Title() != "French Defense: Advance Variation, Paulsen Attack" {
+		t.Fatalf("expected the deeper book line but got %v", o)
+	}
+	if o := book.Find(g.Moves()[:8]); o == nil || o.Title() != "My French Advance" {
+		t.Fatalf("expected the custom line but got %v", o)
+	}
+	if o := book.Find(g.Moves()[:8]); o.Game() == nil || len(o.Game().Moves()) != 8 {
+		t.Fatalf("expected the custom line's game to have 8 moves")
+	}
+	e4 := gameFromMoves(t, "e4").Moves()[0]
+	if _, err := book.AddOpening("", "Illegal", []chess.Move{e4, e4}); err == nil {
+		t.Fatal("expected an error for an illegal line")
+	}
+}
+
+func TestPath(t *testing.T) {
+	book := opening.NewBookECO()
+	g := gameFromMoves(t, "e4", "e6", "d4", "d5", "e5", "c5")
+	var titles []string
+	for _, o := range book.Path(g) {
+		titles = append(titles, o.Title())
+	}
+	expected := []string{"King's Pawn", "French Defense", "French Defense: Normal Variation", "French Defense", "French Defense: Advance Variation"}
+	if strings.Join(titles, ", ") != strings.Join(expected, ", ") {
+		t.Fatalf("expected %v but got %v", expected, titles)
+	}
+}
+
+func gameFromMoves(t *testing.T, moves ...string) *chess.Game {
+	t.Helper()
+	g := chess.NewGame()
+	for _, m := range moves {
+		if err := g.MoveStr(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return g
+}
+
 func BenchmarkNewBookECO(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		opening.NewBookECO()
@@ -0,0 +1,362 @@
+package opening
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math/rand"
+	"sort"
+
+	"github.com/barakmich/chess"
+)
+
+// PolyglotEntry is a single record of a Polyglot book: a move for the
+// position with the given key.
+type PolyglotEntry struct {
+	Key    uint64
+	Move   uint16
+	Weight uint16
+	Learn  uint32
+}
+
+const polyglotEntrySize = 16
+
+// Polyglot is an opening book in the Polyglot .bin format, a list of moves
+// and weights sorted by the Zobrist key of their position.  Polyglot is safe
+// for concurrent use.
+type Polyglot struct {
+	entries []PolyglotEntry
+}
+
+// BookMove is a move from an opening book with its weight.
+type BookMove struct {
+	Move   chess.Move
+	Weight int
+}
+
+// NewPolyglot returns a book of the entries.
+func NewPolyglot(entries []PolyglotEntry) *Polyglot {
+	b := &Polyglot{entries: append([]PolyglotEntry(nil), entries...)}
+	sort.SliceStable(b.entries, func(i, j int) bool {
+		return b.entries[i].Key < b.entries[j].Key
+	})
+	return b
+}
+
+// ReadPolyglot reads a Polyglot .bin book.
+func ReadPolyglot(r io.Reader) (*Polyglot, error) {
+	br := bufio.NewReader(r)
+	var entries []PolyglotEntry
+	buf := make([]byte, polyglotEntrySize)
+	for {
+		if _, err := io.ReadFull(br, buf); err == io.EOF {
+			break
+		} else if err == io.ErrUnexpectedEOF {
+			return nil, errors.New("opening: polyglot book has a truncated entry")
+		} else if err != nil {
+			return nil, err
+		}
+		entries = append(entries, PolyglotEntry{
+			Key:    binary.BigEndian.Uint64(buf[0:8]),
+			Move:   binary.BigEndian.Uint16(buf[8:10]),
+			Weight: binary.BigEndian.Uint16(buf[10:12]),
+			Learn:  binary.BigEndian.Uint32(buf[12:16]),
+		})
+	}
+	return NewPolyglot(entries), nil
+}
+
+// WriteTo implements the io.WriterTo interface and writes the book in the
+// Polyglot .bin format.
+func (b *Polyglot) WriteTo(w io.Writer) (int64, error) {
+	bw := bufio.NewWriter(w)
+	buf := make([]byte, polyglotEntrySize)
+	var n int64
+	for _, e := range b.entries {
+		binary.BigEndian.PutUint64(buf[0:8], e.Key)
+		binary.BigEndian.PutUint16(buf[8:10], e.Move)
+		binary.BigEndian.PutUint16(buf[10:12], e.Weight)
+		binary.BigEndian.PutUint32(buf[12:16], e.Learn)
+		m, err := bw.Write(buf)
+		n += int64(m)
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, bw.Flush()
+}
+
+// Entries returns the entries of the book sorted by key.
+func (b *Polyglot) Entries() []PolyglotEntry {
+	return append([]PolyglotEntry(nil), b.entries...)
+}
+
+// Moves returns the legal book moves for the position, highest weight first.
+func (b *Polyglot) Moves(pos *chess.Position) []BookMove {
+	key := PolyglotKey(pos)
+	i := sort.Search(len(b.entries), func(i int) bool {
+		return b.entries[i].Key >= key
+	})
+	var moves []BookMove
+	for ; i < len(b.entries) && b.entries[i].Key == key; i++ {
+		if m, ok := decodePolyglotMove(pos, b.entries[i].Move); ok {
+			moves = append(moves, BookMove{Move: m, Weight: int(b.entries[i].Weight)})
+		}
+	}
+	sort.SliceStable(moves, func(i, j int) bool {
+		return moves[i].Weight > moves[j].Weight
+	})
+	return moves
+}
+
+// BestMove returns the book move with the highest weight for the position, or
+// false if the position isn't in the book.
+func (b *Polyglot) BestMove(pos *chess.Position) (chess.Move, bool) {
+	moves := b.Moves(pos)
+	if len(moves) == 0 {
+		return 0, false
+	}
+	return moves[0].Move, true
+}
+
+// RandomMove returns a book move for the position chosen at random with
+// probability proportional to its weight, or false if the position isn't in
+// the book.  If r is nil the math/rand default source is used.
+func (b *Polyglot) RandomMove(pos *chess.Position, r *rand.Rand) (chess.Move, bool) {
+	moves := b.Moves(pos)
+	total := 0
+	for _, m := range moves {
+		total += m.Weight
+	}
+	if total == 0 {
+		return 0, false
+	}
+	var n int
+	if r == nil {
+		n = rand.Intn(total)
+	} else {
+		n = r.Intn(total)
+	}
+	for _, m := range moves {
+		n -= m.Weight
+		if n < 0 {
+			return m.Move, true
+		}
+	}
+	return 0, false
+}
+
+// PolyglotKey returns the Zobrist key of the position as used by Polyglot
+// books.
+func PolyglotKey(pos *chess.Position) uint64 {
+	var key uint64
+	board := pos.Board()
+	for sq := chess.A1; sq <= chess.H8; sq++ {
+		p := board.Piece(sq)
+		if p == chess.NoPiece {
+			continue
+		}
+		key ^= polyglotRandom[64*polyglotPieceKind(p)+8*int(sq.Rank())+int(sq.File())]
+	}
+	cr := pos.CastleRights()
+	for i, c := range []struct {
+		color chess.Color
+		side  chess.Side
+	}{{chess.White, chess.KingSide}, {chess.White, chess.QueenSide}, {chess.Black, chess.KingSide}, {chess.Black, chess.QueenSide}} {
+		if cr.CanCastle(c.color, c.side) {
+			key ^= polyglotRandom[768+i]
+		}
+	}
+	if ep := pos.EnPassantSquare(); ep != chess.NoSquare && canCaptureEnPassant(pos, ep) {
+		key ^= polyglotRandom[772+int(ep.File())]
+	}
+	if pos.Turn() == chess.White {
+		key ^= polyglotRandom[780]
+	}
+	return key
+}
+
+// polyglotKinds orders the piece types as in the Random64 array.
+var polyglotKinds = map[chess.PieceType]int{
+	chess.Pawn:   0,
+	chess.Knight: 1,
+	chess.Bishop: 2,
+	chess.Rook:   3,
+	chess.Queen:  4,
+	chess.King:   5,
+}
+
+// polyglotPieceKind returns the piece's index in the Random64 array: black
+// pawn, white pawn, black knight and so on up to white king.
+func polyglotPieceKind(p chess.Piece) int {
+	kind := 2 * polyglotKinds[p.Type()]
+	if p.Color() == chess.White {
+		kind++
+	}
+	return kind
+}
+
+// canCaptureEnPassant returns true if a pawn of the side to move is next to
+// the pawn that just moved past ep.  Polyglot only hashes the en passant
+// file in that case.
+func canCaptureEnPassant(pos *chess.Position, ep chess.Square) bool {
+	pawn := chess.GetPiece(chess.Pawn, pos.Turn())
+	rank := chess.Rank5
+	if pos.Turn() == chess.Black {
+		rank = chess.Rank4
+	}
+	for _, f := range []int{int(ep.File()) - 1, int(ep.File()) + 1} {
+		if f < 0 || f > 7 {
+			continue
+		}
+		if pos.Board().Piece(chess.NewSquare(chess.File(f), rank)) == pawn {
+			return true
+		}
+	}
+	return false
+}
+
+var polyglotPromos = []chess.PromoType{chess.NoPromo, chess.PromoKnight, chess.PromoBishop, chess.PromoRook, chess.PromoQueen}
+
+// encodePolyglotMove encodes the move, with castling written as the king
+// capturing its own rook.
+func encodePolyglotMove(m chess.Move) uint16 {
+	s2 := m.S2()
+	if m.HasTag(chess.KingSideCastle) {
+		s2 = chess.NewSquare(chess.FileH, s2.Rank())
+	} else if m.HasTag(chess.QueenSideCastle) {
+		s2 = chess.NewSquare(chess.FileA, s2.Rank())
+	}
+	var promo uint16
+	for i, p := range polyglotPromos {
+		if m.Promo() == p {
+			promo = uint16(i)
+		}
+	}
+	return uint16(s2.File()) | uint16(s2.Rank())<<3 | uint16(m.S1().File())<<6 | uint16(m.S1().Rank())<<9 | promo<<12
+}
+
+// decodePolyglotMove returns the legal move in pos encoded by m.
+func decodePolyglotMove(pos *chess.Position, m uint16) (chess.Move, bool) {
+	s2 := chess.NewSquare(chess.File(m&7), chess.Rank(m>>3&7))
+	s1 := chess.NewSquare(chess.File(m>>6&7), chess.Rank(m>>9&7))
+	promoIdx := int(m >> 12 & 7)
+	if promoIdx >= len(polyglotPromos) {
+		return 0, false
+	}
+	promo := polyglotPromos[promoIdx]
+	for _, v := range pos.ValidMoves() {
+		if v.S1() != s1 || v.Promo() != promo {
+			continue
+		}
+		if v.S2() == s2 || encodePolyglotMove(v)&0xFFF == m&0xFFF {
+			return v, true
+		}
+	}
+	return 0, false
+}
+
+// PolyglotBuilder builds a Polyglot book from games.  Each move is weighted
+// by the results of the games it was played in, two points for a win and one
+// for a draw for the player who made it.
+type PolyglotBuilder struct {
+	// MinCount is the number of games a move must be played in to be
+	// included in the book.
+	MinCount int
+	// MaxPly is the number of moves from the start of each game that are
+	// added.  Zero means all of them.
+	MaxPly int
+
+	moves map[polyglotBookKey]*polyglotMoveStats
+}
+
+type polyglotBookKey struct {
+	key  uint64
+	move uint16
+}
+
+type polyglotMoveStats struct {
+	count int
+	score int
+}
+
+// NewPolyglotBuilder returns a builder that includes moves played at least
+// minCount times in the first maxPly moves of the games.
+func NewPolyglotBuilder(minCount, maxPly int) *PolyglotBuilder {
+	return &PolyglotBuilder{MinCount: minCount, MaxPly: maxPly, moves: map[polyglotBookKey]*polyglotMoveStats{}}
+}
+
+// AddGame adds the moves of the game to the book.
+func (b *PolyglotBuilder) AddGame(g *chess.Game) {
+	positions := g.Positions()
+	for i, m := range g.Moves() {
+		if b.MaxPly > 0 && i >= b.MaxPly {
+			break
+		}
+		pos := positions[i]
+		k := polyglotBookKey{key: PolyglotKey(pos), move: encodePolyglotMove(m)}
+		s, ok := b.moves[k]
+		if !ok {
+			s = &polyglotMoveStats{}
+			b.moves[k] = s
+		}
+		s.count++
+		switch g.Outcome() {
+		case chess.Draw:
+			s.score++
+		case chess.WhiteWon:
+			if pos.Turn() == chess.White {
+				s.score += 2
+			}
+		case chess.BlackWon:
+			if pos.Turn() == chess.Black {
+				s.score += 2
+			}
+		}
+	}
+}
+
+// AddPGN adds every game of the PGN collection to the book.
+func (b *PolyglotBuilder) AddPGN(r io.Reader) error {
+	scanner := chess.NewScanner(r)
+	for scanner.Scan() {
+		b.AddGame(scanner.Next())
+	}
+	if err := scanner.Err(); err != io.EOF {
+		return err
+	}
+	return nil
+}
+
+// Book returns the book of the moves added so far.  Weights are scaled down
+// to fit in 16 bits if needed, and moves whose weight is zero are left out.
+func (b *PolyglotBuilder) Book() *Polyglot {
+	max := 0
+	for _, s := range b.moves {
+		if s.count >= b.MinCount && s.score > max {
+			max = s.score
+		}
+	}
+	var entries []PolyglotEntry
+	for k, s := range b.moves {
+		if s.count < b.MinCount {
+			continue
+		}
+		weight := s.score
+		if max > 0xFFFF {
+			weight = int(int64(weight) * 0xFFFF / int64(max))
+		}
+		if weight == 0 {
+			continue
+		}
+		entries = append(entries, PolyglotEntry{Key: k.key, Move: k.move, Weight: uint16(weight)})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Key != entries[j].Key {
+			return entries[i].Key < entries[j].Key
+		}
+		return entries[i].Move < entries[j].Move
+	})
+	return NewPolyglot(entries)
+}
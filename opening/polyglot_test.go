@@ -0,0 +1,114 @@
+package opening_test
+
+import (
+	"bytes"
+	"math/rand"
+	"os"
+	"testing"
+
+	"github.com/barakmich/chess"
+	"github.com/barakmich/chess/opening"
+)
+
+// Test positions and keys from the Polyglot book format specification.
+var polyglotKeyTests = []struct {
+	Moves []string
+	Key   uint64
+}{
+	{nil, 0x463b96181691fc9c},
+	{[]string{"e4"}, 0x823c9b50fd114196},
+	{[]string{"e4", "d5"}, 0x0756b94461c50fb0},
+	{[]string{"e4", "d5", "e5"}, 0x662fafb965db29d4},
+	{[]string{"e4", "d5", "e5", "f5"}, 0x22a48b5a8e47ff78},
+	{[]string{"e4", "d5", "e5", "f5", "Ke2"}, 0x652a607ca3f242c1},
+	{[]string{"e4", "d5", "e5", "f5", "Ke2", "Kf7"}, 0x00fdd303c946bdd9},
+	{[]string{"a4", "b5", "h4", "b4", "c4"}, 0x3c8123ea7b067637},
+	{[]string{"a4", "b5", "h4", "b4", "c4", "bxc3", "Ra3"}, 0x5c3f9b829b279560},
+}
+
+func TestPolyglotKey(t *testing.T) {
+	for _, test := range polyglotKeyTests {
+		g := chess.NewGame()
+		for _, m := range test.Moves {
+			if err := g.MoveStr(m); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if key := opening.PolyglotKey(g.Position()); key != test.Key {
+			t.Fatalf("%v: expected key %016x but got %016x", test.Moves, test.Key, key)
+		}
+	}
+}
+
+func TestPolyglotBuilder(t *testing.T) {
+	f, err := os.Open("../fixtures/pgns/0006.pgn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	builder := opening.NewPolyglotBuilder(2, 8)
+	if err := builder.AddPGN(f); err != nil {
+		t.Fatal(err)
+	}
+	book := builder.Book()
+	if len(book.Entries()) == 0 {
+		t.Fatal("expected book entries")
+	}
+	buf := &bytes.Buffer{}
+	if _, err := book.WriteTo(buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 16*len(book.Entries()) {
+		t.Fatalf("expected 16 bytes per entry but wrote %d for %d entries", buf.Len(), len(book.Entries()))
+	}
+	read, err := opening.ReadPolyglot(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pos := chess.StartingPosition()
+	moves := read.Moves(pos)
+	if len(moves) == 0 {
+		t.Fatal("expected book moves for the starting position")
+	}
+	for i := 1; i < len(moves); i++ {
+		if moves[i].Weight > moves[i-1].Weight {
+			t.Fatalf("expected moves sorted by weight but got %v", moves)
+		}
+	}
+	best, ok := read.BestMove(pos)
+	if !ok || best != moves[0].Move {
+		t.Fatalf("expected best move %s but got %s", moves[0].Move, best)
+	}
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 10; i++ {
+		m, ok := read.RandomMove(pos, r)
+		if !ok || m == 0 {
+			t.Fatal("expected a random book move")
+		}
+	}
+}
+
+func TestPolyglotCastling(t *testing.T) {
+	g := chess.NewGame()
+	for _, m := range []string{"e4", "e5", "Nf3", "Nc6", "Bc4", "Bc5", "O-O"} {
+		if err := g.MoveStr(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	g.Resign(chess.Black)
+	builder := opening.NewPolyglotBuilder(1, 0)
+	builder.AddGame(g)
+	book := builder.Book()
+	castle, ok := book.BestMove(g.Positions()[6])
+	if !ok || !castle.HasTag(chess.KingSideCastle) {
+		t.Fatalf("expected O-O from the book but got %s", castle)
+	}
+	// castling is stored as the king taking its rook, e1h1
+	found := false
+	for _, e := range book.Entries() {
+		found = found || e.Move == 4<<6|7
+	}
+	if !found {
+		t.Fatal("expected castling to be encoded as e1h1")
+	}
+}
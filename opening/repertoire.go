@@ -0,0 +1,349 @@
+package opening
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/barakmich/chess"
+)
+
+// Repertoire is the moves one player has prepared, keyed by position so
+// transpositions between lines are recognized.  Positions where the player
+// is to move hold its prepared answers, and those where the opponent is to
+// move hold the replies the repertoire covers.  A Repertoire is safe for
+// concurrent reads once it's loaded.
+type Repertoire struct {
+	// Color is the side the repertoire is played with.
+	Color chess.Color
+
+	positions map[uint64]*repertoireNode
+	order     []uint64
+}
+
+type repertoireNode struct {
+	pos *chess.Position
+	// line is the first line that reached the position, from the start of
+	// its game.
+	line  []chess.Move
+	moves []chess.Move
+}
+
+// NewRepertoire returns an empty repertoire for the color.
+func NewRepertoire(color chess.Color) *Repertoire {
+	return &Repertoire{Color: color, positions: map[uint64]*repertoireNode{}}
+}
+
+// LoadRepertoire returns the repertoire for the color of every game in the
+// PGN collection, with their variations.
+func LoadRepertoire(r io.Reader, color chess.Color) (*Repertoire, error) {
+	rep := NewRepertoire(color)
+	scanner := chess.NewScanner(r)
+	for scanner.Scan() {
+		rep.AddGame(scanner.Next())
+	}
+	if err := scanner.Err(); err != io.EOF {
+		return nil, err
+	}
+	return rep, nil
+}
+
+// AddGame adds the game's main line and all of its variations.
+func (r *Repertoire) AddGame(g *chess.Game) {
+	start := g.Positions()[0]
+	for _, line := range g.Lines() {
+		pos := start
+		n := r.node(pos, nil)
+		for i, m := range line {
+			n.add(m)
+			pos = pos.Update(m)
+			n = r.node(pos, line[:i+1])
+		}
+	}
+}
+
+// Moves returns the moves of the repertoire in the position: the prepared
+// answers if the repertoire's color is to move, and the covered replies
+// otherwise.  It returns nil if the position isn't in the repertoire.
+func (r *Repertoire) Moves(pos *chess.Position) []chess.Move {
+	n, ok := r.positions[PolyglotKey(pos)]
+	if !ok {
+		return nil
+	}
+	return append([]chess.Move(nil), n.moves...)
+}
+
+// Contains returns true if the position is in the repertoire.
+func (r *Repertoire) Contains(pos *chess.Position) bool {
+	_, ok := r.positions[PolyglotKey(pos)]
+	return ok
+}
+
+func (r *Repertoire) node(pos *chess.Position, line []chess.Move) *repertoireNode {
+	key := PolyglotKey(pos)
+	n, ok := r.positions[key]
+	if !ok {
+		n = &repertoireNode{pos: pos, line: append([]chess.Move(nil), line...)}
+		r.positions[key] = n
+		r.order = append(r.order, key)
+	}
+	return n
+}
+
+func (n *repertoireNode) add(m chess.Move) {
+	if !containsMove(n.moves, m) {
+		n.moves = append(n.moves, m)
+	}
+}
+
+// answered returns true if the repertoire has an answer after the
+// opponent's move m in the node's position.
+func (r *Repertoire) answered(n *repertoireNode, m chess.Move) bool {
+	next, ok := r.positions[PolyglotKey(n.pos.Update(m))]
+	return ok && len(next.moves) > 0
+}
+
+func containsMove(moves []chess.Move, m chess.Move) bool {
+	for _, v := range moves {
+		if v.Eq(m) {
+			return true
+		}
+	}
+	return false
+}
+
+// MoveSource reports the moves played in positions, such as an Explorer built
+// from a database of games.
+type MoveSource interface {
+	Query(pos *chess.Position) *ExplorerResult
+}
+
+// Hole is an opponent reply that the repertoire has no answer to.
+type Hole struct {
+	// Line is the moves leading to the reply from the start of the game
+	// that first reached the position.
+	Line     []chess.Move
+	Position *chess.Position
+	Reply    chess.Move
+	// Games is the number of games the reply was played in.
+	Games int
+}
+
+// Holes returns the opponent replies played in at least minGames games of
+// the source that the repertoire has no answer to, most played first.
+func (r *Repertoire) Holes(src MoveSource, minGames int) []Hole {
+	var holes []Hole
+	for _, key := range r.order {
+		n := r.positions[key]
+		if n.pos.Turn() == r.Color || n.pos.Status() != chess.NoMethod {
+			continue
+		}
+		for _, m := range src.Query(n.pos).Moves {
+			if m.Games() < minGames || r.answered(n, m.Move) {
+				continue
+			}
+			holes = append(holes, Hole{Line: append([]chess.Move(nil), n.line...), Position: n.pos, Reply: m.Move, Games: m.Games()})
+		}
+	}
+	sort.SliceStable(holes, func(i, j int) bool {
+		return holes[i].Games > holes[j].Games
+	})
+	return holes
+}
+
+// Deviation is the first move of a game that left the repertoire.
+type Deviation struct {
+	// Ply is the index of the move in the game.
+	Ply  int
+	Move chess.Move
+	// Color is the side that deviated.  If it's the repertoire's color the
+	// player forgot its preparation, otherwise the opponent played a move
+	// the repertoire doesn't cover.
+	Color chess.Color
+	// Expected are the repertoire's moves in the position.
+	Expected []chess.Move
+}
+
+// Deviation returns the first move of the game that left the repertoire,
+// or nil if the game followed it until the preparation ran out.  Moves that
+// transpose to another position of the repertoire don't count as
+// deviations.
+func (r *Repertoire) Deviation(g *chess.Game) *Deviation {
+	positions := g.Positions()
+	for i, m := range g.Moves() {
+		n, ok := r.positions[PolyglotKey(positions[i])]
+		if !ok || len(n.moves) == 0 {
+			return nil
+		}
+		if containsMove(n.moves, m) || r.Contains(positions[i+1]) {
+			continue
+		}
+		return &Deviation{Ply: i, Move: m, Color: positions[i].Turn(), Expected: append([]chess.Move(nil), n.moves...)}
+	}
+	return nil
+}
+
+// CardState is the spaced repetition state of a drill.
+type CardState struct {
+	// Due is when the drill should next be reviewed.
+	Due      time.Time     `json:"due"`
+	Interval time.Duration `json:"interval"`
+	Ease     float64       `json:"ease"`
+	Reps     int           `json:"reps"`
+	Lapses   int           `json:"lapses"`
+}
+
+// Scheduler decides when a drill is reviewed next.
+type Scheduler interface {
+	// Schedule returns the state of a drill after it was answered at now.
+	// The zero CardState is a drill that was never reviewed.
+	Schedule(s CardState, correct bool, now time.Time) CardState
+}
+
+// SM2Scheduler is a Scheduler based on the SuperMemo 2 algorithm, with
+// correct answers graded as perfect recall.
+type SM2Scheduler struct{}
+
+const (
+	sm2InitialEase = 2.5
+	sm2MinEase     = 1.3
+	sm2Day         = 24 * time.Hour
+)
+
+// Schedule implements the Scheduler interface.
+func (SM2Scheduler) Schedule(s CardState, correct bool, now time.Time) CardState {
+	if s.Ease == 0 {
+		s.Ease = sm2InitialEase
+	}
+	if !correct {
+		s.Reps = 0
+		s.Lapses++
+		s.Ease = math.Max(sm2MinEase, s.Ease-0.2)
+		s.Interval = sm2Day
+	} else {
+		s.Reps++
+		switch s.Reps {
+		case 1:
+			s.Interval = sm2Day
+		case 2:
+			s.Interval = 6 * sm2Day
+		default:
+			s.Interval = time.Duration(float64(s.Interval) * s.Ease)
+		}
+		s.Ease += 0.1
+	}
+	s.Due = now.Add(s.Interval)
+	return s
+}
+
+// Drill is a position of the repertoire where the player must find a
+// prepared move.
+type Drill struct {
+	// Line is the moves leading to the position from the start of the game
+	// that first reached it.
+	Line     []chess.Move
+	Position *chess.Position
+	Answers  []chess.Move
+	State    CardState
+}
+
+// Trainer drills the positions of a repertoire where its color is to move.
+// Its review state can be saved and restored with encoding/json.
+type Trainer struct {
+	Repertoire *Repertoire
+	Scheduler  Scheduler
+
+	states map[uint64]CardState
+}
+
+// NewTrainer returns a trainer for the repertoire.  If s is nil the
+// SM2Scheduler is used.
+func NewTrainer(r *Repertoire, s Scheduler) *Trainer {
+	if s == nil {
+		s = SM2Scheduler{}
+	}
+	return &Trainer{Repertoire: r, Scheduler: s, states: map[uint64]CardState{}}
+}
+
+// Due returns the drills due for review at now: those reviewed before in
+// order of due time, followed by those never reviewed in repertoire order.
+func (t *Trainer) Due(now time.Time) []Drill {
+	var reviews, fresh []Drill
+	for _, key := range t.Repertoire.order {
+		n := t.Repertoire.positions[key]
+		if n.pos.Turn() != t.Repertoire.Color || len(n.moves) == 0 {
+			continue
+		}
+		s, ok := t.states[key]
+		if ok && s.Due.After(now) {
+			continue
+		}
+		d := Drill{
+			Line:     append([]chess.Move(nil), n.line...),
+			Position: n.pos,
+			Answers:  append([]chess.Move(nil), n.moves...),
+			State:    s,
+		}
+		if ok {
+			reviews = append(reviews, d)
+		} else {
+			fresh = append(fresh, d)
+		}
+	}
+	sort.SliceStable(reviews, func(i, j int) bool {
+		return reviews[i].State.Due.Before(reviews[j].State.Due)
+	})
+	return append(reviews, fresh...)
+}
+
+// Answer records the move played in the drill's position at now and returns
+// true if it's a prepared move.
+func (t *Trainer) Answer(pos *chess.Position, m chess.Move, now time.Time) (bool, error) {
+	key := PolyglotKey(pos)
+	n, ok := t.Repertoire.positions[key]
+	if !ok || pos.Turn() != t.Repertoire.Color || len(n.moves) == 0 {
+		return false, fmt.Errorf("opening: position %s isn't a drill of the repertoire", pos)
+	}
+	correct := containsMove(n.moves, m)
+	t.states[key] = t.Scheduler.Schedule(t.states[key], correct, now)
+	return correct, nil
+}
+
+// State returns the review state of the drill at the position, and false if
+// it was never reviewed.
+func (t *Trainer) State(pos *chess.Position) (CardState, bool) {
+	s, ok := t.states[PolyglotKey(pos)]
+	return s, ok
+}
+
+// MarshalJSON implements the json.Marshaler interface.  The review states
+// are keyed by the hex Polyglot key of their position.
+func (t *Trainer) MarshalJSON() ([]byte, error) {
+	states := make(map[string]CardState, len(t.states))
+	for key, s := range t.states {
+		states[fmt.Sprintf("%016x", key)] = s
+	}
+	return json.Marshal(states)
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface and replaces the
+// trainer's review states.
+func (t *Trainer) UnmarshalJSON(data []byte) error {
+	var states map[string]CardState
+	if err := json.Unmarshal(data, &states); err != nil {
+		return err
+	}
+	t.states = make(map[uint64]CardState, len(states))
+	for k, s := range states {
+		key, err := strconv.ParseUint(k, 16, 64)
+		if err != nil {
+			return fmt.Errorf("opening: invalid trainer position key %q", k)
+		}
+		t.states[key] = s
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package opening_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/barakmich/chess"
+	"github.com/barakmich/chess/opening"
+)
+
+const repertoirePGN = `[Event "White repertoire"]
+
+1. e4 e5 (1... c5 2. Nf3 d6 (2... Nc6 3. d4) 3. d4) 2. Nf3 Nc6 3. Bb5 *
+`
+
+func loadRepertoire(t *testing.T) *opening.Repertoire {
+	t.Helper()
+	r, err := opening.LoadRepertoire(strings.NewReader(repertoirePGN), chess.White)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestRepertoireMoves(t *testing.T) {
+	r := loadRepertoire(t)
+	if moves := r.Moves(explorerPosition(t, "e4")); len(moves) != 2 {
+		t.Fatalf("expected e5 and c5 to be covered but got %v", moves)
+	}
+	if moves := r.Moves(explorerPosition(t, "e4", "c5", "Nf3", "Nc6")); len(moves) != 1 || moves[0].String() != "d2d4" {
+		t.Fatalf("expected the nested variation's d4 but got %v", moves)
+	}
+	if r.Contains(explorerPosition(t, "d4")) {
+		t.Fatal("expected 1.d4 not to be in the repertoire")
+	}
+}
+
+func TestRepertoireHoles(t *testing.T) {
+	r := loadRepertoire(t)
+	e := opening.NewExplorer(opening.ExplorerOptions{})
+	if _, err := e.AddPGN(context.Background(), strings.NewReader(explorerPGN)); err != nil {
+		t.Fatal(err)
+	}
+	holes := r.Holes(e, 1)
+	if len(holes) != 1 || holes[0].Reply.String() != "a7a6" || len(holes[0].Line) != 5 || holes[0].Games != 1 {
+		t.Fatalf("expected 3...a6 to be the only hole but got %+v", holes)
+	}
+	if holes := r.Holes(e, 2); len(holes) != 0 {
+		t.Fatalf("expected no holes played in two games but got %+v", holes)
+	}
+}
+
+func TestRepertoireDeviation(t *testing.T) {
+	r := loadRepertoire(t)
+	tests := []struct {
+		moves []string
+		ply   int
+		color chess.Color
+	}{
+		{[]string{"e4", "e5", "Nf3", "Nc6", "Bc4"}, 4, chess.White},
+		{[]string{"e4", "e6", "d4"}, 1, chess.Black},
+		{[]string{"d4", "d5"}, 0, chess.White},
+	}
+	for _, test := range tests {
+		d := r.Deviation(gameFromMoves(t, test.moves...))
+		if d == nil || d.Ply != test.ply || d.Color != test.color {
+			t.Fatalf("%v: expected a deviation at ply %d by %s but got %+v", test.moves, test.ply, test.color, d)
+		}
+	}
+	if d := r.Deviation(gameFromMoves(t, "e4", "c5", "Nf3", "d6", "d4", "cxd4", "Nxd4")); d != nil {
+		t.Fatalf("expected no deviation once the preparation ran out but got %+v", d)
+	}
+	if d := r.Deviation(gameFromMoves(t, "e4", "e5", "Nf3", "Nc6", "Bb5", "a6")); d != nil {
+		t.Fatalf("expected no deviation for an uncovered position but got %+v", d)
+	}
+}
+
+func TestTrainer(t *testing.T) {
+	r := loadRepertoire(t)
+	tr := opening.NewTrainer(r, nil)
+	now := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+	drills := tr.Due(now)
+	if len(drills) != 6 {
+		t.Fatalf("expected 6 drills but got %d", len(drills))
+	}
+	start := drills[0].Position
+	if len(drills[0].Line) != 0 || drills[0].Answers[0].String() != "e2e4" {
+		t.Fatalf("expected the first drill to be 1.e4 but got %+v", drills[0])
+	}
+
+	if ok, err := tr.Answer(start, gameFromMoves(t, "e4").Moves()[0], now); err != nil || !ok {
+		t.Fatalf("expected 1.e4 to be correct but got %v, %v", ok, err)
+	}
+	if ok, err := tr.Answer(drills[1].Position, gameFromMoves(t, "d4").Moves()[0], now); err != nil || ok {
+		t.Fatalf("expected a wrong answer but got %v, %v", ok, err)
+	}
+	if _, err := tr.Answer(explorerPosition(t, "e4"), 0, now); err == nil {
+		t.Fatal("expected an error for a position where the opponent is to move")
+	}
+	if n := len(tr.Due(now)); n != 4 {
+		t.Fatalf("expected 4 drills due after two answers but got %d", n)
+	}
+	if d := tr.Due(now.Add(25 * time.Hour)); len(d) != 6 || len(d[0].Line) != 0 {
+		t.Fatalf("expected the reviewed drills first the next day but got %+v", d)
+	}
+
+	data, err := json.Marshal(tr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr2 := opening.NewTrainer(r, nil)
+	if err := json.Unmarshal(data, tr2); err != nil {
+		t.Fatal(err)
+	}
+	s, ok := tr2.State(start)
+	if !ok || s.Reps != 1 || s.Interval != 24*time.Hour || !s.Due.Equal(now.Add(24*time.Hour)) {
+		t.Fatalf("unexpected restored state %+v", s)
+	}
+	if s, _ := tr2.State(drills[1].Position); s.Lapses != 1 || s.Reps != 0 {
+		t.Fatalf("unexpected restored state %+v", s)
+	}
+}
+
+func TestSM2Scheduler(t *testing.T) {
+	now := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+	s := opening.CardState{}
+	for _, days := range []float64{1, 6, 6 * 2.7} {
+		s = opening.SM2Scheduler{}.Schedule(s, true, now)
+		if got := s.Interval.Hours() / 24; got < days-0.01 || got > days+0.01 {
+			t.Fatalf("expected an interval of %.1f days but got %.2f", days, got)
+		}
+	}
+	s = opening.SM2Scheduler{}.Schedule(s, false, now)
+	if s.Interval != 24*time.Hour || s.Reps != 0 || s.Lapses != 1 {
+		t.Fatalf("unexpected state after a lapse %+v", s)
+	}
+}
@@ -47,11 +47,14 @@ OUTER:
 				if s.err == nil {
 					s.err = io.EOF
 				}
+				if state == inMoves {
+					work <- sb.String()
+				}
 				break OUTER
 			}
 			line := strings.TrimSpace(s.scanr.Text())
 			isTagPair := strings.HasPrefix(line, "[")
-			isMoveSeq := strings.HasPrefix(line, "1. ")
+			isMoveSeq := line != "" && !isTagPair
 			switch state {
 			case notInPGN:
 				if !isTagPair {
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"regexp"
+	"sort"
 	"strings"
 )
 
@@ -60,11 +61,14 @@ func (s *Scanner) Scan() bool {
 			if s.err == nil {
 				s.err = io.EOF
 			}
+			if state == notInPGN {
+				return false
+			}
 			return setGame()
 		}
 		line := strings.TrimSpace(s.scanr.Text())
 		isTagPair := strings.HasPrefix(line, "[")
-		isMoveSeq := strings.HasPrefix(line, "1. ")
+		isMoveSeq := line != "" && !isTagPair
 		switch state {
 		case notInPGN:
 			if !isTagPair {
@@ -103,9 +107,19 @@ func decodePGN(pgn string, debug bool) (*Game, error) {
 	moveComments, outcome := moveListWithComments(pgn)
 	var g *Game
 	var err error
+	variant := Standard
+	for _, tp := range tagPairs {
+		if strings.ToLower(tp.Key) == "variant" {
+			// games of unsupported variants are decoded as standard
+			// chess, keeping their Variant tag
+			if v, ok := VariantByName(tp.Value); ok {
+				variant = v
+			}
+		}
+	}
 	for _, tp := range tagPairs {
 		if strings.ToLower(tp.Key) == "fen" {
-			g, err = NewGameFromFEN(tp.Value)
+			g, err = NewVariantGameFromFEN(variant, tp.Value)
 			if err != nil {
 				return nil, fmt.Errorf("chess: pgn decode error %s on tag %s", err.Error(), tp.Key)
 			}
@@ -113,7 +127,7 @@ func decodePGN(pgn string, debug bool) (*Game, error) {
 		}
 	}
 	if g == nil {
-		g = NewGame()
+		g = NewVariantGame(variant)
 	}
 	for _, t := range tagPairs {
 		g.AddTagPair(t.Key, t.Value)
@@ -139,30 +153,141 @@ func decodePGN(pgn string, debug bool) (*Game, error) {
 		if err := g.Move(m); err != nil {
 			return nil, fmt.Errorf("chess: pgn invalid move error %s on move %d", err.Error(), g.Position().moveCount)
 		}
-		//TODO(barakmich): reinstate Comments
+		if len(move.Comments) == 0 && len(move.NAGs) == 0 && len(move.Variations) == 0 {
+			continue
+		}
+		ply := len(g.moves) - 1
+		a := g.annotationsAt(ply)
+		a.comments = move.Comments
+		a.nags = move.NAGs
+		for _, v := range move.Variations {
+			line, err := decodeVariation(g.positions[ply], v)
+			if err != nil {
+				return nil, fmt.Errorf("chess: pgn variation error %s on move %d", err.Error(), g.positions[ply].moveCount)
+			}
+			a.variations = append(a.variations, line)
+		}
 	}
 	g.outcome = outcome
+	termination := ""
+	if tp := g.GetTagPair("Termination"); tp != nil {
+		termination = tp.Value
+	}
+	g.method = methodFromTermination(g, outcome, termination)
 	return g, nil
 }
 
+// decodeVariation decodes the moves of a variation played from start,
+// along with the variations nested inside it.  Comments and NAGs inside
+// variations are dropped.
+func decodeVariation(start *Position, moves []moveWithComment) (variation, error) {
+	v := variation{moves: make([]Move, 0, len(moves))}
+	pos := start
+	for i, move := range moves {
+		m, err := parseSAN(move.MoveStr, pos)
+		if err != nil {
+			return variation{}, err
+		}
+		for _, sub := range move.Variations {
+			nested, err := decodeVariation(pos, sub)
+			if err != nil {
+				return variation{}, err
+			}
+			if v.variations == nil {
+				v.variations = map[int][]variation{}
+			}
+			v.variations[i] = append(v.variations[i], nested)
+		}
+		v.moves = append(v.moves, m)
+		pos = pos.Update(m)
+	}
+	line, err := validLine(start, v.moves)
+	v.moves = line
+	return v, err
+}
+
 func encodePGN(g *Game) string {
-	s := ""
-	for k, v := range g.tagPairs {
-		s += fmt.Sprintf("[%s \"%s\"]\n", k, v)
+	var sb strings.Builder
+	for _, k := range tagPairOrder(g.tagPairs) {
+		fmt.Fprintf(&sb, "[%s \"%s\"]\n", k, g.tagPairs[k])
 	}
-	s += "\n"
+	sb.WriteString("\n")
+	interrupted := true
 	for i, move := range g.moves {
 		pos := g.positions[i]
-		txt := pos.EncodeMove(move, g.Notation)
-		if i%2 == 0 {
-			s += fmt.Sprintf("%d. %s", (i/2)+1, txt)
-		} else {
-			s += fmt.Sprintf(" %s ", txt)
+		writeMoveNumber(&sb, pos, interrupted)
+		sb.WriteString(pos.EncodeMove(move, g.Notation))
+		a := g.annotation(i)
+		for _, nag := range a.nags {
+			sb.WriteString(" " + nag.String())
+		}
+		for _, c := range a.comments {
+			sb.WriteString(" { " + c + " }")
+		}
+		for _, v := range a.variations {
+			sb.WriteString(" (")
+			writeLine(&sb, pos, v, g.Notation)
+			sb.WriteString(")")
+		}
+		sb.WriteString(" ")
+		interrupted = len(a.comments) != 0 || len(a.variations) != 0
+	}
+	sb.WriteString(string(g.outcome))
+	return sb.String()
+}
+
+// sevenTagRoster is the order of the tags every PGN game should have.
+var sevenTagRoster = []string{"Event", "Site", "Date", "Round", "White", "Black", "Result"}
+
+// tagPairOrder returns the keys of the tag pairs with the Seven Tag Roster
+// first and the rest sorted.
+func tagPairOrder(tagPairs map[string]string) []string {
+	keys := make([]string, 0, len(tagPairs))
+	for _, k := range sevenTagRoster {
+		if _, ok := tagPairs[k]; ok {
+			keys = append(keys, k)
 		}
-		//TODO(barakmich): reinstate comments
 	}
-	s += " " + string(g.outcome)
-	return s
+	rest := len(keys)
+	for k := range tagPairs {
+		isRoster := false
+		for _, r := range sevenTagRoster {
+			isRoster = isRoster || k == r
+		}
+		if !isRoster {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys[rest:])
+	return keys
+}
+
+// writeMoveNumber writes the move number before a move by White, or before
+// a move by Black that doesn't directly follow White's move.
+func writeMoveNumber(sb *strings.Builder, pos *Position, interrupted bool) {
+	if pos.Turn() == White {
+		fmt.Fprintf(sb, "%d. ", pos.moveCount)
+	} else if interrupted {
+		fmt.Fprintf(sb, "%d... ", pos.moveCount)
+	}
+}
+
+func writeLine(sb *strings.Builder, pos *Position, v variation, n Notation) {
+	interrupted := true
+	for i, m := range v.moves {
+		if i > 0 {
+			sb.WriteString(" ")
+		}
+		writeMoveNumber(sb, pos, interrupted)
+		sb.WriteString(pos.EncodeMove(m, n))
+		for _, sub := range v.variations[i] {
+			sb.WriteString(" (")
+			writeLine(sb, pos, sub, n)
+			sb.WriteString(")")
+		}
+		interrupted = len(v.variations[i]) != 0
+		pos = pos.Update(m)
+	}
 }
 
 var (
@@ -186,47 +311,151 @@ func getTagPairs(pgn string) []*TagPair {
 }
 
 type moveWithComment struct {
-	MoveStr  string
-	Comments []string
+	MoveStr    string
+	Comments   []string
+	NAGs       []NAG
+	Variations [][]moveWithComment
 }
 
-var moveListTokenRe = regexp.MustCompile(`(?:\d+\.)|(O-O(?:-O)?(?:\+|#)?|\w*[abcdefgh][12345678]\w*(?:=[QRBN])?(?:\+|#)?)|(?:\{([^}]*)\})|(?:\([^)]*\))|(\*|0-1|1-0|1\/2-1\/2)`)
-
 func moveListWithComments(pgn string) ([]moveWithComment, Outcome) {
-	pgn = stripTagPairs(pgn)
-	var outcome Outcome
-	moves := []moveWithComment{}
+	p := &moveTextParser{s: pgn}
+	return p.parseLine(0)
+}
 
-	for _, match := range moveListTokenRe.FindAllStringSubmatch(pgn, -1) {
-		move, commentText, outcomeText := match[1], match[2], match[3]
-		if len(move+commentText+outcomeText) == 0 {
-			continue
-		}
+// moveTextParser splits PGN movetext into moves along with their
+// comments, NAGs and (possibly nested) variations.  Tag pairs are skipped.
+type moveTextParser struct {
+	s string
+	i int
+}
 
-		if outcomeText != "" {
-			outcome = Outcome(outcomeText)
-			break
-		}
+func isMoveTextDelim(c byte) bool {
+	switch c {
+	case ' ', '\t', '\n', '\r', '{', '}', '(', ')', ';', '$', '[':
+		return true
+	}
+	return false
+}
 
-		if commentText != "" {
-			moves[len(moves)-1].Comments = append(moves[len(moves)-1].Comments, strings.TrimSpace(commentText))
+// parseLine parses moves until the end of the variation at the given depth,
+// or the end of the game at depth zero.
+func (p *moveTextParser) parseLine(depth int) ([]moveWithComment, Outcome) {
+	moves := []moveWithComment{}
+	last := func() *moveWithComment {
+		if len(moves) == 0 {
+			return nil
 		}
-
-		if move != "" {
-			moves = append(moves, moveWithComment{MoveStr: move})
+		return &moves[len(moves)-1]
+	}
+	for p.i < len(p.s) {
+		c := p.s[p.i]
+		switch c {
+		case ' ', '\t', '\n', '\r':
+			p.i++
+		case '{', ';':
+			end := byte('}')
+			if c == ';' {
+				end = '\n'
+			}
+			p.i++
+			n := strings.IndexByte(p.s[p.i:], end)
+			if n < 0 {
+				n = len(p.s) - p.i
+			}
+			comment := strings.TrimSpace(p.s[p.i : p.i+n])
+			p.i += n + 1
+			if m := last(); m != nil && comment != "" {
+				m.Comments = append(m.Comments, comment)
+			}
+		case '(':
+			p.i++
+			variation, _ := p.parseLine(depth + 1)
+			if m := last(); m != nil && len(variation) > 0 {
+				m.Variations = append(m.Variations, variation)
+			}
+		case ')':
+			p.i++
+			if depth > 0 {
+				return moves, NoOutcome
+			}
+		case '$':
+			p.i++
+			n := 0
+			for p.i < len(p.s) && p.s[p.i] >= '0' && p.s[p.i] <= '9' {
+				n = n*10 + int(p.s[p.i]-'0')
+				p.i++
+			}
+			if m := last(); m != nil && n < 256 {
+				m.NAGs = append(m.NAGs, NAG(n))
+			}
+		case '[':
+			p.skipTagPair()
+		default:
+			start := p.i
+			for p.i < len(p.s) && !isMoveTextDelim(p.s[p.i]) {
+				p.i++
+			}
+			if p.i == start {
+				// a stray closing brace
+				p.i++
+				continue
+			}
+			tok := p.s[start:p.i]
+			switch tok {
+			case string(NoOutcome), string(WhiteWon), string(BlackWon), string(Draw):
+				if depth == 0 {
+					return moves, Outcome(tok)
+				}
+				continue
+			}
+			tok = stripMoveNumber(tok)
+			if tok == "" || tok == "--" || tok == "e.p." {
+				continue
+			}
+			mv := moveWithComment{MoveStr: tok}
+			if n := strings.IndexAny(tok, "!?"); n > 0 {
+				mv.MoveStr = tok[:n]
+				if nag, ok := nagSuffixes[tok[n:]]; ok {
+					mv.NAGs = append(mv.NAGs, nag)
+				}
+			}
+			moves = append(moves, mv)
 		}
 	}
-	return moves, outcome
+	return moves, NoOutcome
 }
 
-func stripTagPairs(pgn string) string {
-	lines := strings.Split(pgn, "\n")
-	cp := []string{}
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line != "" && !strings.HasPrefix(line, "[") {
-			cp = append(cp, line)
+// skipTagPair skips a [Key "Value"] tag pair, allowing for ] inside the
+// quoted value.
+func (p *moveTextParser) skipTagPair() {
+	quoted := false
+	for p.i++; p.i < len(p.s); p.i++ {
+		switch p.s[p.i] {
+		case '\\':
+			p.i++
+		case '"':
+			quoted = !quoted
+		case ']':
+			if !quoted {
+				p.i++
+				return
+			}
 		}
 	}
-	return strings.Join(cp, "\n")
+}
+
+// stripMoveNumber removes a leading move number such as "12." or "12..."
+// from a movetext token.
+func stripMoveNumber(tok string) string {
+	n := 0
+	for n < len(tok) && tok[n] >= '0' && tok[n] <= '9' {
+		n++
+	}
+	if n == len(tok) {
+		return ""
+	}
+	if n == 0 || tok[n] != '.' {
+		return tok
+	}
+	return strings.TrimLeft(tok[n:], ".")
 }
@@ -93,47 +93,121 @@ var (
 	}
 )
 
-//func TestCommentsDetection(t *testing.T) {
-//for _, test := range commentTests {
-//game, err := decodePGN(test.PGN)
-//if err != nil {
-//t.Fatal(err)
-//}
-//comment := strings.Join(game.Comments()[test.MoveNumber], " ")
-//if comment != test.CommentText {
-//t.Fatalf("expected pgn comment to be %s but got %s", test.CommentText, comment)
-//}
-//}
-//}
-
-//func TestNewGameComments(t *testing.T) {
-//for _, test := range commentTests {
-//pgn, err := PGN(strings.NewReader(test.PGN))
-//if err != nil {
-//t.Fatal(err)
-//}
-//game := NewGame(pgn)
-//comment := strings.Join(game.Comments()[test.MoveNumber], " ")
-//if comment != test.CommentText {
-//t.Fatalf("expected pgn comment to be %s but got %s", test.CommentText, comment)
-//}
-//}
-//}
-
-//func TestWriteComments(t *testing.T) {
-//pgn := mustParsePGN("fixtures/pgns/0005.pgn")
-//game, err := decodePGN(pgn)
-//if err != nil {
-//t.Fatal(err)
-//}
-//game, err = decodePGN(game.String())
-//if err != nil {
-//t.Fatal(err)
-//}
-//if len(game.Comments()[7]) != 2 {
-//t.Fatalf("expected %d comments for move 7 but got %d", 2, len(game.Comments()[7]))
-//}
-//}
+func TestCommentsDetection(t *testing.T) {
+	for _, test := range commentTests {
+		game, err := decodePGN(test.PGN, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		comment := strings.Join(game.Comments()[test.MoveNumber], " ")
+		if comment != test.CommentText {
+			t.Fatalf("expected pgn comment to be %s but got %s", test.CommentText, comment)
+		}
+	}
+}
+
+func TestNewGameComments(t *testing.T) {
+	for _, test := range commentTests {
+		game, err := NewGameFromPGN(strings.NewReader(test.PGN))
+		if err != nil {
+			t.Fatal(err)
+		}
+		comment := strings.Join(game.Comments()[test.MoveNumber], " ")
+		if comment != test.CommentText {
+			t.Fatalf("expected pgn comment to be %s but got %s", test.CommentText, comment)
+		}
+	}
+}
+
+func TestWriteComments(t *testing.T) {
+	pgn := mustParsePGN("fixtures/pgns/0005.pgn")
+	game, err := decodePGN(pgn, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	game, err = decodePGN(game.String(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(game.Comments()[7]) != 2 {
+		t.Fatalf("expected %d comments for move 7 but got %d", 2, len(game.Comments()[7]))
+	}
+}
+
+func TestAnnotations(t *testing.T) {
+	pgn := mustParsePGN("fixtures/pgns/0005.pgn")
+	game, err := decodePGN(pgn, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if nags := game.NAGs()[7]; len(nags) != 1 || nags[0] != DubiousMove {
+			t.Fatalf("expected ?! on move 7 but got %v", nags)
+		}
+		if nags := game.NAGs()[8]; len(nags) != 1 || nags[0] != BlunderMove {
+			t.Fatalf("expected ?? on move 8 but got %v", nags)
+		}
+		variations := game.Variations(7)
+		if len(variations) != 1 || len(variations[0]) != 10 {
+			t.Fatalf("expected a ten move variation on move 7 but got %v", variations)
+		}
+		if s := variations[0][0].String(); s != "c5d4" {
+			t.Fatalf("expected variation to start with c5d4 but got %s", s)
+		}
+		game, err = decodePGN(game.String(), false)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestNestedVariations(t *testing.T) {
+	pgn := `1. e4 e5 (1... c5 2. Nf3 (2. c3 d5) 2... d6 $2) 2. Nf3 ; a line comment
+2... Nc6!? 3. Bb5 *`
+	game, err := decodePGN(pgn, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(game.Moves()) != 5 {
+		t.Fatalf("expected 5 moves but got %d", len(game.Moves()))
+	}
+	if v := game.Variations(1); len(v) != 1 || len(v[0]) != 3 {
+		t.Fatalf("expected one three move variation but got %v", v)
+	}
+	if c := game.Comments()[2]; len(c) != 1 || c[0] != "a line comment" {
+		t.Fatalf("expected a line comment but got %v", c)
+	}
+	if nags := game.NAGs()[3]; len(nags) != 1 || nags[0] != SpeculativeMove {
+		t.Fatalf("expected !? but got %v", nags)
+	}
+	lines := game.Lines()
+	if len(lines) != 3 || len(lines[1]) != 4 || len(lines[2]) != 4 || lines[2][3].String() != "d7d5" {
+		t.Fatalf("expected the main line and two variations but got %v", lines)
+	}
+	if s := game.String(); !strings.Contains(s, "(1... c5 2. Nf3 (2. c3 d5) 2... d6)") {
+		t.Fatalf("expected the nested variation to be written but got %q", s)
+	}
+}
+
+func TestAddVariation(t *testing.T) {
+	game := NewGame()
+	if err := game.MoveStr("e4"); err != nil {
+		t.Fatal(err)
+	}
+	d4 := NewMove(D2, D4, NoPromo)
+	if err := game.AddVariation(0, []Move{d4, NewMove(D7, D5, NoPromo)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := game.AddVariation(0, []Move{NewMove(D2, D5, NoPromo)}); err == nil {
+		t.Fatal("expected an error for an illegal variation")
+	}
+	if err := game.AddComment(1, "no such move"); err == nil {
+		t.Fatal("expected an error for a comment on a missing move")
+	}
+	if s := game.String(); s != "\n1. e4 (1. d4 d5) *" {
+		t.Fatalf("unexpected pgn %q", s)
+	}
+}
 
 func TestScanner(t *testing.T) {
 	for _, fname := range []string{"fixtures/pgns/0006.pgn", "fixtures/pgns/0007.pgn"} {
@@ -154,6 +228,90 @@ func TestScanner(t *testing.T) {
 	}
 }
 
+func TestEncodePGNTagOrder(t *testing.T) {
+	g := NewGame()
+	for _, k := range []string{"TimeControl", "Result", "White", "ECO", "Event", "Black", "Site"} {
+		g.AddTagPair(k, "?")
+	}
+	want := []string{"Event", "Site", "White", "Black", "Result", "ECO", "TimeControl"}
+	// map iteration order is random, so encode a few times
+	for i := 0; i < 10; i++ {
+		var keys []string
+		for _, line := range strings.Split(g.String(), "\n") {
+			if strings.HasPrefix(line, "[") {
+				keys = append(keys, strings.Fields(line[1:])[0])
+			}
+		}
+		if strings.Join(keys, " ") != strings.Join(want, " ") {
+			t.Fatalf("expected tag pairs in the order %v but got %v", want, keys)
+		}
+	}
+}
+
+// A game without moves, like one abandoned on lichess before the first
+// move, has only a result for its move text.  It used to be merged with the
+// following game, losing that game's moves and result.
+func TestScannerMovelessGame(t *testing.T) {
+	pgn := `[Site "https://lichess.org/vs6I8tWu"]
+[Result "0-1"]
+
+0-1
+
+[Site "https://lichess.org/6OaA2QVm"]
+[Result "1-0"]
+
+1. f3 e5 2. g4 Qh4# 0-1
+
+[Site "https://lichess.org/O9kjEPeF"]
+[Result "1-0"]
+
+1. e4 e5 1-0
+`
+	want := []struct {
+		site    string
+		outcome Outcome
+		moves   int
+	}{
+		{"https://lichess.org/vs6I8tWu", BlackWon, 0},
+		{"https://lichess.org/6OaA2QVm", BlackWon, 4},
+		{"https://lichess.org/O9kjEPeF", WhiteWon, 2},
+	}
+	scanner := NewScanner(strings.NewReader(pgn))
+	n := 0
+	for scanner.Scan() {
+		g := scanner.Next()
+		if n >= len(want) {
+			t.Fatalf("expected %d games but got more", len(want))
+		}
+		w := want[n]
+		if site := g.GetTagPair("Site"); site == nil || site.Value != w.site {
+			t.Fatalf("game %d: expected site %s but got %v", n, w.site, site)
+		}
+		if g.Outcome() != w.outcome || len(g.Moves()) != w.moves {
+			t.Fatalf("game %d: expected %s in %d moves but got %s in %d", n, w.outcome, w.moves, g.Outcome(), len(g.Moves()))
+		}
+		n++
+	}
+	if n != len(want) {
+		t.Fatalf("expected %d games but got %d", len(want), n)
+	}
+}
+
+func TestScannerTrailingBlankLines(t *testing.T) {
+	pgn := "[Event \"a\"]\n\n1. e4 e5 *\n\n[Event \"b\"]\n\n1. d4 d5 *\n\n\n"
+	scanner := NewScanner(strings.NewReader(pgn))
+	n := 0
+	for scanner.Scan() {
+		if len(scanner.Next().Moves()) != 2 {
+			t.Fatalf("game %d: expected 2 moves but got %d", n, len(scanner.Next().Moves()))
+		}
+		n++
+	}
+	if n != 2 {
+		t.Fatalf("expected 2 games but got %d", n)
+	}
+}
+
 func BenchmarkPGN(b *testing.B) {
 	pgn := mustParsePGN("fixtures/pgns/0001.pgn")
 	b.ResetTimer()
@@ -83,11 +83,17 @@ const (
 	PromoBishop
 	// Knight represents a knight
 	PromoKnight
+	// PromoKing represents a king, which pawns only promote to in
+	// Antichess
+	PromoKing
 )
 
 func (promo PromoType) PieceType() PieceType {
-	if promo == NoPromo {
+	switch promo {
+	case NoPromo:
 		return NoPieceType
+	case PromoKing:
+		return King
 	}
 	return PieceType(promo)
 }
@@ -102,6 +108,8 @@ func promoFromPieceType(p PieceType) PromoType {
 		return PromoKnight
 	case Bishop:
 		return PromoBishop
+	case King:
+		return PromoKing
 	}
 	return NoPromo
 }
@@ -53,6 +53,15 @@ type Position struct {
 	moveCount       int
 	inCheck         bool
 	validMoves      []Move
+	variant         Variant
+	// checks is the number of checks given by each color, which is
+	// only kept for Three-check.
+	checks [2]int
+	// pockets are the pieces of each type each color can drop, and
+	// promoted the promoted pieces on the board, which are only kept
+	// for Crazyhouse.
+	pockets  [2][6]uint8
+	promoted bitboard
 }
 
 func NewPosition(board *Board, turn Color, castle CastleRights, epSquare Square) *Position {
@@ -106,7 +115,7 @@ func (pos *Position) Update(m Move) *Position {
 	newBoard := &Board{}
 	pos.board.copyInto(newBoard)
 	newBoard.update(m)
-	return &Position{
+	next := &Position{
 		board:           newBoard,
 		turn:            pos.turn.Other(),
 		castleRights:    ncr,
@@ -114,7 +123,13 @@ func (pos *Position) Update(m Move) *Position {
 		halfMoveClock:   halfMove,
 		moveCount:       moveCount,
 		inCheck:         m.HasTag(Check),
+		variant:         pos.variant,
+		checks:          pos.checks,
+		pockets:         pos.pockets,
+		promoted:        pos.promoted,
 	}
+	pos.Variant().update(pos, next, m)
+	return next
 }
 
 // ValidMoves returns a list of valid moves for the position.
@@ -125,14 +140,33 @@ func (pos *Position) ValidMoves() []Move {
 
 func (pos *Position) ensureValidMoves() {
 	if pos.validMoves == nil {
-		pos.validMoves = engine{}.CalcMoves(pos, false)
+		pos.validMoves = pos.Variant().moves(pos, false)
 	}
 }
 
 // Status returns the position's status as one of the outcome methods.
-// Possible returns values include Checkmate, Stalemate, and NoMethod.
+// Possible returns values include Checkmate, Stalemate, and NoMethod,
+// along with VariantWin and VariantDraw for the endings of variants.
 func (pos *Position) Status() Method {
-	return engine{}.Status(pos)
+	_, method := pos.Variant().result(pos)
+	return method
+}
+
+// Variant returns the variant whose rules the position follows.
+func (pos *Position) Variant() Variant {
+	if pos.variant == nil {
+		return Standard
+	}
+	return pos.variant
+}
+
+// Checks returns the number of checks the color has given in a
+// Three-check game.
+func (pos *Position) Checks(c Color) int {
+	if c != White && c != Black {
+		return 0
+	}
+	return pos.checks[c]
 }
 
 // Board returns the position's board.
@@ -145,6 +179,11 @@ func (pos *Position) Turn() Color {
 	return pos.turn
 }
 
+// InCheck returns true if the king of the color to move is in check.
+func (pos *Position) InCheck() bool {
+	return pos.inCheck
+}
+
 func (pos *Position) MoveCount() int {
 	return pos.moveCount
 }
@@ -166,13 +205,20 @@ func (pos *Position) CastleRights() CastleRights {
 // string with the FEN format: rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1
 func (pos *Position) String() string {
 	b := pos.board.String()
+	if pos.Variant() == Crazyhouse {
+		b = crazyhouseBoardFEN(pos)
+	}
 	t := pos.turn.String()
 	c := pos.castleRights.String()
 	sq := "-"
 	if pos.enPassantSquare != NoSquare {
 		sq = pos.enPassantSquare.String()
 	}
-	return fmt.Sprintf("%s %s %s %s %d %d", b, t, c, sq, pos.halfMoveClock, pos.moveCount)
+	fen := fmt.Sprintf("%s %s %s %s %d %d", b, t, c, sq, pos.halfMoveClock, pos.moveCount)
+	if pos.Variant() == ThreeCheck {
+		fen += fmt.Sprintf(" +%d+%d", pos.checks[White], pos.checks[Black])
+	}
+	return fen
 }
 
 // Hash returns a unique hash of the position
@@ -188,9 +234,9 @@ func (pos *Position) MarshalText() (text []byte, err error) {
 }
 
 // UnmarshalText implements the encoding.TextUnarshaler interface and
-// assumes the data is in the FEN format.
+// assumes the data is in the FEN format of the position's variant.
 func (pos *Position) UnmarshalText(text []byte) error {
-	cp, err := decodeFEN(string(text))
+	cp, err := decodeVariantFEN(pos.Variant(), string(text))
 	if err != nil {
 		return err
 	}
@@ -200,7 +246,11 @@ func (pos *Position) UnmarshalText(text []byte) error {
 	pos.enPassantSquare = cp.enPassantSquare
 	pos.halfMoveClock = cp.halfMoveClock
 	pos.moveCount = cp.moveCount
-	pos.inCheck = isInCheck(cp.board, cp.turn)
+	pos.inCheck = cp.inCheck
+	pos.checks = cp.checks
+	pos.pockets = cp.pockets
+	pos.promoted = cp.promoted
+	pos.validMoves = nil
 	return nil
 }
 
@@ -305,7 +355,7 @@ func (pos *Position) UnmarshalBinary(data []byte) error {
 	if b&bitsHasEnPassant == 0 {
 		pos.enPassantSquare = NoSquare
 	}
-	pos.inCheck = isInCheck(pos.board, pos.turn)
+	pos.inCheck = pos.Variant().inCheck(pos.board, pos.turn)
 	return nil
 }
 
@@ -320,6 +370,10 @@ func (pos *Position) copy() *Position {
 		halfMoveClock:   pos.halfMoveClock,
 		moveCount:       pos.moveCount,
 		inCheck:         pos.inCheck,
+		variant:         pos.variant,
+		checks:          pos.checks,
+		pockets:         pos.pockets,
+		promoted:        pos.promoted,
 	}
 }
 
@@ -368,7 +422,7 @@ func (pos *Position) updateEnPassantSquare(m Move) Square {
 	if p == NoPiece {
 		p = pos.board.Piece(m.S1())
 	}
-	if p.Type() != Pawn {
+	if p.Type() != Pawn || m.HasTag(Drop) {
 		return NoSquare
 	}
 	if pos.turn == White &&
@@ -387,5 +441,8 @@ func (pos *Position) samePosition(pos2 *Position) bool {
 	return pos.board.Eq(pos2.board) &&
 		pos.turn == pos2.turn &&
 		pos.castleRights.String() == pos2.castleRights.String() &&
-		pos.enPassantSquare == pos2.enPassantSquare
+		pos.enPassantSquare == pos2.enPassantSquare &&
+		pos.checks == pos2.checks &&
+		pos.pockets == pos2.pockets &&
+		pos.promoted == pos2.promoted
 }
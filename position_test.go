@@ -23,3 +23,19 @@ func TestPositionBinary(t *testing.T) {
 		}
 	}
 }
+
+func TestPositionInCheck(t *testing.T) {
+	g := NewGame()
+	for _, m := range []string{"e4", "f5", "Qh5+"} {
+		if err := g.MoveStr(m); err != nil {
+			t.Fatal(err)
+		}
+		if inCheck := g.Position().InCheck(); inCheck != (m == "Qh5+") {
+			t.Fatalf("after %s expected in check to be %v", m, !inCheck)
+		}
+	}
+	pos := unsafeFEN("rnbqkbnr/ppppp2p/8/5ppQ/4P3/8/PPPP1PPP/RNB1KBNR b KQkq - 1 3")
+	if !pos.InCheck() {
+		t.Fatal("expected the position from FEN to be in check")
+	}
+}
@@ -63,6 +63,10 @@ func parseSAN(s string, pos *Position) (Move, error) {
 		return parseSANTail(move, s[3:])
 	}
 
+	if at := strings.IndexByte(s, '@'); at != -1 {
+		return parseSANDrop(s, at, pos)
+	}
+
 	originalMove := s
 	// Find the index of the last number.
 	lastNum := -1
@@ -80,7 +84,10 @@ func parseSAN(s string, pos *Position) (Move, error) {
 	head := s[:lastNum-1]
 	// Every SAN move contains the full destination square
 	toSquareStr := s[lastNum-1 : lastNum+1]
-	toSq = strToSquareMap[strings.ToLower(toSquareStr)]
+	toSq, ok := strToSquareMap[strings.ToLower(toSquareStr)]
+	if !ok {
+		return 0, fmt.Errorf("parseSAN: invalid square `%s` in `%s`", toSquareStr, s)
+	}
 	// These are the extra info parsed at the end
 	tail := s[lastNum+1:]
 
@@ -102,7 +109,7 @@ func parseSAN(s string, pos *Position) (Move, error) {
 		// Capitalization is important here; consider the conflation of
 		// "bxc5" or "Bxc5"
 		if head[0] < 0x60 {
-			typ = fenPieceMap[head[0:1]].Type()
+			typ = sanPieceType(head[0:1])
 		} else {
 			typ = Pawn
 			fileHint = int(head[0] - 0x61)
@@ -114,7 +121,7 @@ func parseSAN(s string, pos *Position) (Move, error) {
 		}
 	case 2:
 		// A disambiguated move. Must contain a piece at the front.
-		typ = fenPieceMap[head[0:1]].Type()
+		typ = sanPieceType(head[0:1])
 		if head[1] > 0x30 && head[1] < 0x3A {
 			// It's a number disambiguator.
 			rankHint = int(head[1]) - 0x31
@@ -129,10 +136,15 @@ func parseSAN(s string, pos *Position) (Move, error) {
 		}
 	case 3:
 		// A fully disambiguated move. Contains all the info.
-		typ = fenPieceMap[head[0:1]].Type()
-		fromSq := strToSquareMap[strings.ToLower(head[1:])]
+		typ = sanPieceType(head[0:1])
+		fromSq, ok := strToSquareMap[strings.ToLower(head[1:])]
+		if !ok {
+			return 0, fmt.Errorf("parseSAN: invalid square `%s` in `%s`", head[1:], originalMove)
+		}
 		rankHint = int(fromSq.Rank())
 		fileHint = int(fromSq.File())
+	default:
+		return 0, fmt.Errorf("parseSAN: couldn't parse `%s` before the square in `%s`", head, originalMove)
 	}
 	if typ == NoPieceType {
 		return 0, fmt.Errorf("parseSAN: Couldn't deduce a piece type for `%s`", originalMove)
@@ -179,6 +191,39 @@ func parseSAN(s string, pos *Position) (Move, error) {
 	return parseSANTail(move, tail)
 }
 
+// parseSANDrop parses a Crazyhouse drop like N@f3, or P@e4 or @e4 for a
+// pawn, where at is the index of the @.
+func parseSANDrop(s string, at int, pos *Position) (Move, error) {
+	typ := Pawn
+	if at == 1 {
+		p, ok := fenPieceMap[s[:1]]
+		if !ok || p.Color() != White || p.Type() == King {
+			return 0, fmt.Errorf("parseSAN: invalid piece to drop in `%s`", s)
+		}
+		typ = p.Type()
+	} else if at != 0 {
+		return 0, fmt.Errorf("parseSAN: invalid drop `%s`", s)
+	}
+	if len(s) < at+3 {
+		return 0, fmt.Errorf("parseSAN: couldn't find a square to drop on in `%s`", s)
+	}
+	sq, ok := strToSquareMap[s[at+1:at+3]]
+	if !ok {
+		return 0, fmt.Errorf("parseSAN: couldn't find a square to drop on in `%s`", s)
+	}
+	return parseSANTail(NewDrop(GetPiece(typ, pos.Turn()), sq), s[at+3:])
+}
+
+// sanPieceType returns the type of the piece letter, or NoPieceType if it
+// isn't one.
+func sanPieceType(c string) PieceType {
+	p, ok := fenPieceMap[c]
+	if !ok {
+		return NoPieceType
+	}
+	return p.Type()
+}
+
 func parseSANQuality(s string) string {
 	// TODO(barakmich): Perhaps add move comments about the quality of the move.
 	// But for now, drop it.
@@ -222,6 +267,8 @@ func parseSANTail(move Move, s string) (Move, error) {
 			move = move.setPromo(PromoBishop)
 		case "=N", "=n":
 			move = move.setPromo(PromoKnight)
+		case "=K", "=k":
+			move = move.setPromo(PromoKing)
 		default:
 			return 0, fmt.Errorf("parseSANTail: detected promotion but can't parse `%s`", s)
 		}
@@ -0,0 +1,111 @@
+package chess
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// letterSAN is SAN written with other symbols for the pieces, for each
+// color in the order king, queen, rook, bishop and knight.  pawns are
+// symbols that may be written before a pawn move and are dropped when
+// decoding.
+type letterSAN struct {
+	symbols [2]string
+	pawns   string
+}
+
+// sanLetters are the SAN piece letters in the order of letterSAN's
+// symbols.
+const sanLetters = "KQRBN"
+
+func (n letterSAN) Encode(pos *Position, m Move) string {
+	c := pos.Turn()
+	s := []rune(pos.EncodeSAN(m))
+	for i, r := range s {
+		// piece letters start the move or follow the = of a promotion
+		if i != 0 && s[i-1] != '=' {
+			continue
+		}
+		if j := strings.IndexRune(sanLetters, r); j != -1 {
+			s[i] = []rune(n.symbols[c])[j]
+		}
+	}
+	return string(s)
+}
+
+func (n letterSAN) Decode(pos *Position, s string) (Move, error) {
+	var sb strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(n.pawns, r) {
+			continue
+		}
+		sb.WriteRune(n.letter(r))
+	}
+	return pos.DecodeSAN(sb.String())
+}
+
+// letter returns the SAN letter of the symbol, or the symbol if it isn't
+// one of the notation's.
+func (n letterSAN) letter(r rune) rune {
+	for _, symbols := range n.symbols {
+		for i, sym := range []rune(symbols) {
+			if sym == r {
+				return rune(sanLetters[i])
+			}
+		}
+	}
+	return r
+}
+
+// sanLanguages are the piece letters of languages by their ISO 639-1
+// code, in the order king, queen, rook, bishop and knight.
+var sanLanguages = map[string]string{
+	"cs": "KDVSJ",
+	"da": "KDTLS",
+	"de": "KDTLS",
+	"en": "KQRBN",
+	"es": "RDTAC",
+	"fi": "KDTLR",
+	"fr": "RDTFC",
+	"hu": "KVBFH",
+	"it": "RDTAC",
+	"nl": "KDTLP",
+	"no": "KDTLS",
+	"pl": "KHWGS",
+	"pt": "RDTBC",
+	"sv": "KDTLS",
+}
+
+// NewLocalizedSAN returns SAN written with the piece letters, which are
+// given in the order king, queen, rook, bishop and knight, like "KDTLS"
+// for German.  An error is returned if there aren't five different upper
+// case letters or one of them is the O of castling.
+func NewLocalizedSAN(letters string) (Notation, error) {
+	symbols := []rune(letters)
+	err := fmt.Errorf("chess: invalid piece letters %q", letters)
+	if len(symbols) != len(sanLetters) {
+		return nil, err
+	}
+	for i, r := range symbols {
+		if !unicode.IsUpper(r) || r == 'O' || strings.ContainsRune(string(symbols[:i]), r) {
+			return nil, err
+		}
+	}
+	return letterSAN{symbols: [2]string{letters, letters}}, nil
+}
+
+// LocalizedSAN returns SAN written with the piece letters of the language
+// with the ISO 639-1 code, like Sf3 in German ("de") or Cf3 in French
+// ("fr").  Czech, Danish, Dutch, English, Finnish, French, German,
+// Hungarian, Italian, Norwegian, Polish, Portuguese, Spanish and Swedish
+// are supported.  Since languages reuse letters, like the R of the
+// French king, localized notations aren't registered for DecodeMove; set
+// one as the Game's Notation for MoveStr to decode it first.
+func LocalizedSAN(lang string) (Notation, error) {
+	letters, ok := sanLanguages[strings.ToLower(lang)]
+	if !ok {
+		return nil, fmt.Errorf("chess: no piece letters for language %q", lang)
+	}
+	return NewLocalizedSAN(letters)
+}
@@ -42,11 +42,15 @@ func runBigScanner(t testing.TB) {
 			blackWins += 1
 		}
 	}
-	if whiteWins != 1214 {
-		t.Errorf("Apparent White wins doesn't match: got %d expected %d", whiteWins, 1214)
+	// the fixture has ten games without moves, each followed by a game
+	// that was lost when they were merged: 6OaA2QVm, 5lNEKEgS, nL4A8jCu,
+	// 5XTPUOFs and uv6Hokxf won by white, and O9kjEPeF, iJ8W7Ywq,
+	// PsyIJHPs, L63YhiTH and U7G9DLK9 won by black
+	if whiteWins != 1219 {
+		t.Errorf("Apparent White wins doesn't match: got %d expected %d", whiteWins, 1219)
 	}
-	if blackWins != 1189 {
-		t.Errorf("Apparent Black wins doesn't match: got %d expected %d", blackWins, 1189)
+	if blackWins != 1194 {
+		t.Errorf("Apparent Black wins doesn't match: got %d expected %d", blackWins, 1194)
 	}
 }
 
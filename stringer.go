@@ -4,9 +4,9 @@ package chess
 
 import "fmt"
 
-const _Method_name = "NoMethodCheckmateResignationDrawOfferStalemateThreefoldRepetitionFivefoldRepetitionFiftyMoveRuleSeventyFiveMoveRuleInsufficientMaterial"
+const _Method_name = "NoMethodCheckmateResignationDrawOfferStalemateThreefoldRepetitionFivefoldRepetitionFiftyMoveRuleSeventyFiveMoveRuleInsufficientMaterialVariantWinVariantDrawDeadPositionTimeoutAbandonedAdjudicationRulesInfractionTimeoutVsInsufficientMaterial"
 
-var _Method_index = [...]uint8{0, 8, 17, 28, 37, 46, 65, 83, 96, 115, 135}
+var _Method_index = [...]uint8{0, 8, 17, 28, 37, 46, 65, 83, 96, 115, 135, 145, 156, 168, 175, 184, 196, 211, 240}
 
 func (i Method) String() string {
 	if i >= Method(len(_Method_index)-1) {
@@ -0,0 +1,142 @@
+package chess
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Termination returns the value of the PGN Termination tag for a game
+// ended by the method: "time forfeit", "abandoned", "adjudication" and
+// "rules infraction" for the methods named after them, "normal" for the
+// others, and "unterminated" for NoMethod.
+func (m Method) Termination() string {
+	switch m {
+	case NoMethod:
+		return "unterminated"
+	case Timeout, TimeoutVsInsufficientMaterial:
+		return "time forfeit"
+	case Abandoned:
+		return "abandoned"
+	case Adjudication:
+		return "adjudication"
+	case RulesInfraction:
+		return "rules infraction"
+	}
+	return "normal"
+}
+
+// terminationMethods are the methods named by the values of Termination
+// tags, in lower case, from the PGN standard and from the sentences
+// Chess.com writes, like "Hikaru won on time".  Values are matched in
+// order by suffix, so more specific ones come first.
+var terminationMethods = []struct {
+	value  string
+	method Method
+}{
+	{"time forfeit", Timeout},
+	{"timeout vs insufficient material", TimeoutVsInsufficientMaterial},
+	{"on time", Timeout},
+	{"abandoned", Abandoned},
+	{"adjudication", Adjudication},
+	{"rules infraction", RulesInfraction},
+	{"checkmate", Checkmate},
+	{"resignation", Resignation},
+	{"agreement", DrawOffer},
+	{"stalemate", Stalemate},
+	{"repetition", ThreefoldRepetition},
+	{"insufficient material", InsufficientMaterial},
+	{"50-move rule", FiftyMoveRule},
+}
+
+// methodFromTermination returns the method of a game with the outcome
+// and the Termination tag, working it out from the final position when
+// the tag doesn't say, or NoMethod if it can't.
+func methodFromTermination(g *Game, outcome Outcome, termination string) Method {
+	if outcome == NoOutcome {
+		return NoMethod
+	}
+	termination = strings.ToLower(strings.TrimSpace(termination))
+	method := NoMethod
+	for _, t := range terminationMethods {
+		if strings.HasSuffix(termination, t.value) {
+			method = t.method
+			break
+		}
+	}
+	if method == Timeout && outcome == Draw {
+		method = TimeoutVsInsufficientMaterial
+	}
+	if method != NoMethod {
+		return method
+	}
+	// the final position proves checkmate, stalemate or a variant's end
+	if o, m := g.pos.Variant().result(g.pos); o == outcome {
+		return m
+	}
+	if outcome == Draw {
+		switch {
+		case g.pos.Variant().insufficientMaterial(g.pos):
+			return InsufficientMaterial
+		case g.numOfRepetitions() >= 5:
+			return FivefoldRepetition
+		case g.pos.halfMoveClock >= 150:
+			return SeventyFiveMoveRule
+		case g.numOfRepetitions() >= 3:
+			return ThreefoldRepetition
+		case g.pos.halfMoveClock >= 100:
+			return FiftyMoveRule
+		}
+	}
+	// a game ended normally without a reason on the board was resigned
+	// or agreed drawn
+	if termination == "normal" {
+		if outcome == Draw {
+			return DrawOffer
+		}
+		return Resignation
+	}
+	return NoMethod
+}
+
+// Forfeit ends the game as a loss for the given color by Timeout,
+// Abandoned or RulesInfraction, and sets its Termination tag pair.  A
+// Timeout is drawn by TimeoutVsInsufficientMaterial if the opponent can't
+// checkmate.  If the game has already been completed then it isn't
+// updated.  An error is returned for any other method.
+func (g *Game) Forfeit(color Color, method Method) error {
+	switch method {
+	case Timeout, Abandoned, RulesInfraction:
+	default:
+		return fmt.Errorf("chess: unsupported forfeit method %s", method.String())
+	}
+	if g.outcome != NoOutcome || color == NoColor {
+		return nil
+	}
+	g.outcome = winner(color.Other())
+	g.method = method
+	if method == Timeout && g.pos.Variant() == Standard && !hasMatingMaterial(g.pos.board, color.Other()) {
+		g.outcome = Draw
+		g.method = TimeoutVsInsufficientMaterial
+	}
+	g.AddTagPair("Termination", g.method.Termination())
+	return nil
+}
+
+// Adjudicate ends the game with the outcome decided by an arbiter or an
+// adjudication rule, and sets its Termination tag pair.  If the game has
+// already been completed then it isn't updated.  An error is returned if
+// the outcome is NoOutcome.
+func (g *Game) Adjudicate(outcome Outcome) error {
+	switch outcome {
+	case WhiteWon, BlackWon, Draw:
+	default:
+		return fmt.Errorf("chess: invalid adjudication outcome %s", outcome)
+	}
+	if g.outcome != NoOutcome {
+		return nil
+	}
+	g.outcome = outcome
+	g.method = Adjudication
+	g.AddTagPair("Termination", g.method.Termination())
+	return nil
+}
@@ -0,0 +1,102 @@
+package chess
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPGNMethod(t *testing.T) {
+	tests := []struct {
+		tags   string
+		moves  string
+		method Method
+	}{
+		{``, `1. f3 e5 2. g4 Qh4# 0-1`, Checkmate},
+		{`[FEN "7k/8/5QK1/8/8/8/8/8 w - - 0 1"]`, `1. Qf7 1/2-1/2`, Stalemate},
+		{`[FEN "8/8/8/8/8/2k5/8/1K1B4 w - - 0 1"]`, `1. Be2 1/2-1/2`, InsufficientMaterial},
+		{``, `1. e4 e5 1-0`, NoMethod},
+		{``, `1. e4 e5 *`, NoMethod},
+		{`[Termination "Normal"]`, `1. e4 e5 1-0`, Resignation},
+		{`[Termination "Normal"]`, `1. e4 e5 1/2-1/2`, DrawOffer},
+		{`[Termination "Normal"]`, `1. f3 e5 2. g4 Qh4# 0-1`, Checkmate},
+		{`[Termination "Time forfeit"]`, `1. e4 e5 1-0`, Timeout},
+		{`[Termination "Time forfeit"]`, `1. e4 e5 1/2-1/2`, TimeoutVsInsufficientMaterial},
+		{`[Termination "Abandoned"]`, `1. e4 0-1`, Abandoned},
+		{`[Termination "Rules infraction"]`, `1. e4 0-1`, RulesInfraction},
+		{`[Termination "adjudication"]`, `1. e4 1/2-1/2`, Adjudication},
+		{`[Termination "Hikaru won on time"]`, `1. e4 0-1`, Timeout},
+		{`[Termination "Hikaru won by resignation"]`, `1. e4 0-1`, Resignation},
+		{`[Termination "Hikaru won - game abandoned"]`, `1. e4 0-1`, Abandoned},
+		{`[Termination "Game drawn by repetition"]`, `1. e4 1/2-1/2`, ThreefoldRepetition},
+		{`[Termination "Game drawn by timeout vs insufficient material"]`, `1. e4 1/2-1/2`, TimeoutVsInsufficientMaterial},
+	}
+	for _, test := range tests {
+		pgn := test.tags + "\n\n" + test.moves
+		g, err := NewGameFromPGN(strings.NewReader(pgn))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if g.Method() != test.method {
+			t.Fatalf("expected %s from %q but got %s", test.method, pgn, g.Method())
+		}
+	}
+}
+
+func TestForfeit(t *testing.T) {
+	tests := []struct {
+		fen     string
+		method  Method
+		outcome Outcome
+		want    Method
+		tag     string
+	}{
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", Timeout, BlackWon, Timeout, "time forfeit"},
+		{"8/2k5/8/8/8/3K4/4P3/8 w - - 0 1", Timeout, Draw, TimeoutVsInsufficientMaterial, "time forfeit"},
+		{"8/2k5/8/8/8/3K4/4P3/8 w - - 0 1", Abandoned, BlackWon, Abandoned, "abandoned"},
+		{"8/2k5/8/8/8/3K4/4P3/8 w - - 0 1", RulesInfraction, BlackWon, RulesInfraction, "rules infraction"},
+	}
+	for _, test := range tests {
+		g, err := NewGameFromFEN(test.fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := g.Forfeit(White, test.method); err != nil {
+			t.Fatal(err)
+		}
+		if g.Outcome() != test.outcome || g.Method() != test.want {
+			t.Fatalf("expected %s by %s but got %s by %s", test.outcome, test.want, g.Outcome(), g.Method())
+		}
+		if tp := g.GetTagPair("Termination"); tp == nil || tp.Value != test.tag {
+			t.Fatalf("expected termination %s but got %v", test.tag, tp)
+		}
+		cp, err := NewGameFromPGN(strings.NewReader(g.String()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cp.Method() != g.Method() {
+			t.Fatalf("expected %s after decoding but got %s", g.Method(), cp.Method())
+		}
+	}
+	if err := NewGame().Forfeit(White, Checkmate); err == nil {
+		t.Fatal("expected checkmate to not be a forfeit")
+	}
+}
+
+func TestAdjudicate(t *testing.T) {
+	g := NewGame()
+	if err := g.Adjudicate(NoOutcome); err == nil {
+		t.Fatal("expected an error adjudicating no outcome")
+	}
+	if err := g.Adjudicate(WhiteWon); err != nil {
+		t.Fatal(err)
+	}
+	if g.Outcome() != WhiteWon || g.Method() != Adjudication {
+		t.Fatalf("expected white to win by adjudication but got %s by %s", g.Outcome(), g.Method())
+	}
+	if g.GetTagPair("Termination").Value != "adjudication" {
+		t.Fatalf("expected an adjudication termination but got %s", g.GetTagPair("Termination").Value)
+	}
+	if NoMethod.Termination() != "unterminated" || Checkmate.Termination() != "normal" {
+		t.Fatal("expected unterminated and normal terminations")
+	}
+}
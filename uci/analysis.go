@@ -0,0 +1,312 @@
+package uci
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/barakmich/chess"
+)
+
+// EvalCap is the largest evaluation, in centipawns, used when measuring
+// centipawn loss.  Forced mates and checkmates count as EvalCap.
+const EvalCap = 1000
+
+// Eval is an engine evaluation from White's point of view.
+type Eval struct {
+	// CP is the score in centipawns.  A checkmated position has a CP
+	// of -EvalCap if White is mated and EvalCap if Black is.
+	CP int
+	// Mate is the number of moves to a forced mate, positive if White mates
+	// and negative if Black does.  It is zero when there is no forced mate.
+	Mate int
+}
+
+// Centipawns returns the evaluation in centipawns clamped to +/-EvalCap.
+func (e Eval) Centipawns() int {
+	switch {
+	case e.Mate > 0:
+		return EvalCap
+	case e.Mate < 0:
+		return -EvalCap
+	case e.CP > EvalCap:
+		return EvalCap
+	case e.CP < -EvalCap:
+		return -EvalCap
+	}
+	return e.CP
+}
+
+// String returns the evaluation in the form used by PGN %eval annotations,
+// such as 0.39 or #-3.
+func (e Eval) String() string {
+	if e.Mate != 0 {
+		return "#" + strconv.Itoa(e.Mate)
+	}
+	return strconv.FormatFloat(float64(e.CP)/100, 'f', 2, 64)
+}
+
+// forColor returns the evaluation in centipawns from c's point of view.
+func (e Eval) forColor(c chess.Color) int {
+	if c == chess.Black {
+		return -e.Centipawns()
+	}
+	return e.Centipawns()
+}
+
+// Judgment classifies a move by how much it lost.
+type Judgment int
+
+const (
+	// NoJudgment is a move that lost less than the inaccuracy threshold.
+	NoJudgment Judgment = iota
+	// Inaccuracy is a move that lost at least the inaccuracy threshold.
+	Inaccuracy
+	// Mistake is a move that lost at least the mistake threshold.
+	Mistake
+	// Blunder is a move that lost at least the blunder threshold.
+	Blunder
+)
+
+// String implements the fmt.Stringer interface.
+func (j Judgment) String() string {
+	switch j {
+	case Inaccuracy:
+		return "Inaccuracy"
+	case Mistake:
+		return "Mistake"
+	case Blunder:
+		return "Blunder"
+	}
+	return ""
+}
+
+// NAG returns the annotation glyph for the judgment: ?! for an inaccuracy,
+// ? for a mistake and ?? for a blunder.
+func (j Judgment) NAG() chess.NAG {
+	switch j {
+	case Inaccuracy:
+		return chess.DubiousMove
+	case Mistake:
+		return chess.MistakeMove
+	case Blunder:
+		return chess.BlunderMove
+	}
+	return chess.NoNAG
+}
+
+// AnalysisOptions configures AnalyzeGame.
+type AnalysisOptions struct {
+	// Go is the search run on every position, such as CmdGo{Depth: 18} or
+	// CmdGo{MoveTime: time.Second}.  The zero value searches to depth 12.
+	Go CmdGo
+	// Inaccuracy, Mistake and Blunder are the centipawn losses from which a
+	// move is classified as such.  Zero values default to 50, 100 and 300.
+	Inaccuracy int
+	Mistake    int
+	Blunder    int
+}
+
+// MoveAnalysis is the analysis of a single move of a game.
+type MoveAnalysis struct {
+	Move  chess.Move
+	Color chess.Color
+	// Before and After are the evaluations of the positions before and
+	// after the move.
+	Before Eval
+	After  Eval
+	// BestMove is the engine's choice in the position before the move and
+	// BestLine is its principal variation.
+	BestMove chess.Move
+	BestLine []chess.Move
+	// CPLoss is how many centipawns the move lost for the player who made it.
+	CPLoss   int
+	Judgment Judgment
+	// Accuracy is the move's accuracy from 0 to 100, based on how much the
+	// player's winning chances dropped.
+	Accuracy float64
+}
+
+// GameAnalysis is the result of AnalyzeGame.
+type GameAnalysis struct {
+	// Moves holds the analysis of each move, indexed by ply.
+	Moves []MoveAnalysis
+	// WhiteAccuracy and BlackAccuracy are the mean accuracies of each
+	// player's moves.  WhiteACPL and BlackACPL are their average
+	// centipawn losses.
+	WhiteAccuracy float64
+	BlackAccuracy float64
+	WhiteACPL     float64
+	BlackACPL     float64
+}
+
+// AnalyzeGame searches every position of the game with the engine, which must
+// have been initialized with CmdUCI, and works out the centipawn loss,
+// judgment and accuracy of each move.
+func AnalyzeGame(ctx context.Context, e *Engine, g *chess.Game, opts AnalysisOptions) (*GameAnalysis, error) {
+	opts = opts.withDefaults()
+	if opts.Go.Infinite || opts.Go.Ponder {
+		return nil, errors.New("uci: analysis search must be finite")
+	}
+	if err := e.RunContext(ctx, CmdUCINewGame, CmdIsReady); err != nil {
+		return nil, err
+	}
+	positions := g.Positions()
+	moves := g.Moves()
+	evals := make([]Eval, len(positions))
+	results := make([]SearchResults, len(positions))
+	for i, pos := range positions {
+		switch pos.Status() {
+		case chess.Checkmate:
+			evals[i] = Eval{CP: EvalCap}
+			if pos.Turn() == chess.White {
+				evals[i].CP = -EvalCap
+			}
+			continue
+		case chess.Stalemate:
+			continue
+		}
+		setPos := CmdPosition{Position: positions[0], Moves: moves[:i]}
+		if err := e.RunContext(ctx, setPos, opts.Go); err != nil {
+			return nil, err
+		}
+		results[i] = e.SearchResults()
+		score := results[i].Info.Score
+		evals[i] = Eval{CP: score.CP, Mate: score.Mate}
+		if pos.Turn() == chess.Black {
+			evals[i] = Eval{CP: -score.CP, Mate: -score.Mate}
+		}
+	}
+
+	a := &GameAnalysis{}
+	var whiteMoves, blackMoves float64
+	for i, m := range moves {
+		color := positions[i].Turn()
+		ma := MoveAnalysis{
+			Move:     m,
+			Color:    color,
+			Before:   evals[i],
+			After:    evals[i+1],
+			BestMove: results[i].BestMove,
+			BestLine: results[i].Info.PV,
+		}
+		before, after := ma.Before.forColor(color), ma.After.forColor(color)
+		if before > after {
+			ma.CPLoss = before - after
+		}
+		ma.Judgment = opts.judge(ma.CPLoss)
+		ma.Accuracy = moveAccuracy(winPercent(before), winPercent(after))
+		if color == chess.White {
+			a.WhiteAccuracy += ma.Accuracy
+			a.WhiteACPL += float64(ma.CPLoss)
+			whiteMoves++
+		} else {
+			a.BlackAccuracy += ma.Accuracy
+			a.BlackACPL += float64(ma.CPLoss)
+			blackMoves++
+		}
+		a.Moves = append(a.Moves, ma)
+	}
+	if whiteMoves > 0 {
+		a.WhiteAccuracy /= whiteMoves
+		a.WhiteACPL /= whiteMoves
+	}
+	if blackMoves > 0 {
+		a.BlackAccuracy /= blackMoves
+		a.BlackACPL /= blackMoves
+	}
+	return a, nil
+}
+
+// Annotate writes the analysis into the game it was made from.  Every move
+// gets a comment with an [%eval] command, and judged moves also get a NAG,
+// the best move named in the same comment and the engine's best line as a
+// variation.
+func (a *GameAnalysis) Annotate(g *chess.Game) error {
+	positions := g.Positions()
+	if len(a.Moves) != len(positions)-1 {
+		return fmt.Errorf("uci: analysis of %d moves doesn't match game of %d moves", len(a.Moves), len(positions)-1)
+	}
+	for ply, ma := range a.Moves {
+		pos := positions[ply]
+		// the judgment and the [%eval] command share the move's comment
+		var comment []string
+		if ma.Judgment != NoJudgment && ma.BestMove != 0 && !ma.BestMove.Eq(ma.Move) {
+			best := ma.BestMove
+			for _, m := range pos.ValidMoves() {
+				if m.Eq(best) {
+					// use the move with its tags so the SAN has + and x
+					best = m
+				}
+			}
+			comment = append(comment, fmt.Sprintf("(%s → %s) %s. %s was best.", ma.Before, ma.After, ma.Judgment,
+				pos.EncodeMove(best, chess.SANNotation)))
+			if err := g.AddNAG(ply, ma.Judgment.NAG()); err != nil {
+				return err
+			}
+			line := ma.BestLine
+			if len(line) == 0 || !line[0].Eq(ma.BestMove) {
+				line = []chess.Move{ma.BestMove}
+			}
+			if err := g.AddVariation(ply, line); err != nil {
+				return err
+			}
+		}
+		if positions[ply+1].Status() != chess.Checkmate {
+			comment = append(comment, "[%eval "+ma.After.String()+"]")
+		}
+		if len(comment) != 0 {
+			if err := g.AddComment(ply, strings.Join(comment, " ")); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (opts AnalysisOptions) withDefaults() AnalysisOptions {
+	g := opts.Go
+	if g.Depth == 0 && g.Nodes == 0 && g.MoveTime == 0 && g.Mate == 0 && g.WhiteTime == 0 && g.BlackTime == 0 {
+		opts.Go.Depth = 12
+	}
+	if opts.Inaccuracy == 0 {
+		opts.Inaccuracy = 50
+	}
+	if opts.Mistake == 0 {
+		opts.Mistake = 100
+	}
+	if opts.Blunder == 0 {
+		opts.Blunder = 300
+	}
+	return opts
+}
+
+func (opts AnalysisOptions) judge(cpLoss int) Judgment {
+	switch {
+	case cpLoss >= opts.Blunder:
+		return Blunder
+	case cpLoss >= opts.Mistake:
+		return Mistake
+	case cpLoss >= opts.Inaccuracy:
+		return Inaccuracy
+	}
+	return NoJudgment
+}
+
+// winPercent converts centipawns into winning chances from 0 to 100.
+func winPercent(cp int) float64 {
+	return 50 + 50*(2/(1+math.Exp(-0.00368208*float64(cp)))-1)
+}
+
+// moveAccuracy scores a move from 0 to 100 by the drop in winning chances it
+// caused.
+func moveAccuracy(before, after float64) float64 {
+	if after >= before {
+		return 100
+	}
+	acc := 103.1668*math.Exp(-0.04354*(before-after)) - 3.1669
+	return math.Max(0, math.Min(100, acc))
+}
@@ -0,0 +1,67 @@
+package uci_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/barakmich/chess"
+	"github.com/barakmich/chess/uci"
+)
+
+func TestAnalyzeGame(t *testing.T) {
+	eng := newFakeEngine(t, "")
+	if err := eng.Run(uci.CmdUCI); err != nil {
+		t.Fatal(err)
+	}
+	game := chess.NewGame()
+	for _, m := range []string{"e4", "e5", "Qh5", "Nc6", "Bc4", "Nf6", "Qxf7#"} {
+		if err := game.MoveStr(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	a, err := uci.AnalyzeGame(context.Background(), eng, game, uci.AnalysisOptions{Go: uci.CmdGo{Depth: 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(a.Moves) != 7 {
+		t.Fatalf("expected 7 analyzed moves but got %d", len(a.Moves))
+	}
+	blunder := a.Moves[5]
+	if blunder.Judgment != uci.Blunder || blunder.CPLoss != uci.EvalCap {
+		t.Fatalf("expected Nf6 to be a blunder losing %d but got %s losing %d", uci.EvalCap, blunder.Judgment, blunder.CPLoss)
+	}
+	if blunder.After.Mate != 1 {
+		t.Fatalf("expected White to mate in one after Nf6 but got %s", blunder.After)
+	}
+	if mate := a.Moves[6]; mate.Judgment != uci.NoJudgment || mate.Accuracy != 100 {
+		t.Fatalf("expected Qxf7# to be perfect but got %s with accuracy %f", mate.Judgment, mate.Accuracy)
+	}
+	if a.BlackAccuracy >= a.WhiteAccuracy {
+		t.Fatalf("expected Black to be less accurate than White but got %f and %f", a.BlackAccuracy, a.WhiteAccuracy)
+	}
+
+	if err := a.Annotate(game); err != nil {
+		t.Fatal(err)
+	}
+	if nags := game.NAGs()[5]; len(nags) != 1 || nags[0] != chess.BlunderMove {
+		t.Fatalf("expected ?? on Nf6 but got %v", nags)
+	}
+	if v := game.Variations(5); len(v) != 1 || !v[0][0].Eq(blunder.BestMove) {
+		t.Fatalf("expected the best move as a variation but got %v", v)
+	}
+	pgn := game.String()
+	if !strings.Contains(pgn, "Blunder.") || !strings.Contains(pgn, "[%eval #1]") {
+		t.Fatalf("expected annotations in pgn:\n%s", pgn)
+	}
+	if c := game.Comments()[5]; len(c) != 1 || !strings.HasPrefix(c[0], "(") || !strings.HasSuffix(c[0], " was best. [%eval #1]") {
+		t.Fatalf("expected the judgment and eval in one comment on Nf6 but got %q", c)
+	}
+	decoded, err := chess.NewGameFromPGN(strings.NewReader(pgn))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.String() != pgn {
+		t.Fatalf("expected annotated pgn to round trip:\n%s\n%s", pgn, decoded.String())
+	}
+}
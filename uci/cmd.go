@@ -1,7 +1,6 @@
 package uci
 
 import (
-	"bufio"
 	"errors"
 	"fmt"
 	"strings"
@@ -49,9 +48,11 @@ var (
 	CmdUCI = cmdNoOptions{Name: "uci", F: func(e *Engine) error {
 		e.id = map[string]string{}
 		e.options = map[string]Option{}
-		scanner := bufio.NewScanner(e.out)
-		for scanner.Scan() {
-			text := e.readLine(scanner)
+		for {
+			text, err := e.readLine()
+			if err != nil {
+				return err
+			}
 			k, v, err := parseIDLine(text)
 			if err == nil {
 				e.id[k] = v
@@ -81,14 +82,15 @@ var (
 	// This command must always be answered with "readyok" and can be sent also when the engine is calculating
 	// in which case the engine should also immediately answer with "readyok" without stopping the search.
 	CmdIsReady = cmdNoOptions{Name: "isready", F: func(e *Engine) error {
-		scanner := bufio.NewScanner(e.out)
-		for scanner.Scan() {
-			text := e.readLine(scanner)
+		for {
+			text, err := e.readLine()
+			if err != nil {
+				return err
+			}
 			if text == "readyok" {
-				break
+				return nil
 			}
 		}
-		return nil
 	}}
 
 	// CmdUCINewGame corresponds to the "ucinewgame" command:
@@ -138,12 +140,18 @@ var (
 //    "setoption name Style value Risky\n"
 //    "setoption name Clear Hash\n"
 //    "setoption name NalimovPath value c:\chess\tb\4;c:\chess\tb\5\n"
+//
+// An empty Value omits the value entirely, which is what button options expect.
+// To set a string option to the empty string use "<empty>" as the Value.
 type CmdSetOption struct {
 	Name  string
 	Value string
 }
 
 func (cmd CmdSetOption) String() string {
+	if cmd.Value == "" {
+		return "setoption name " + cmd.Name
+	}
 	return fmt.Sprintf("setoption name %s value %s", cmd.Name, cmd.Value)
 }
 
@@ -152,6 +160,23 @@ func (cmd CmdSetOption) ProcessResponse(e *Engine) error {
 	return nil
 }
 
+// validate checks the value against the option advertised by the engine
+// in response to CmdUCI.  Options the engine didn't advertise are passed
+// through unchecked.
+func (cmd CmdSetOption) validate(e *Engine) error {
+	for name, o := range e.options {
+		if !strings.EqualFold(name, cmd.Name) {
+			continue
+		}
+		value := cmd.Value
+		if o.Type == OptionString && value == optionEmptyString {
+			value = ""
+		}
+		return o.Validate(value)
+	}
+	return nil
+}
+
 // CmdPosition corresponds to the "position" command:
 // set up the position described in fenstring on the internal board and
 // play the moves on the internal chess board.
@@ -286,15 +311,21 @@ func (cmd CmdGo) String() string {
 
 // ProcessResponse implements the Cmd interface
 func (CmdGo) ProcessResponse(e *Engine) error {
-	scanner := bufio.NewScanner(e.out)
 	results := SearchResults{}
-	for scanner.Scan() {
-		text := e.readLine(scanner)
+	for {
+		text, err := e.readLine()
+		if err != nil {
+			return err
+		}
 		if strings.HasPrefix(text, "bestmove") {
 			parts := strings.Split(text, " ")
 			if len(parts) <= 1 {
 				return errors.New("best move not found " + text)
 			}
+			if parts[1] == "(none)" {
+				// the position is checkmate or stalemate
+				break
+			}
 			bestMove, err := DecodeUCI(parts[1])
 			if err != nil {
 				return err
@@ -311,8 +342,7 @@ func (CmdGo) ProcessResponse(e *Engine) error {
 		}
 
 		info := &Info{}
-		err := info.UnmarshalText([]byte(text))
-		if err == nil {
+		if err := info.UnmarshalText([]byte(text)); err == nil {
 			results.Info = *info
 		}
 	}
@@ -2,26 +2,39 @@ package uci
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"os"
 	"os/exec"
+	"strings"
 	"sync"
+	"time"
 )
 
 // Engine represents a UCI compliant chess engine (e.g. Stockfish, Shredder, etc.).
 // Engine is safe for concurrent use.
 type Engine struct {
 	cmd     *exec.Cmd
-	in      *io.PipeWriter
-	out     *io.PipeReader
+	in      io.WriteCloser
+	lines   chan string
+	done    chan struct{}
+	exitErr error
+	stderr  *tailBuffer
+	timeout time.Duration
+	ctx     context.Context
 	debug   bool
 	logger  *log.Logger
 	id      map[string]string
 	options map[string]Option
 	results SearchResults
 	mu      *sync.RWMutex
+	// resync is set when a command stopped waiting before the engine had
+	// answered it, and searching when that command was a search.
+	resync    bool
+	searching bool
 }
 
 // Debug is an option for the New function to add logging for debugging.  This will
@@ -38,6 +51,16 @@ func Logger(logger *log.Logger) func(e *Engine) {
 	}
 }
 
+// Timeout is an option for the New function that bounds how long each command
+// may wait for the engine's response.  Open ended searches (CmdGo with Infinite
+// or Ponder set) aren't bounded since they wait for CmdStop or CmdPonderHit.
+// Use WithTimeout to bound a single command instead.
+func Timeout(d time.Duration) func(e *Engine) {
+	return func(e *Engine) {
+		e.timeout = d
+	}
+}
+
 // New constructs an engine from the executable path (found using exec.LookPath).
 // New also starts running the executable process in the background.  Once created
 // the Engine can be controlled via the Run method.
@@ -46,19 +69,61 @@ func New(path string, opts ...func(e *Engine)) (*Engine, error) {
 	if err != nil {
 		return nil, fmt.Errorf("uci: executable not found at path %s %w", path, err)
 	}
-	rIn, wIn := io.Pipe()
-	rOut, wOut := io.Pipe()
 	cmd := exec.Command(path)
-	cmd.Stdin = rIn
+	in, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	rOut, wOut, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
 	cmd.Stdout = wOut
-	e := &Engine{cmd: cmd, in: wIn, out: rOut, mu: &sync.RWMutex{}, logger: log.New(os.Stdout, "uci", log.LstdFlags)}
+	e := &Engine{
+		cmd:    cmd,
+		in:     in,
+		lines:  make(chan string, 64),
+		done:   make(chan struct{}),
+		stderr: &tailBuffer{max: stderrTailSize},
+		mu:     &sync.RWMutex{},
+		logger: log.New(os.Stdout, "uci", log.LstdFlags),
+	}
+	cmd.Stderr = e.stderr
 	for _, opt := range opts {
 		opt(e)
 	}
-	go e.cmd.Run()
+	err = cmd.Start()
+	wOut.Close()
+	if err != nil {
+		rOut.Close()
+		return nil, fmt.Errorf("uci: couldn't start engine %s %w", path, err)
+	}
+	go e.readOutput(rOut)
+	go e.wait()
 	return e, nil
 }
 
+// Done returns a channel that is closed once the engine process has exited.
+func (e *Engine) Done() <-chan struct{} {
+	return e.done
+}
+
+// Err returns nil while the engine process is running.  Once it has exited
+// Err returns an *ExitError describing how.
+func (e *Engine) Err() error {
+	select {
+	case <-e.done:
+		return e.exitErr
+	default:
+		return nil
+	}
+}
+
+// Stderr returns the most recent output the engine wrote to its standard error.
+func (e *Engine) Stderr() string {
+	return e.stderr.String()
+}
+
 // ID returns the id values returned from the most recent CmdUCI invocation.  It includes
 // key value data such as the following:
 // id name Stockfish 12
@@ -124,47 +189,167 @@ func (e *Engine) SearchResults() SearchResults {
 // any of the commands fails.  Except for CmdStop (usually paired with
 // CmdGo's infinite option) all commands block via mutux until completed.
 func (e *Engine) Run(cmds ...Cmd) error {
+	return e.RunContext(context.Background(), cmds...)
+}
+
+// RunContext is like Run but stops waiting on the engine once ctx is done,
+// returning ctx's error.  If the engine process exits while a command is
+// running the returned error matches ErrEngineDied, and if the engine
+// doesn't answer within the command's timeout it matches ErrTimeout.  The
+// engine's late answer to a command that stopped waiting is discarded
+// before the next command is run, by stopping its search and waiting for
+// "readyok".
+func (e *Engine) RunContext(ctx context.Context, cmds ...Cmd) error {
 	for _, cmd := range cmds {
 		if cmd.String() == CmdStop.Name {
 			if err := e.processCommand(cmd); err != nil {
 				return err
 			}
-		} else {
-			if err := e.processCommandLocked(cmd); err != nil {
-				return err
-			}
+			continue
+		}
+		if err := e.processCommandLocked(ctx, cmd); err != nil {
+			return err
 		}
 	}
 	return nil
 }
 
 // Close releases readers, writers, and processes associated with the
-// Engine.  It also invokes the CmdQuit to signal the engine to terminate.
+// Engine.  It also invokes the CmdQuit to signal the engine to terminate
+// and kills the process if it hasn't exited shortly afterwards.
 func (e *Engine) Close() error {
-	if err := e.Run(CmdQuit); err != nil {
+	if err := e.Run(CmdQuit); err != nil && !errors.Is(err, ErrEngineDied) {
 		return err
 	}
-	if err := e.in.Close(); err != nil {
-		return err
+	e.in.Close()
+	select {
+	case <-e.done:
+	case <-time.After(closeGracePeriod):
+		if err := e.cmd.Process.Kill(); err != nil {
+			return err
+		}
+		<-e.done
 	}
-	if err := e.out.Close(); err != nil {
-		return err
+	return nil
+}
+
+const (
+	closeGracePeriod = time.Second
+	stderrTailSize   = 16 * 1024
+)
+
+func (e *Engine) commandContext(ctx context.Context, cmd Cmd) (context.Context, context.CancelFunc) {
+	d := e.timeout
+	if t, ok := cmd.(cmdWithTimeout); ok {
+		d = t.timeout
+	} else if g, ok := cmd.(CmdGo); ok && (g.Infinite || g.Ponder) {
+		d = 0
 	}
-	return e.cmd.Process.Kill()
+	if d <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, d)
 }
 
-func (e *Engine) processCommandLocked(cmd Cmd) error {
+func (e *Engine) processCommandLocked(ctx context.Context, cmd Cmd) error {
+	cmdCtx, cancel := e.commandContext(ctx, cmd)
+	defer cancel()
 	e.mu.Lock()
 	defer e.mu.Unlock()
-	return e.processCommand(cmd)
+	e.ctx = cmdCtx
+	defer func() { e.ctx = nil }()
+	// Close has to get through to an engine that never answers.
+	if e.resync && cmd.String() != CmdQuit.Name {
+		if err := e.sync(); err != nil {
+			return e.interrupted(ctx, cmdCtx, cmd, err)
+		}
+	}
+	if err := e.processCommand(cmd); err != nil {
+		err = e.interrupted(ctx, cmdCtx, cmd, err)
+		inner := cmd
+		if t, ok := cmd.(cmdWithTimeout); ok {
+			inner = t.Cmd
+		}
+		if _, ok := inner.(CmdGo); ok && e.resync {
+			e.searching = true
+		}
+		return err
+	}
+	return nil
+}
+
+// interrupted returns the error of a command that failed, which is a
+// timeout or ctx's error if the command stopped waiting on the engine.
+// The engine then needs to be resynced.
+func (e *Engine) interrupted(ctx, cmdCtx context.Context, cmd Cmd, err error) error {
+	if cmdCtx.Err() == nil || errors.Is(err, ErrEngineDied) {
+		return err
+	}
+	e.resync = true
+	if ctx.Err() != nil {
+		return fmt.Errorf("uci: %s: %w", cmd, ctx.Err())
+	}
+	return &timeoutError{cmd: cmd}
+}
+
+// sync discards the engine's answers to interrupted commands by stopping
+// the search, if one was running, and then waiting for "readyok".
+func (e *Engine) sync() error {
+	if e.searching {
+		if err := e.processCommand(cmdSync{Cmd: CmdStop, last: "bestmove"}); err != nil {
+			return err
+		}
+		e.searching = false
+	}
+	if err := e.processCommand(cmdSync{Cmd: CmdIsReady, last: "readyok"}); err != nil {
+		return err
+	}
+	e.resync = false
+	return nil
+}
+
+// cmdSync is a command whose answer is skipped up to the line starting
+// with last.
+type cmdSync struct {
+	Cmd
+	last string
+}
+
+func (cmd cmdSync) ProcessResponse(e *Engine) error {
+	for {
+		text, err := e.readLine()
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(text, cmd.last) {
+			return nil
+		}
+	}
 }
 
 func (e *Engine) processCommand(cmd Cmd) error {
+	inner := cmd
+	if t, ok := cmd.(cmdWithTimeout); ok {
+		inner = t.Cmd
+	}
+	if v, ok := inner.(interface{ validate(e *Engine) error }); ok {
+		if err := v.validate(e); err != nil {
+			return err
+		}
+	}
+	if err := e.Err(); err != nil {
+		return err
+	}
 	if e.debug {
 		e.logger.Println(cmd.String())
 	}
 	if _, err := fmt.Fprintln(e.in, cmd.String()); err != nil {
-		return err
+		select {
+		case <-e.done:
+			return e.exitErr
+		case <-time.After(closeGracePeriod):
+			return err
+		}
 	}
 	if err := cmd.ProcessResponse(e); err != nil {
 		return err
@@ -172,10 +357,137 @@ func (e *Engine) processCommand(cmd Cmd) error {
 	return nil
 }
 
-func (e *Engine) readLine(scanner *bufio.Scanner) string {
-	s := scanner.Text()
-	if e.debug {
-		e.logger.Println(s)
+// readLine returns the next line of engine output.  It fails if the
+// running command's context is done or the engine process exits.
+func (e *Engine) readLine() (string, error) {
+	ctx := e.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	select {
+	case s, ok := <-e.lines:
+		if !ok {
+			select {
+			case <-e.done:
+				return "", e.exitErr
+			case <-ctx.Done():
+				return "", ctx.Err()
+			}
+		}
+		if e.debug {
+			e.logger.Println(s)
+		}
+		return s, nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+func (e *Engine) readOutput(r io.ReadCloser) {
+	defer r.Close()
+	defer close(e.lines)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		e.lines <- scanner.Text()
+	}
+}
+
+func (e *Engine) wait() {
+	err := e.cmd.Wait()
+	e.exitErr = &ExitError{
+		Err:      err,
+		ExitCode: e.cmd.ProcessState.ExitCode(),
+		Stderr:   e.stderr.String(),
+	}
+	close(e.done)
+}
+
+// ErrEngineDied is matched (using errors.Is) by the errors returned once
+// the engine process has exited.
+var ErrEngineDied = errors.New("uci: engine process died")
+
+// ErrTimeout is matched (using errors.Is) by the errors returned when the
+// engine doesn't answer a command within its timeout, set by the Timeout
+// option or WithTimeout.  They also match context.DeadlineExceeded.
+var ErrTimeout = errors.New("uci: engine timed out")
+
+type timeoutError struct {
+	cmd Cmd
+}
+
+func (e *timeoutError) Error() string {
+	return fmt.Sprintf("uci: %s: %s", e.cmd, context.DeadlineExceeded)
+}
+
+// Is implements errors.Is for ErrTimeout and context.DeadlineExceeded.
+func (e *timeoutError) Is(target error) bool {
+	return target == ErrTimeout || target == context.DeadlineExceeded
+}
+
+// ExitError is returned from Run and RunContext when the engine process
+// has exited.  It matches ErrEngineDied.
+type ExitError struct {
+	// Err is the error returned from waiting on the process, which is nil
+	// if it exited cleanly.
+	Err error
+	// ExitCode is the exit code of the process or -1 if it was killed by a signal.
+	ExitCode int
+	// Stderr holds the last output the process wrote to its standard error.
+	Stderr string
+}
+
+func (e *ExitError) Error() string {
+	s := fmt.Sprintf("%s with exit code %d", ErrEngineDied, e.ExitCode)
+	if e.Err != nil {
+		s += ": " + e.Err.Error()
+	}
+	if e.Stderr != "" {
+		s += ": " + strings.TrimSpace(e.Stderr)
 	}
 	return s
 }
+
+// Is implements errors.Is for ErrEngineDied.
+func (e *ExitError) Is(target error) bool {
+	return target == ErrEngineDied
+}
+
+// Unwrap returns the underlying process error.
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// WithTimeout wraps the command so that Run fails with ErrTimeout
+// if the engine hasn't finished responding to it within d.  It overrides the
+// Timeout option of the Engine for this command.
+func WithTimeout(cmd Cmd, d time.Duration) Cmd {
+	return cmdWithTimeout{Cmd: cmd, timeout: d}
+}
+
+type cmdWithTimeout struct {
+	Cmd
+	timeout time.Duration
+}
+
+// tailBuffer is an io.Writer that keeps the last max bytes written to it.
+type tailBuffer struct {
+	mu  sync.Mutex
+	buf []byte
+	max int
+}
+
+func (t *tailBuffer) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.buf = append(t.buf, p...)
+	if len(t.buf) > t.max {
+		t.buf = append(t.buf[:0], t.buf[len(t.buf)-t.max:]...)
+	}
+	return len(p), nil
+}
+
+func (t *tailBuffer) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return string(t.buf)
+}
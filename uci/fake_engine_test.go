@@ -0,0 +1,181 @@
+package uci_test
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/barakmich/chess"
+	"github.com/barakmich/chess/uci"
+)
+
+// fakeEngineEnv makes the test binary act as a scripted UCI engine instead of
+// running the tests.  Its value is a comma separated list of behaviours:
+// crash (exit when asked to search), hang (never answer isready),
+// crashonce=<file> (crash on the first search of the process that creates file)
+// and slow=<duration> (take that long to answer each search).
+const fakeEngineEnv = "UCI_FAKE_ENGINE"
+
+func TestMain(m *testing.M) {
+	if mode := os.Getenv(fakeEngineEnv); mode != "" {
+		runFakeEngine(mode)
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// newFakeEngine starts the test binary as a fake engine with the given mode.
+func newFakeEngine(t *testing.T, mode string, opts ...func(e *uci.Engine)) *uci.Engine {
+	t.Helper()
+	eng, err := uci.New(fakeEnginePath(t, mode), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { eng.Close() })
+	return eng
+}
+
+func fakeEnginePath(t *testing.T, mode string) string {
+	t.Helper()
+	t.Setenv(fakeEngineEnv, "on,"+mode)
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return exe
+}
+
+func runFakeEngine(mode string) {
+	flags := map[string]string{}
+	for _, f := range strings.Split(mode, ",") {
+		k, v, _ := strings.Cut(f, "=")
+		flags[k] = v
+	}
+	pos := chess.StartingPosition()
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "uci":
+			fmt.Println("id name Fake Engine")
+			fmt.Println("id author the chess authors")
+			fmt.Println("option name Hash type spin default 16 min 1 max 1024")
+			fmt.Println("option name Clear Hash type button")
+			fmt.Println("option name Skill Level type spin default 20 min 0 max 20")
+			fmt.Println("uciok")
+		case "isready":
+			if _, ok := flags["hang"]; ok {
+				time.Sleep(time.Hour)
+			}
+			fmt.Println("readyok")
+		case "position":
+			pos = fakePosition(fields[1:])
+		case "go":
+			if _, ok := flags["crash"]; ok {
+				fakeCrash()
+			}
+			if file, ok := flags["crashonce"]; ok {
+				if _, err := os.Stat(file); err != nil {
+					os.WriteFile(file, nil, 0644)
+					fakeCrash()
+				}
+			}
+			if d, ok := flags["slow"]; ok {
+				dur, _ := time.ParseDuration(d)
+				time.Sleep(dur)
+			}
+			fakeSearch(pos)
+		case "quit":
+			return
+		}
+	}
+}
+
+func fakeCrash() {
+	fmt.Fprintln(os.Stderr, "fake engine: segmentation fault")
+	os.Exit(3)
+}
+
+func fakePosition(args []string) *chess.Position {
+	pos := chess.StartingPosition()
+	if len(args) > 0 && args[0] == "fen" {
+		i := 1
+		for i < len(args) && args[i] != "moves" {
+			i++
+		}
+		pos = &chess.Position{}
+		if err := pos.UnmarshalText([]byte(strings.Join(args[1:i], " "))); err != nil {
+			panic(err)
+		}
+		args = args[i:]
+	} else if len(args) > 0 {
+		args = args[1:]
+	}
+	if len(args) > 0 && args[0] == "moves" {
+		for _, s := range args[1:] {
+			m, err := pos.DecodeUCI(s)
+			if err != nil {
+				panic(err)
+			}
+			pos = pos.Update(m)
+		}
+	}
+	return pos
+}
+
+// fakeSearch plays the move that wins the most material, or mates, and
+// scores the position by the material balance after it.
+func fakeSearch(pos *chess.Position) {
+	if len(pos.ValidMoves()) == 0 {
+		if pos.Status() == chess.Checkmate {
+			fmt.Println("info depth 0 score mate 0")
+		} else {
+			fmt.Println("info depth 0 score cp 0")
+		}
+		fmt.Println("bestmove (none)")
+		return
+	}
+	m, score := fakeBestMove(pos)
+	uci := pos.EncodeUCI(m)
+	fmt.Printf("info depth 1 seldepth 1 multipv 1 score %s nodes %d time 1 pv %s\n", score, len(pos.ValidMoves()), uci)
+	fmt.Println("bestmove " + uci)
+}
+
+var fakePieceValues = map[chess.PieceType]int{
+	chess.Queen:  900,
+	chess.Rook:   500,
+	chess.Bishop: 300,
+	chess.Knight: 300,
+	chess.Pawn:   100,
+}
+
+// fakeBestMove returns the fake engine's choice in pos and its score from
+// the side to move's point of view.  Ties go to the first valid move.
+func fakeBestMove(pos *chess.Position) (chess.Move, string) {
+	var best chess.Move
+	bestCP := 0
+	for i, m := range pos.ValidMoves() {
+		next := pos.Update(m)
+		if next.Status() == chess.Checkmate {
+			return m, "mate 1"
+		}
+		cp := 0
+		for _, p := range next.Board().SquareMap() {
+			if p.Color() == pos.Turn() {
+				cp += fakePieceValues[p.Type()]
+			} else {
+				cp -= fakePieceValues[p.Type()]
+			}
+		}
+		if i == 0 || cp > bestCP {
+			best, bestCP = m, cp
+		}
+	}
+	return best, fmt.Sprintf("cp %d", bestCP)
+}
@@ -2,7 +2,10 @@ package uci
 
 import (
 	"errors"
+	"fmt"
+	"strconv"
 	"strings"
+	"unicode"
 )
 
 // Option corresponds to the "option" engine output:
@@ -116,22 +119,25 @@ type Option struct {
 // UnmarshalText implements the encoding.TextUnmarshaler interface and parses
 // data like the following:
 // option name EvalFile type string default nn-82215d0fd0df.nnue
+// Names, defaults and vars may contain spaces (e.g. "option name Clear Hash type button")
+// and the string default "<empty>" is decoded as an empty string.
 func (o *Option) UnmarshalText(text []byte) error {
-	o.Type = OptionNoType
-	parts := strings.Split(string(text), " ")
-	if len(parts) == 0 {
-		return errors.New("uci: invalid option line")
-	}
-	if parts[0] != "option" {
+	*o = Option{Type: OptionNoType}
+	line := string(text)
+	parts := optionFields(line)
+	if len(parts) == 0 || line[parts[0][0]:parts[0][1]] != "option" {
 		return errors.New("uci: invalid option line")
 	}
 	ref := ""
-	for i := 1; i < len(parts); i++ {
-		s := parts[i]
-		if ref == "" {
-			ref = s
-			continue
+	// start and end are the offsets of the current field's value in line,
+	// which is sliced rather than joined to keep the spaces within it.
+	start, end := -1, -1
+	flush := func() error {
+		s := ""
+		if start != -1 {
+			s = line[start:end]
 		}
+		start, end = -1, -1
 		switch ref {
 		case "name":
 			o.Name = s
@@ -142,6 +148,9 @@ func (o *Option) UnmarshalText(text []byte) error {
 			}
 			o.Type = ot
 		case "default":
+			if s == optionEmptyString {
+				s = ""
+			}
 			o.Default = s
 		case "min":
 			o.Min = s
@@ -150,7 +159,24 @@ func (o *Option) UnmarshalText(text []byte) error {
 		case "var":
 			o.Vars = append(o.Vars, s)
 		}
-		ref = ""
+		return nil
+	}
+	for _, part := range parts[1:] {
+		s := line[part[0]:part[1]]
+		if isOptionKeyword(s, ref) {
+			if err := flush(); err != nil {
+				return err
+			}
+			ref = s
+			continue
+		}
+		if start == -1 {
+			start = part[0]
+		}
+		end = part[1]
+	}
+	if err := flush(); err != nil {
+		return err
 	}
 	if o.Name == "" || o.Type == OptionNoType {
 		return errors.New("uci: invalid option line")
@@ -158,6 +184,79 @@ func (o *Option) UnmarshalText(text []byte) error {
 	return nil
 }
 
+// optionFields returns the start and end offsets of the space separated
+// fields of line.
+func optionFields(line string) [][2]int {
+	var fields [][2]int
+	start := -1
+	for i, r := range line {
+		if unicode.IsSpace(r) {
+			if start != -1 {
+				fields = append(fields, [2]int{start, i})
+				start = -1
+			}
+		} else if start == -1 {
+			start = i
+		}
+	}
+	if start != -1 {
+		fields = append(fields, [2]int{start, len(line)})
+	}
+	return fields
+}
+
+// isOptionKeyword reports whether s starts a new field of an option line,
+// given the field currently being read.  Only "type" may end a name, so that
+// names such as "Skill Level" or "Move Overhead" are kept intact.
+func isOptionKeyword(s, ref string) bool {
+	if ref == "name" {
+		return s == "type"
+	}
+	switch s {
+	case "name", "type", "default", "min", "max", "var":
+		return true
+	}
+	return false
+}
+
+// optionEmptyString is the value engines use to denote an empty string.
+const optionEmptyString = "<empty>"
+
+// Validate returns an error if value isn't acceptable for the option:
+// check options take true or false, spin options take an integer within
+// Min and Max, combo options take one of Vars and button options take no value.
+func (o Option) Validate(value string) error {
+	switch o.Type {
+	case OptionCheck:
+		if value != "true" && value != "false" {
+			return fmt.Errorf("uci: option %s expects true or false but got %q", o.Name, value)
+		}
+	case OptionSpin:
+		v, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("uci: option %s expects an integer but got %q", o.Name, value)
+		}
+		if min, err := strconv.Atoi(o.Min); err == nil && v < min {
+			return fmt.Errorf("uci: option %s value %d is below the minimum %d", o.Name, v, min)
+		}
+		if max, err := strconv.Atoi(o.Max); err == nil && v > max {
+			return fmt.Errorf("uci: option %s value %d is above the maximum %d", o.Name, v, max)
+		}
+	case OptionCombo:
+		for _, s := range o.Vars {
+			if strings.EqualFold(s, value) {
+				return nil
+			}
+		}
+		return fmt.Errorf("uci: option %s value %q is not one of %s", o.Name, value, strings.Join(o.Vars, ", "))
+	case OptionButton:
+		if value != "" {
+			return fmt.Errorf("uci: button option %s doesn't take a value", o.Name)
+		}
+	}
+	return nil
+}
+
 // OptionType corresponds to the "option"'s type engine output:
 // * type
 // The option has type t.
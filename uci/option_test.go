@@ -0,0 +1,128 @@
+package uci_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/barakmich/chess/uci"
+)
+
+var optionTests = []struct {
+	Line   string
+	Option uci.Option
+}{
+	{
+		Line:   "option name Clear Hash type button",
+		Option: uci.Option{Name: "Clear Hash", Type: uci.OptionButton},
+	},
+	{
+		Line:   "option name Skill Level type spin default 20 min 0 max 20",
+		Option: uci.Option{Name: "Skill Level", Type: uci.OptionSpin, Default: "20", Min: "0", Max: "20"},
+	},
+	{
+		Line:   "option name SyzygyPath type string default <empty>",
+		Option: uci.Option{Name: "SyzygyPath", Type: uci.OptionString},
+	},
+	{
+		Line:   "option name Debug Log File type string default",
+		Option: uci.Option{Name: "Debug Log File", Type: uci.OptionString},
+	},
+	{
+		Line:   "option name NalimovPath type string default c:\\chess tb\\4",
+		Option: uci.Option{Name: "NalimovPath", Type: uci.OptionString, Default: "c:\\chess tb\\4"},
+	},
+	{
+		Line:   "option name Analysis Contempt type combo default Both var Off var White var Black var Both",
+		Option: uci.Option{Name: "Analysis Contempt", Type: uci.OptionCombo, Default: "Both", Vars: []string{"Off", "White", "Black", "Both"}},
+	},
+	{
+		Line:   "option name Style type combo default Very Solid var Very Solid var Risky Play",
+		Option: uci.Option{Name: "Style", Type: uci.OptionCombo, Default: "Very Solid", Vars: []string{"Very Solid", "Risky Play"}},
+	},
+	{
+		Line:   "option name Greeting type string default foo  bar",
+		Option: uci.Option{Name: "Greeting", Type: uci.OptionString, Default: "foo  bar"},
+	},
+	{
+		Line:   "option name Style  Name type combo default Very  Solid var Very  Solid var Risky\tPlay ",
+		Option: uci.Option{Name: "Style  Name", Type: uci.OptionCombo, Default: "Very  Solid", Vars: []string{"Very  Solid", "Risky\tPlay"}},
+	},
+	{
+		Line:   "option name UCI_ShowWDL type check default false",
+		Option: uci.Option{Name: "UCI_ShowWDL", Type: uci.OptionCheck, Default: "false"},
+	},
+}
+
+func TestOptionUnmarshalText(t *testing.T) {
+	for _, test := range optionTests {
+		o := uci.Option{}
+		if err := o.UnmarshalText([]byte(test.Line)); err != nil {
+			t.Fatalf("%s: unexpected error %s", test.Line, err)
+		}
+		if !reflect.DeepEqual(o, test.Option) {
+			t.Fatalf("%s: expected %+v but got %+v", test.Line, test.Option, o)
+		}
+	}
+}
+
+func TestInvalidOptionUnmarshalText(t *testing.T) {
+	lines := []string{
+		"",
+		"id name Stockfish 14.1",
+		"option name Hash",
+		"option type spin default 1",
+		"option name Hash type knob",
+	}
+	for _, line := range lines {
+		o := uci.Option{}
+		if err := o.UnmarshalText([]byte(line)); err == nil {
+			t.Fatalf("%q: expected an error", line)
+		}
+	}
+}
+
+func TestOptionValidate(t *testing.T) {
+	spin := uci.Option{Name: "Skill Level", Type: uci.OptionSpin, Min: "0", Max: "20"}
+	combo := uci.Option{Name: "Style", Type: uci.OptionCombo, Vars: []string{"Solid", "Risky"}}
+	check := uci.Option{Name: "Ponder", Type: uci.OptionCheck}
+	button := uci.Option{Name: "Clear Hash", Type: uci.OptionButton}
+	tests := []struct {
+		Option uci.Option
+		Value  string
+		Valid  bool
+	}{
+		{spin, "10", true},
+		{spin, "0", true},
+		{spin, "21", false},
+		{spin, "-1", false},
+		{spin, "ten", false},
+		{combo, "Risky", true},
+		{combo, "Normal", false},
+		{check, "true", true},
+		{check, "yes", false},
+		{button, "", true},
+		{button, "true", false},
+	}
+	for _, test := range tests {
+		err := test.Option.Validate(test.Value)
+		if (err == nil) != test.Valid {
+			t.Fatalf("%s=%q: expected valid %t but got error %v", test.Option.Name, test.Value, test.Valid, err)
+		}
+	}
+}
+
+func TestCmdSetOptionString(t *testing.T) {
+	tests := []struct {
+		Cmd      uci.CmdSetOption
+		Expected string
+	}{
+		{uci.CmdSetOption{Name: "Clear Hash"}, "setoption name Clear Hash"},
+		{uci.CmdSetOption{Name: "Skill Level", Value: "5"}, "setoption name Skill Level value 5"},
+		{uci.CmdSetOption{Name: "NalimovPath", Value: "c:\\chess tb\\4"}, "setoption name NalimovPath value c:\\chess tb\\4"},
+	}
+	for _, test := range tests {
+		if s := test.Cmd.String(); s != test.Expected {
+			t.Fatalf("expected %q but got %q", test.Expected, s)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package uci
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/barakmich/chess"
+)
+
+// Job is a unit of analysis for a Pool: the position (after playing Moves
+// from Position) and the CmdGo used to search it.  A nil Position is the
+// starting position.
+type Job struct {
+	Position *chess.Position
+	Moves    []chess.Move
+	Go       CmdGo
+}
+
+// Result is the outcome of a Job.  Index is the position of the Job in the
+// stream given to Analyze.
+type Result struct {
+	Index         int
+	Job           Job
+	SearchResults SearchResults
+	Err           error
+}
+
+// Pool runs analysis jobs across several engine processes that share the
+// same options.  Each engine is run by a Supervisor so an engine that dies
+// is restarted.  Pool is safe for concurrent use.
+type Pool struct {
+	engines chan *Supervisor
+	all     []*Supervisor
+}
+
+// NewPool starts size engines from the executable path, initializes them with
+// CmdUCI and the given options and returns a Pool for them.  The opts are
+// applied to every engine process (see New).
+func NewPool(path string, size int, options []CmdSetOption, opts ...func(e *Engine)) (*Pool, error) {
+	if size < 1 {
+		return nil, errors.New("uci: pool size must be at least one")
+	}
+	setup := []Cmd{CmdUCI}
+	for _, o := range options {
+		setup = append(setup, o)
+	}
+	setup = append(setup, CmdIsReady)
+	p := &Pool{engines: make(chan *Supervisor, size)}
+	for i := 0; i < size; i++ {
+		s, err := NewSupervisor(path, opts...)
+		if err == nil {
+			p.all = append(p.all, s)
+			err = s.Run(setup...)
+		}
+		if err != nil {
+			p.Close()
+			return nil, err
+		}
+		p.engines <- s
+	}
+	return p, nil
+}
+
+// Size returns the number of engines in the pool.
+func (p *Pool) Size() int {
+	return len(p.all)
+}
+
+// Do runs a single job on the next free engine.
+func (p *Pool) Do(ctx context.Context, job Job) Result {
+	select {
+	case s := <-p.engines:
+		defer func() { p.engines <- s }()
+		return runJob(ctx, s, job)
+	case <-ctx.Done():
+		return Result{Job: job, Err: ctx.Err()}
+	}
+}
+
+// Analyze runs the jobs received from the channel across the pool and sends
+// their results, in the order the jobs were received, on the returned channel.
+// At most twice as many jobs as there are engines are in flight, so a slow
+// reader of the results holds up reading further jobs.  The returned channel
+// is closed once the jobs channel is closed and all of its results are sent,
+// or once ctx is done.
+func (p *Pool) Analyze(ctx context.Context, jobs <-chan Job) <-chan Result {
+	out := make(chan Result)
+	go p.analyze(ctx, jobs, out)
+	return out
+}
+
+// AnalyzeAll runs the jobs across the pool and returns their results in order.
+// The returned error is ctx's error if it was done before all jobs finished.
+func (p *Pool) AnalyzeAll(ctx context.Context, jobs []Job) ([]Result, error) {
+	in := make(chan Job)
+	go func() {
+		defer close(in)
+		for _, job := range jobs {
+			select {
+			case in <- job:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	results := make([]Result, 0, len(jobs))
+	for r := range p.Analyze(ctx, in) {
+		results = append(results, r)
+	}
+	return results, ctx.Err()
+}
+
+// Close closes all engines in the pool.
+func (p *Pool) Close() error {
+	var err error
+	for _, s := range p.all {
+		if cerr := s.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+type indexedJob struct {
+	index int
+	job   Job
+}
+
+func (p *Pool) analyze(ctx context.Context, jobs <-chan Job, out chan<- Result) {
+	defer close(out)
+	window := make(chan struct{}, 2*p.Size())
+	work := make(chan indexedJob)
+	results := make(chan Result)
+
+	go func() {
+		defer close(work)
+		for i := 0; ; i++ {
+			select {
+			case window <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case job, ok := <-jobs:
+				if !ok {
+					return
+				}
+				work <- indexedJob{index: i, job: job}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < p.Size(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for w := range work {
+				r := p.Do(ctx, w.job)
+				r.Index = w.index
+				results <- r
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	pending := map[int]Result{}
+	next := 0
+	for r := range results {
+		pending[r.Index] = r
+		for {
+			r, ok := pending[next]
+			if !ok {
+				break
+			}
+			if ctx.Err() == nil {
+				select {
+				case out <- r:
+				case <-ctx.Done():
+				}
+			}
+			delete(pending, next)
+			next++
+			<-window
+		}
+	}
+}
+
+// jobCleanupTimeout bounds how long an engine may take to finish a search
+// that was abandoned because its context was done.
+const jobCleanupTimeout = 10 * time.Second
+
+func runJob(ctx context.Context, s *Supervisor, job Job) Result {
+	cmds := []Cmd{
+		CmdUCINewGame,
+		CmdIsReady,
+		CmdPosition{Position: job.Position, Moves: job.Moves},
+		job.Go,
+	}
+	r := Result{Job: job}
+	r.Err = s.RunContext(ctx, cmds...)
+	if r.Err == nil {
+		r.SearchResults = s.SearchResults()
+	} else if ctx.Err() != nil {
+		// The engine may still be searching, stop it and wait for it to
+		// catch up so its stale output doesn't leak into the next job.
+		s.Run(CmdStop)
+		s.Run(WithTimeout(CmdIsReady, jobCleanupTimeout))
+	}
+	return r
+}
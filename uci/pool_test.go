@@ -0,0 +1,95 @@
+package uci_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/barakmich/chess"
+	"github.com/barakmich/chess/uci"
+)
+
+func poolJobs(t *testing.T) []uci.Job {
+	t.Helper()
+	g := chess.NewGame()
+	for _, s := range []string{"e4", "e5", "Nf3", "Nc6", "Bb5", "a6", "Ba4", "Nf6", "O-O", "Be7"} {
+		if err := g.MoveStr(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	jobs := []uci.Job{}
+	moves := g.Moves()
+	for i := range moves {
+		jobs = append(jobs, uci.Job{Moves: moves[:i], Go: uci.CmdGo{Depth: 1}})
+	}
+	return jobs
+}
+
+func TestPoolAnalyzeAll(t *testing.T) {
+	pool, err := uci.NewPool(fakeEnginePath(t, ""), 3, []uci.CmdSetOption{{Name: "Hash", Value: "32"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	jobs := poolJobs(t)
+	results, err := pool.AnalyzeAll(context.Background(), jobs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != len(jobs) {
+		t.Fatalf("expected %d results but got %d", len(jobs), len(results))
+	}
+	pos := chess.StartingPosition()
+	for i, r := range results {
+		if r.Err != nil {
+			t.Fatal(r.Err)
+		}
+		if r.Index != i {
+			t.Fatalf("expected result %d but got %d", i, r.Index)
+		}
+		expected, _ := fakeBestMove(pos)
+		if !r.SearchResults.BestMove.Eq(expected) {
+			t.Fatalf("result %d: expected best move %s but got %s", i, expected, r.SearchResults.BestMove)
+		}
+		if i < len(jobs)-1 {
+			pos = pos.Update(jobs[i+1].Moves[i])
+		}
+	}
+}
+
+func TestPoolOptionValidation(t *testing.T) {
+	_, err := uci.NewPool(fakeEnginePath(t, ""), 2, []uci.CmdSetOption{{Name: "Hash", Value: "4096"}})
+	if err == nil {
+		t.Fatal("expected an out of range option to fail")
+	}
+}
+
+func TestPoolCancel(t *testing.T) {
+	pool, err := uci.NewPool(fakeEnginePath(t, "slow=200ms"), 2, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	results, err := pool.AnalyzeAll(ctx, poolJobs(t))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected a deadline error but got %v", err)
+	}
+	if len(results) >= len(poolJobs(t)) {
+		t.Fatalf("expected analysis to stop early but got %d results", len(results))
+	}
+	if time.Since(start) > 2*time.Second {
+		t.Fatal("cancellation took too long")
+	}
+	// the pool is still usable after cancellation
+	r := pool.Do(context.Background(), uci.Job{Go: uci.CmdGo{Depth: 1}})
+	if r.Err != nil {
+		t.Fatal(r.Err)
+	}
+	if expected, _ := fakeBestMove(chess.StartingPosition()); !r.SearchResults.BestMove.Eq(expected) {
+		t.Fatalf("expected a fresh search result but got %s", r.SearchResults.BestMove)
+	}
+}
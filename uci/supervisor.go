@@ -0,0 +1,206 @@
+package uci
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+)
+
+// Supervisor runs an Engine and restarts it when the process dies or a
+// command times out (see ErrEngineDied and ErrTimeout).  After a restart
+// the CmdUCI and CmdSetOption commands previously run through the
+// Supervisor are replayed, except for those pressing button options,
+// followed by the most recent CmdPosition, and then the failed commands are
+// retried.
+// Supervisor is safe for concurrent use.
+type Supervisor struct {
+	// MaxRestarts is the number of restarts in a row, without a command
+	// succeeding in between, after which the Supervisor gives up and returns
+	// the engine's error.  A negative value means no limit.
+	MaxRestarts int
+
+	path string
+	opts []func(e *Engine)
+
+	runMu     sync.Mutex
+	mu        sync.Mutex
+	eng       *Engine
+	handshake []Cmd
+	position  Cmd
+	restarts  int
+	failures  int
+}
+
+// NewSupervisor starts the engine at path (see New) and returns a Supervisor
+// for it.  The options are applied to every engine process it starts.
+func NewSupervisor(path string, opts ...func(e *Engine)) (*Supervisor, error) {
+	eng, err := New(path, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &Supervisor{
+		MaxRestarts: 3,
+		path:        path,
+		opts:        opts,
+		eng:         eng,
+	}, nil
+}
+
+// Engine returns the currently running engine.
+func (s *Supervisor) Engine() *Engine {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.eng
+}
+
+// Restarts returns the number of times the engine has been restarted.
+func (s *Supervisor) Restarts() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.restarts
+}
+
+// ID returns the id values of the running engine.
+func (s *Supervisor) ID() map[string]string {
+	return s.Engine().ID()
+}
+
+// Options returns the options of the running engine.
+func (s *Supervisor) Options() map[string]Option {
+	return s.Engine().Options()
+}
+
+// SearchResults returns results from the most recent CmdGo invocation.
+func (s *Supervisor) SearchResults() SearchResults {
+	return s.Engine().SearchResults()
+}
+
+// Run runs the commands on the engine, restarting it as needed.
+func (s *Supervisor) Run(cmds ...Cmd) error {
+	return s.RunContext(context.Background(), cmds...)
+}
+
+// RunContext runs the commands on the engine, restarting it and retrying the
+// commands if the engine dies or times out.  Once ctx is done its error is
+// returned without restarting the engine, which discards the interrupted
+// commands' output before running the next ones.
+func (s *Supervisor) RunContext(ctx context.Context, cmds ...Cmd) error {
+	// CmdStop has to get through while a search is holding runMu.
+	if len(cmds) == 1 && cmds[0].String() == CmdStop.Name {
+		return s.Engine().RunContext(ctx, cmds...)
+	}
+	s.runMu.Lock()
+	defer s.runMu.Unlock()
+	err := s.Engine().RunContext(ctx, cmds...)
+	for err != nil {
+		if !restartable(err) || ctx.Err() != nil {
+			return err
+		}
+		if s.MaxRestarts >= 0 && s.failed() > s.MaxRestarts {
+			return err
+		}
+		rerr := s.restart(ctx)
+		if ctx.Err() != nil {
+			return err
+		}
+		if rerr != nil {
+			err = rerr
+			continue
+		}
+		err = s.Engine().RunContext(ctx, cmds...)
+	}
+	s.record(cmds)
+	return nil
+}
+
+// restartable returns true for errors from the engine dying or timing out,
+// but not from the caller's context.
+func restartable(err error) bool {
+	return errors.Is(err, ErrEngineDied) || errors.Is(err, ErrTimeout)
+}
+
+// failed counts a failed run of commands and returns the number of them in
+// a row.
+func (s *Supervisor) failed() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures++
+	return s.failures
+}
+
+// Close closes the running engine.
+func (s *Supervisor) Close() error {
+	s.runMu.Lock()
+	defer s.runMu.Unlock()
+	return s.Engine().Close()
+}
+
+func (s *Supervisor) record(cmds []Cmd) {
+	options := s.Engine().Options()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = 0
+	for _, cmd := range cmds {
+		if t, ok := cmd.(cmdWithTimeout); ok {
+			cmd = t.Cmd
+		}
+		switch c := cmd.(type) {
+		case cmdNoOptions:
+			if c.Name == CmdUCI.Name && len(s.handshake) == 0 {
+				s.handshake = append(s.handshake, CmdUCI)
+			}
+		case CmdSetOption:
+			// pressing a button is an action, not a setting to restore
+			if isButton(options, c.Name) {
+				continue
+			}
+			replaced := false
+			for i, h := range s.handshake {
+				if o, ok := h.(CmdSetOption); ok && strings.EqualFold(o.Name, c.Name) {
+					s.handshake[i] = c
+					replaced = true
+				}
+			}
+			if !replaced {
+				s.handshake = append(s.handshake, c)
+			}
+		case CmdPosition:
+			s.position = c
+		}
+	}
+}
+
+func isButton(options map[string]Option, name string) bool {
+	for n, o := range options {
+		if strings.EqualFold(n, name) {
+			return o.Type == OptionButton
+		}
+	}
+	return false
+}
+
+func (s *Supervisor) restart(ctx context.Context) error {
+	s.mu.Lock()
+	old := s.eng
+	s.restarts++
+	replay := append([]Cmd(nil), s.handshake...)
+	if len(replay) != 0 {
+		replay = append(replay, CmdIsReady)
+	}
+	if s.position != nil {
+		replay = append(replay, s.position)
+	}
+	s.mu.Unlock()
+
+	old.cmd.Process.Kill()
+	<-old.Done()
+	eng, err := New(s.path, s.opts...)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.eng = eng
+	s.mu.Unlock()
+	return eng.RunContext(ctx, replay...)
+}
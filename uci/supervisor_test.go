@@ -0,0 +1,223 @@
+package uci_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/barakmich/chess"
+	"github.com/barakmich/chess/uci"
+)
+
+func TestFakeEngine(t *testing.T) {
+	eng := newFakeEngine(t, "")
+	setPos := uci.CmdPosition{Position: chess.StartingPosition()}
+	if err := eng.Run(uci.CmdUCI, uci.CmdIsReady, uci.CmdUCINewGame, setPos, uci.CmdGo{Depth: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if eng.ID()["name"] != "Fake Engine" {
+		t.Fatalf("expected engine name Fake Engine but got %s", eng.ID()["name"])
+	}
+	if _, ok := eng.Options()["Clear Hash"]; !ok {
+		t.Fatalf("expected Clear Hash option in %v", eng.Options())
+	}
+	if eng.SearchResults().BestMove == 0 {
+		t.Fatal("expected a best move")
+	}
+	if err := eng.Run(uci.CmdSetOption{Name: "Skill Level", Value: "21"}); err == nil {
+		t.Fatal("expected an out of range setoption to fail")
+	}
+}
+
+func TestEngineDied(t *testing.T) {
+	eng := newFakeEngine(t, "crash")
+	err := eng.Run(uci.CmdUCI, uci.CmdIsReady, uci.CmdGo{Depth: 1})
+	if !errors.Is(err, uci.ErrEngineDied) {
+		t.Fatalf("expected ErrEngineDied but got %v", err)
+	}
+	var exitErr *uci.ExitError
+	if !errors.As(err, &exitErr) {
+		t.Fatalf("expected an ExitError but got %T", err)
+	}
+	if exitErr.ExitCode != 3 {
+		t.Fatalf("expected exit code 3 but got %d", exitErr.ExitCode)
+	}
+	if !strings.Contains(exitErr.Stderr, "segmentation fault") {
+		t.Fatalf("expected stderr to be captured but got %q", exitErr.Stderr)
+	}
+	select {
+	case <-eng.Done():
+	default:
+		t.Fatal("expected engine to be done")
+	}
+	if err := eng.Run(uci.CmdIsReady); !errors.Is(err, uci.ErrEngineDied) {
+		t.Fatalf("expected ErrEngineDied but got %v", err)
+	}
+}
+
+func TestEngineTimeout(t *testing.T) {
+	eng := newFakeEngine(t, "hang", uci.Timeout(100*time.Millisecond))
+	if err := eng.Run(uci.CmdUCI); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	err := eng.Run(uci.CmdIsReady)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected a deadline error but got %v", err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Fatal("timeout took too long")
+	}
+}
+
+func TestEngineRunContext(t *testing.T) {
+	eng := newFakeEngine(t, "hang")
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		cancel()
+	}()
+	if err := eng.RunContext(ctx, uci.CmdIsReady); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected a cancelation error but got %v", err)
+	}
+	err := eng.Run(uci.WithTimeout(uci.CmdIsReady, 100*time.Millisecond))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected a deadline error but got %v", err)
+	}
+}
+
+func TestEngineTimeoutResync(t *testing.T) {
+	eng := newFakeEngine(t, "slow=300ms")
+	if err := eng.Run(uci.CmdUCI, uci.CmdPosition{Position: chess.StartingPosition()}); err != nil {
+		t.Fatal(err)
+	}
+	err := eng.Run(uci.WithTimeout(uci.CmdGo{Depth: 1}, 100*time.Millisecond))
+	if !errors.Is(err, uci.ErrTimeout) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected a timeout but got %v", err)
+	}
+	// the late answer to the first search can't be white's best move here,
+	// exd5
+	g := chess.NewGame()
+	for _, m := range []string{"e4", "d5"} {
+		if err := g.MoveStr(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pos := g.Position()
+	if err := eng.Run(uci.CmdPosition{Position: pos}, uci.CmdGo{Depth: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if s := eng.SearchResults().BestMove.String(); s != "e4d5" {
+		t.Fatalf("expected best move e4d5 but got %s", s)
+	}
+}
+
+func TestSupervisorRestart(t *testing.T) {
+	b := &bytes.Buffer{}
+	logger := log.New(b, "", 0)
+	path := fakeEnginePath(t, "crashonce="+filepath.Join(t.TempDir(), "crashed"))
+	s, err := uci.NewSupervisor(path, uci.Debug, uci.Logger(logger))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	setOpt := uci.CmdSetOption{Name: "Skill Level", Value: "5"}
+	setPos := uci.CmdPosition{Position: chess.StartingPosition()}
+	if err := s.Run(uci.CmdUCI, setOpt, uci.CmdIsReady, setPos); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Run(uci.CmdGo{Depth: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if s.Restarts() != 1 {
+		t.Fatalf("expected one restart but got %d", s.Restarts())
+	}
+	if s.SearchResults().BestMove == 0 {
+		t.Fatal("expected a best move after restart")
+	}
+	if n := strings.Count(b.String(), setOpt.String()); n != 2 {
+		t.Fatalf("expected setoption to be replayed once but it was sent %d times:\n%s", n, b.String())
+	}
+}
+
+func TestSupervisorRestartButton(t *testing.T) {
+	b := &bytes.Buffer{}
+	logger := log.New(b, "", 0)
+	path := fakeEnginePath(t, "crashonce="+filepath.Join(t.TempDir(), "crashed"))
+	s, err := uci.NewSupervisor(path, uci.Debug, uci.Logger(logger))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	clearHash := uci.CmdSetOption{Name: "clear hash"}
+	hash := uci.CmdSetOption{Name: "Hash", Value: "32"}
+	if err := s.Run(uci.CmdUCI, clearHash, hash, uci.CmdIsReady); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Run(uci.CmdGo{Depth: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if s.Restarts() != 1 {
+		t.Fatalf("expected one restart but got %d", s.Restarts())
+	}
+	if n := strings.Count(b.String(), clearHash.String()); n != 1 {
+		t.Fatalf("expected the button to be pressed once but it was sent %d times:\n%s", n, b.String())
+	}
+	if n := strings.Count(b.String(), hash.String()); n != 2 {
+		t.Fatalf("expected setoption to be replayed once but it was sent %d times:\n%s", n, b.String())
+	}
+}
+
+func TestSupervisorContextDeadline(t *testing.T) {
+	s, err := uci.NewSupervisor(fakeEnginePath(t, "slow=300ms"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if err := s.Run(uci.CmdUCI, uci.CmdIsReady); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	err = s.RunContext(ctx, uci.CmdGo{Depth: 1})
+	if !errors.Is(err, context.DeadlineExceeded) || errors.Is(err, uci.ErrTimeout) {
+		t.Fatalf("expected the context's deadline error but got %v", err)
+	}
+	if err := s.Run(uci.CmdGo{Depth: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if s.Restarts() != 0 {
+		t.Fatalf("expected no restarts but got %d", s.Restarts())
+	}
+}
+
+func TestSupervisorMaxRestarts(t *testing.T) {
+	crashed := filepath.Join(t.TempDir(), "crashed")
+	s, err := uci.NewSupervisor(fakeEnginePath(t, "crashonce="+crashed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	s.MaxRestarts = 1
+	if err := s.Run(uci.CmdUCI, uci.CmdIsReady); err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 3; i++ {
+		// the running engine crashes on its next search
+		if err := os.Remove(crashed); err != nil && !os.IsNotExist(err) {
+			t.Fatal(err)
+		}
+		if err := s.Run(uci.CmdGo{Depth: 1}); err != nil {
+			t.Fatalf("expected restart %d to succeed but got %v", i, err)
+		}
+		if s.Restarts() != i {
+			t.Fatalf("expected %d restarts but got %d", i, s.Restarts())
+		}
+	}
+}
@@ -0,0 +1,323 @@
+package chess
+
+import "strings"
+
+// A Variant is a set of rules for playing chess.  Positions and games
+// follow the rules of standard chess unless they are created for another
+// variant, with NewVariantGame or NewVariantGameFromFEN, or decoded from a
+// PGN with a Variant tag.
+type Variant interface {
+	// Name returns the name of the variant as it's written in the PGN
+	// Variant tag.
+	Name() string
+	// StartingPosition returns the position games of the variant start
+	// from.
+	StartingPosition() *Position
+
+	// moves returns the legal moves of the position, or only the first
+	// one found if first is true.
+	moves(pos *Position, first bool) []Move
+	// result returns the outcome of the game at the position and the
+	// method that decided it, or NoOutcome and NoMethod if it goes on.
+	result(pos *Position) (Outcome, Method)
+	// update updates the state the variant keeps in the position after
+	// a move.
+	update(prev, next *Position, m Move)
+	// insufficientMaterial returns true if neither side can win.
+	insufficientMaterial(pos *Position) bool
+	// inCheck returns true if the color's king is in check on the board.
+	inCheck(b *Board, c Color) bool
+}
+
+var (
+	// Standard is the rules of standard chess.
+	Standard Variant = standard{}
+	// KingOfTheHill is standard chess where a player also wins by moving
+	// their king to one of the four centre squares.
+	KingOfTheHill Variant = kingOfTheHill{}
+	// ThreeCheck is standard chess where a player also wins by giving
+	// check three times.  Its FEN ends with the number of checks given
+	// by each side, like +1+0.
+	ThreeCheck Variant = threeCheck{}
+	// RacingKings is a race of the kings to the eighth rank, starting
+	// without pawns, where giving check is illegal.  If white's king gets
+	// there first black gets one more move, and the game is drawn if
+	// black's king gets there too.
+	RacingKings Variant = racingKings{}
+	// Crazyhouse is standard chess where captured pieces go to the
+	// capturer's pocket, and can be dropped back on the board as a move.
+	// Its FEN has the pockets after the board, like RNBQKBNR[Qp], and
+	// marks promoted pieces, which go back to the pocket as pawns, with
+	// a ~.
+	Crazyhouse Variant = crazyhouse{}
+	// Antichess is chess where capturing is compulsory and a player wins
+	// by losing all their pieces or being stalemated.  The king is an
+	// ordinary piece that can be captured, pawns can promote to it, and
+	// there is no castling.
+	Antichess Variant = antichess{}
+	// Atomic is standard chess where a capture explodes, removing the
+	// captured and capturing pieces and every piece but pawns around
+	// them.  A player wins by exploding the other king, so kings can't
+	// capture, and a king next to the other king can't be in check.
+	Atomic Variant = atomic{}
+)
+
+var variants = []Variant{Standard, KingOfTheHill, ThreeCheck, RacingKings, Crazyhouse, Antichess, Atomic}
+
+// variantAliases are other names for variants, normalized like
+// variantKey.
+var variantAliases = map[string]Variant{
+	"":             Standard,
+	"fromposition": Standard,
+	"koth":         KingOfTheHill,
+	"3check":       ThreeCheck,
+	"giveaway":     Antichess,
+	"losingchess":  Antichess,
+	"suicide":      Antichess,
+}
+
+// VariantByName returns the variant with the name, as it's written in
+// the PGN Variant tag by Lichess and other sites.  Case, spaces and
+// hyphens are ignored, so "Three-check" and "threecheck" are the same.
+func VariantByName(name string) (Variant, bool) {
+	key := variantKey(name)
+	for _, v := range variants {
+		if variantKey(v.Name()) == key {
+			return v, true
+		}
+	}
+	v, ok := variantAliases[key]
+	return v, ok
+}
+
+func variantKey(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '_':
+			return -1
+		}
+		return r
+	}, strings.ToLower(strings.TrimSpace(name)))
+}
+
+// variantPosition returns the position of the FEN in the variant, which
+// must be valid.
+func variantPosition(v Variant, fen string) *Position {
+	pos, err := decodeVariantFEN(v, fen)
+	if err != nil {
+		panic(err)
+	}
+	return pos
+}
+
+func winner(c Color) Outcome {
+	if c == White {
+		return WhiteWon
+	}
+	return BlackWon
+}
+
+type standard struct{}
+
+func (standard) Name() string {
+	return "Standard"
+}
+
+func (standard) StartingPosition() *Position {
+	return StartingPosition()
+}
+
+func (standard) moves(pos *Position, first bool) []Move {
+	return engine{}.CalcMoves(pos, first)
+}
+
+func (standard) result(pos *Position) (Outcome, Method) {
+	switch (engine{}).Status(pos) {
+	case Checkmate:
+		return winner(pos.turn.Other()), Checkmate
+	case Stalemate:
+		return Draw, Stalemate
+	}
+	return NoOutcome, NoMethod
+}
+
+func (standard) update(prev, next *Position, m Move) {}
+
+func (standard) insufficientMaterial(pos *Position) bool {
+	return !pos.board.hasSufficientMaterial()
+}
+
+func (standard) inCheck(b *Board, c Color) bool {
+	return isInCheck(b, c)
+}
+
+var bbHill = bbForSquare(D4) | bbForSquare(E4) | bbForSquare(D5) | bbForSquare(E5)
+
+type kingOfTheHill struct {
+	standard
+}
+
+func (kingOfTheHill) Name() string {
+	return "King of the Hill"
+}
+
+func (kingOfTheHill) StartingPosition() *Position {
+	return variantPosition(KingOfTheHill, startFEN)
+}
+
+func (v kingOfTheHill) moves(pos *Position, first bool) []Move {
+	if v.winner(pos) != NoOutcome {
+		return []Move{}
+	}
+	return engine{}.CalcMoves(pos, first)
+}
+
+func (v kingOfTheHill) result(pos *Position) (Outcome, Method) {
+	if o := v.winner(pos); o != NoOutcome {
+		return o, VariantWin
+	}
+	return v.standard.result(pos)
+}
+
+// either king can always walk to the hill
+func (kingOfTheHill) insufficientMaterial(pos *Position) bool {
+	return false
+}
+
+func (kingOfTheHill) winner(pos *Position) Outcome {
+	if pos.board.bbForPiece(WhiteKing)&bbHill != 0 {
+		return WhiteWon
+	}
+	if pos.board.bbForPiece(BlackKing)&bbHill != 0 {
+		return BlackWon
+	}
+	return NoOutcome
+}
+
+// threeCheckLimit is the number of checks that wins a Three-check game.
+const threeCheckLimit = 3
+
+type threeCheck struct {
+	standard
+}
+
+func (threeCheck) Name() string {
+	return "Three-check"
+}
+
+func (threeCheck) StartingPosition() *Position {
+	return variantPosition(ThreeCheck, startFEN)
+}
+
+func (v threeCheck) moves(pos *Position, first bool) []Move {
+	if v.winner(pos) != NoOutcome {
+		return []Move{}
+	}
+	return engine{}.CalcMoves(pos, first)
+}
+
+func (v threeCheck) result(pos *Position) (Outcome, Method) {
+	if o := v.winner(pos); o != NoOutcome {
+		return o, VariantWin
+	}
+	return v.standard.result(pos)
+}
+
+func (threeCheck) update(prev, next *Position, m Move) {
+	if next.inCheck {
+		next.checks[prev.turn]++
+	}
+}
+
+// a lone king can't give check
+func (threeCheck) insufficientMaterial(pos *Position) bool {
+	b := pos.board
+	return b.occupied() == b.bbForPiece(WhiteKing)|b.bbForPiece(BlackKing)
+}
+
+func (threeCheck) winner(pos *Position) Outcome {
+	if pos.checks[White] >= threeCheckLimit {
+		return WhiteWon
+	}
+	if pos.checks[Black] >= threeCheckLimit {
+		return BlackWon
+	}
+	return NoOutcome
+}
+
+const racingKingsFEN = "8/8/8/8/8/8/krbnNBRK/qrbnNBRQ w - - 0 1"
+
+type racingKings struct {
+	standard
+}
+
+func (racingKings) Name() string {
+	return "Racing Kings"
+}
+
+func (racingKings) StartingPosition() *Position {
+	return variantPosition(RacingKings, racingKingsFEN)
+}
+
+func (v racingKings) moves(pos *Position, first bool) []Move {
+	if v.winner(pos) != NoOutcome {
+		return []Move{}
+	}
+	return v.legalMoves(pos, first)
+}
+
+func (v racingKings) result(pos *Position) (Outcome, Method) {
+	switch o := v.winner(pos); o {
+	case NoOutcome:
+	case Draw:
+		return o, VariantDraw
+	default:
+		return o, VariantWin
+	}
+	return v.standard.result(pos)
+}
+
+// a king can always race for the eighth rank
+func (racingKings) insufficientMaterial(pos *Position) bool {
+	return false
+}
+
+// legalMoves returns the moves of the position that don't give check,
+// whether or not the race is over.
+func (racingKings) legalMoves(pos *Position, first bool) []Move {
+	moves := engine{}.CalcMoves(pos, false)
+	legal := moves[:0]
+	for _, m := range moves {
+		if m.HasTag(Check) {
+			continue
+		}
+		legal = append(legal, m)
+		if first {
+			break
+		}
+	}
+	return legal
+}
+
+// winner returns the outcome of the race, which is only over when white
+// has reached the eighth rank if black can't follow on the next move.
+func (v racingKings) winner(pos *Position) Outcome {
+	whiteHome := pos.board.bbForPiece(WhiteKing)&bbRank8 != 0
+	blackHome := pos.board.bbForPiece(BlackKing)&bbRank8 != 0
+	switch {
+	case whiteHome && blackHome:
+		return Draw
+	case blackHome:
+		return BlackWon
+	case !whiteHome:
+		return NoOutcome
+	case pos.turn == White:
+		return WhiteWon
+	}
+	for _, m := range v.legalMoves(pos, false) {
+		if m.S1() == pos.board.blackKingSq && m.S2().Rank() == Rank8 {
+			return NoOutcome
+		}
+	}
+	return WhiteWon
+}
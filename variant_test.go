@@ -0,0 +1,341 @@
+package chess
+
+import (
+	"log"
+	"strings"
+	"testing"
+)
+
+func unsafeVariantFEN(v Variant, s string) *Position {
+	pos, err := decodeVariantFEN(v, s)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return pos
+}
+
+/* https://github.com/niklasf/python-chess/tree/master/examples/perft */
+var variantPerfResults = []perfTest{
+	{pos: RacingKings.StartingPosition(), nodesPerDepth: []int{
+		21, 421, 11264, 296242,
+		// 9472927
+	}},
+	{pos: unsafeVariantFEN(ThreeCheck, "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 1+1 0 1"), nodesPerDepth: []int{
+		48, 2039, 97848,
+	}},
+	{pos: KingOfTheHill.StartingPosition(), nodesPerDepth: []int{
+		20, 400, 8902, 197281,
+	}},
+	{pos: Antichess.StartingPosition(), nodesPerDepth: []int{
+		20, 400, 8067, 153299,
+	}},
+	{pos: unsafeVariantFEN(Antichess, "8/1p6/8/8/8/8/P7/8 w - - 0 1"), nodesPerDepth: []int{
+		2, 4, 4, 3, 1, 0,
+	}},
+	{pos: unsafeVariantFEN(Antichess, "8/2p5/8/8/8/8/P7/8 w - - 0 1"), nodesPerDepth: []int{
+		2, 4, 4, 4, 4, 4,
+	}},
+	{pos: Atomic.StartingPosition(), nodesPerDepth: []int{
+		20, 400, 8902, 197326,
+	}},
+	{pos: unsafeVariantFEN(Atomic, "rn2kb1r/1pp1p2p/p2q1pp1/3P4/2P3b1/4PN2/PP3PPP/R2QKB1R b KQkq - 0 1"), nodesPerDepth: []int{
+		40, 1238, 45237,
+		// 1434825
+	}},
+	{pos: unsafeVariantFEN(Atomic, "rn1qkb1r/p5pp/2p5/3p4/N3P3/5P2/PPP4P/R1BQK3 w Qkq - 0 1"), nodesPerDepth: []int{
+		28, 833, 23353,
+		// 714499
+	}},
+}
+
+func TestVariantPerfResults(t *testing.T) {
+	for _, perf := range variantPerfResults {
+		countMoves(t, perf.pos, []*Position{perf.pos}, perf.nodesPerDepth, len(perf.nodesPerDepth))
+	}
+}
+
+func TestVariantByName(t *testing.T) {
+	tests := map[string]Variant{
+		"Standard":         Standard,
+		"From Position":    Standard,
+		"King of the Hill": KingOfTheHill,
+		"kingOfTheHill":    KingOfTheHill,
+		"Three-check":      ThreeCheck,
+		"3check":           ThreeCheck,
+		"Racing Kings":     RacingKings,
+		"racingKings":      RacingKings,
+		"Antichess":        Antichess,
+		"Giveaway":         Antichess,
+		"Atomic":           Atomic,
+	}
+	for name, want := range tests {
+		v, ok := VariantByName(name)
+		if !ok || v != want {
+			t.Fatalf("expected %q to be %s but got %v", name, want.Name(), v)
+		}
+	}
+	if _, ok := VariantByName("Chess960"); ok {
+		t.Fatal("expected Chess960 to be unsupported")
+	}
+}
+
+func TestKingOfTheHill(t *testing.T) {
+	g, err := NewVariantGameFromFEN(KingOfTheHill, "7k/8/8/8/8/3K4/8/8 w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.MoveStr("Kd4"); err != nil {
+		t.Fatal(err)
+	}
+	if g.Outcome() != WhiteWon || g.Method() != VariantWin {
+		t.Fatalf("expected king on the hill to win but got %s by %s", g.Outcome(), g.Method())
+	}
+	if moves := g.ValidMoves(); len(moves) != 0 {
+		t.Fatalf("expected no moves after the game ended but got %v", moves)
+	}
+	if g.Variant() != KingOfTheHill || g.GetTagPair("Variant").Value != "King of the Hill" {
+		t.Fatal("expected the game to be King of the Hill")
+	}
+}
+
+func TestThreeCheck(t *testing.T) {
+	g := NewVariantGame(ThreeCheck)
+	for _, m := range []string{"e4", "e5", "Qh5", "Nc6", "Qxf7+", "Kxf7", "Bc4+", "d5"} {
+		if err := g.MoveStr(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if g.Outcome() != NoOutcome {
+		t.Fatalf("expected the game to go on after two checks but got %s", g.Outcome())
+	}
+	if fen := g.FEN(); !strings.HasSuffix(fen, " +2+0") {
+		t.Fatalf("expected two checks by white in %s", fen)
+	}
+	if err := g.MoveStr("Bxd5+"); err != nil {
+		t.Fatal(err)
+	}
+	if g.Outcome() != WhiteWon || g.Method() != VariantWin {
+		t.Fatalf("expected the third check to win but got %s by %s", g.Outcome(), g.Method())
+	}
+	if g.Position().Checks(White) != 3 || g.Position().Checks(Black) != 0 {
+		t.Fatalf("expected 3 checks by white but got %s", g.FEN())
+	}
+}
+
+func TestThreeCheckFENs(t *testing.T) {
+	fens := map[string]string{
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1":       "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1 +0+0",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1 +1+2":  "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1 +1+2",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 2+1 0 1":   "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1 +1+2",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1 +0+0 ": "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1 +0+0",
+	}
+	for fen, want := range fens {
+		pos, err := decodeVariantFEN(ThreeCheck, fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pos.String() != want {
+			t.Fatalf("expected %s to be %s but got %s", fen, want, pos.String())
+		}
+	}
+	for _, fen := range []string{
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1 +4+0",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1 +1",
+	} {
+		if _, err := decodeVariantFEN(ThreeCheck, fen); err == nil {
+			t.Fatalf("expected error from %s", fen)
+		}
+	}
+	if _, err := decodeFEN("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1 +0+0"); err == nil {
+		t.Fatal("expected check counts to be invalid in standard chess")
+	}
+}
+
+func TestRacingKings(t *testing.T) {
+	tests := []struct {
+		fen     string
+		moves   []string
+		outcome Outcome
+		method  Method
+	}{
+		{fen: "8/6K1/k7/8/8/8/8/8 w - - 0 1", moves: []string{"Kg8"}, outcome: WhiteWon, method: VariantWin},
+		{fen: "8/k5K1/8/8/8/8/8/8 w - - 0 1", moves: []string{"Kg8"}, outcome: NoOutcome},
+		{fen: "8/k5K1/8/8/8/8/8/8 w - - 0 1", moves: []string{"Kg8", "Ka8"}, outcome: Draw, method: VariantDraw},
+		{fen: "8/k5K1/8/8/8/8/8/8 w - - 0 1", moves: []string{"Kg8", "Kb6"}, outcome: WhiteWon, method: VariantWin},
+		{fen: "8/k7/8/6K1/8/8/8/8 b - - 0 1", moves: []string{"Ka8"}, outcome: BlackWon, method: VariantWin},
+	}
+	for _, test := range tests {
+		g, err := NewVariantGameFromFEN(RacingKings, test.fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, m := range test.moves {
+			if err := g.MoveStr(m); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if g.Outcome() != test.outcome || g.Method() != test.method {
+			t.Fatalf("expected %s after %v to be %s by %s but got %s by %s",
+				test.fen, test.moves, test.outcome, test.method, g.Outcome(), g.Method())
+		}
+	}
+	// giving check is illegal
+	g, err := NewVariantGameFromFEN(RacingKings, "8/8/8/8/8/8/k7/6RK w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.MoveStr("Ra1"); err == nil {
+		t.Fatal("expected Ra1+ to be illegal")
+	}
+	if err := g.MoveStr("Rg2"); err == nil {
+		t.Fatal("expected Rg2+ to be illegal")
+	}
+}
+
+func TestAntichess(t *testing.T) {
+	g := NewVariantGame(Antichess)
+	for _, m := range []string{"e3", "b5"} {
+		if err := g.MoveStr(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := g.MoveStr("Nf3"); err == nil {
+		t.Fatal("expected Nf3 to be illegal when Bxb5 can capture")
+	}
+	if err := g.MoveStr("Bxb5"); err != nil {
+		t.Fatal(err)
+	}
+
+	// losing all the pieces wins
+	g, err := NewVariantGameFromFEN(Antichess, "8/8/8/8/8/8/1p6/1R6 w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.MoveStr("Rxb2"); err != nil {
+		t.Fatal(err)
+	}
+	if g.Outcome() != BlackWon || g.Method() != VariantWin {
+		t.Fatalf("expected black to win without pieces but got %s by %s", g.Outcome(), g.Method())
+	}
+
+	// pawns promote to kings, which can be captured
+	g, err = NewVariantGameFromFEN(Antichess, "8/P7/8/8/8/8/8/7k w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.MoveStr("a8=K"); err != nil {
+		t.Fatal(err)
+	}
+	if fen := g.FEN(); fen != "K7/8/8/8/8/8/8/7k b - - 0 1" {
+		t.Fatalf("expected a king on a8 but got %s", fen)
+	}
+	if err := g.MoveStr("Kg1"); err != nil {
+		t.Fatal(err)
+	}
+	pos := unsafeVariantFEN(Antichess, "8/P7/8/8/8/8/8/7k w - - 0 1")
+	if m, err := pos.DecodeUCI("a7a8k"); err != nil || m.Promo() != PromoKing {
+		t.Fatalf("expected a7a8k to promote to a king but got %v %v", m, err)
+	}
+
+	// bishops on squares of different colors can never capture
+	g, err = NewVariantGameFromFEN(Antichess, "8/8/8/8/8/8/B6b/8 w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.MoveStr("Bb1"); err != nil {
+		t.Fatal(err)
+	}
+	if g.Outcome() != Draw || g.Method() != InsufficientMaterial {
+		t.Fatalf("expected a draw by insufficient material but got %s by %s", g.Outcome(), g.Method())
+	}
+}
+
+func TestAtomic(t *testing.T) {
+	g := NewVariantGame(Atomic)
+	for _, m := range []string{"Nf3", "a6", "Ng5", "a5", "Nxf7"} {
+		if err := g.MoveStr(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if fen := g.FEN(); fen != "rnbq3r/1pppp1pp/8/p7/8/8/PPPPPPPP/RNBQKB1R b KQ - 0 3" {
+		t.Fatalf("expected Nxf7 to explode the black king but got %s", fen)
+	}
+	if g.Outcome() != WhiteWon || g.Method() != VariantWin {
+		t.Fatalf("expected white to win but got %s by %s", g.Outcome(), g.Method())
+	}
+
+	// kings can't capture
+	g, err := NewVariantGameFromFEN(Atomic, "4k3/8/8/8/8/8/3p4/4K3 w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.MoveStr("Kxd2"); err == nil {
+		t.Fatal("expected Kxd2 to be illegal")
+	}
+
+	// a king next to the other king is never in check
+	pos := unsafeVariantFEN(Atomic, "8/8/8/8/8/8/2kK3r/8 w - - 0 1")
+	if pos.InCheck() {
+		t.Fatal("expected the kings touching to not be check")
+	}
+
+	// a lone knight can't explode a bare king
+	g, err = NewVariantGameFromFEN(Atomic, "4k3/8/8/8/8/8/8/3NK3 w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.MoveStr("Kf2"); err != nil {
+		t.Fatal(err)
+	}
+	if g.Outcome() != Draw || g.Method() != InsufficientMaterial {
+		t.Fatalf("expected a draw by insufficient material but got %s by %s", g.Outcome(), g.Method())
+	}
+}
+
+func TestVariantPGN(t *testing.T) {
+	pgn := `[Event "Rated Three-check game"]
+[Site "https://lichess.org/abcdefgh"]
+[Result "1-0"]
+[Variant "Three-check"]
+
+1. e4 e5 2. Qh5 Nc6 3. Qxf7+ Kxf7 4. Bc4+ d5 5. Bxd5+ 1-0`
+	g, err := NewGameFromPGN(strings.NewReader(pgn))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.Variant() != ThreeCheck {
+		t.Fatalf("expected Three-check but got %s", g.Variant().Name())
+	}
+	if g.Position().Checks(White) != 3 {
+		t.Fatalf("expected 3 checks by white but got %s", g.FEN())
+	}
+	if _, err := NewGameFromPGN(strings.NewReader(g.String())); err != nil {
+		t.Fatal(err)
+	}
+
+	pgn = `[Variant "Racing Kings"]
+[FEN "8/8/8/8/8/8/krbnNBRK/qrbnNBRQ w - - 0 1"]
+
+1. Ng3 *`
+	g, err = NewGameFromPGN(strings.NewReader(pgn))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.Variant() != RacingKings {
+		t.Fatalf("expected Racing Kings but got %s", g.Variant().Name())
+	}
+
+	pgn = `[Variant "Chess960"]
+
+1. e4 *`
+	g, err = NewGameFromPGN(strings.NewReader(pgn))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.Variant() != Standard {
+		t.Fatalf("expected an unsupported variant to be standard chess but got %s", g.Variant().Name())
+	}
+	if v := g.GetTagPair("Variant"); v == nil || v.Value != "Chess960" {
+		t.Fatalf("expected the Variant tag to be kept but got %v", v)
+	}
+}